@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/auth"
+	"github.com/kodra-pay/payout-service/internal/config"
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/migrations"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+const usage = `usage:
+  payout-service                                   start the HTTP server
+  payout-service apikey create [-plan <plan>] <merchant_id> [name]
+                                                   issue a merchant API key
+  payout-service apikey revoke <key_id>             revoke an API key
+  payout-service reencrypt [-batch <n>]            move account numbers to the active encryption key
+  payout-service migrate up                        apply pending database migrations
+  payout-service migrate down [-steps <n>]         revert the latest migrations (default 1)
+  payout-service migrate status                    list migrations and whether they are applied`
+
+// runCommand dispatches administrative subcommands.
+func runCommand(cfg config.Config, args []string) error {
+	switch args[0] {
+	case "apikey":
+		return runAPIKey(cfg, args[1:])
+	case "reencrypt":
+		return runReencrypt(cfg, args[1:])
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	case "help", "-h", "--help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+}
+
+func runAPIKey(cfg config.Config, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("%s", usage)
+	}
+	fs := flag.NewFlagSet("apikey "+args[0], flag.ContinueOnError)
+	plan := fs.String("plan", cfg.RateLimitDefaultPlan, "merchant plan used for rate limiting")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if len(rest) < 1 {
+		return fmt.Errorf("%s", usage)
+	}
+	id, err := strconv.Atoi(rest[0])
+	if err != nil || id <= 0 {
+		return fmt.Errorf("invalid id %q", rest[0])
+	}
+
+	db, err := repositories.Open(cfg.PostgresDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	repo := repositories.NewAPIKeyRepository(db)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "create":
+		key, prefix, err := auth.GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		k := &models.APIKey{
+			MerchantID: id,
+			Name:       strings.Join(rest[1:], " "),
+			Prefix:     prefix,
+			Plan:       *plan,
+			KeyHash:    auth.HashAPIKey(key),
+		}
+		if err := repo.Create(ctx, k); err != nil {
+			return fmt.Errorf("create api key: %w", err)
+		}
+		// The plaintext key is only ever shown here.
+		fmt.Printf("api key %d for merchant %d: %s\n", k.ID, k.MerchantID, key)
+		return nil
+	case "revoke":
+		if err := repo.Revoke(ctx, id); err != nil {
+			return fmt.Errorf("revoke api key: %w", err)
+		}
+		fmt.Printf("api key %d revoked\n", id)
+		return nil
+	default:
+		return fmt.Errorf("unknown apikey command %q\n%s", args[0], usage)
+	}
+}
+
+func runReencrypt(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("reencrypt", flag.ContinueOnError)
+	batch := fs.Int("batch", 500, "rows per batch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	keys, err := fieldcrypt.NewKeyring(cfg.FieldEncryptionKeys, cfg.FieldEncryptionKeysFile, cfg.FieldEncryptionActiveVersion, cfg.BlindIndexKey)
+	if err != nil {
+		return err
+	}
+	db, err := repositories.Open(cfg.PostgresDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	n, err := repositories.NewPayoutRepository(db, keys).ReencryptAccounts(context.Background(), *batch)
+	fmt.Printf("re-encrypted %d payouts under key version %d\n", n, keys.ActiveVersion())
+	return err
+}
+
+func runMigrate(cfg config.Config, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("%s", usage)
+	}
+	fs := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	db, err := repositories.Open(cfg.PostgresDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator := migrations.New(db)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+	case "down":
+		if *steps < 1 {
+			return fmt.Errorf("-steps must be at least 1")
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Println("no applied migrations")
+		}
+		return err
+	case "status":
+		list, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range list {
+			state := "pending"
+			switch {
+			case s.Dirty:
+				state = "dirty"
+			case s.Applied:
+				state = "applied " + s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-32s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], usage)
+	}
+}
@@ -1,24 +1,151 @@
 package main
 
 import (
-	"log"
+	"context"
+	"database/sql"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/kodra-pay/payout-service/internal/background"
 	"github.com/kodra-pay/payout-service/internal/config"
+	"github.com/kodra-pay/payout-service/internal/logging"
 	"github.com/kodra-pay/payout-service/internal/middleware"
 	"github.com/kodra-pay/payout-service/internal/routes"
+	"github.com/kodra-pay/payout-service/internal/tracing"
 )
 
 func main() {
 	cfg := config.Load("payout-service", "7009")
 
-	app := fiber.New()
-	app.Use(middleware.RequestID())
+	logger := logging.New(cfg.ServiceName, cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
 
-	routes.Register(app, cfg)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.ServiceName, cfg.TraceExporter, cfg.TraceSampleRatio)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(os.Args) > 1 {
+		err = runCommand(cfg, os.Args[1:])
+		_ = shutdownTracing(context.Background())
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	app := fiber.New(publicConfig(cfg))
+	app.Use(middleware.RequestID(), middleware.Tracing(), middleware.AccessLog(logger), middleware.Metrics("public"))
+
+	internal := fiber.New(fiber.Config{DisableStartupMessage: true, ErrorHandler: middleware.ErrorHandler})
+	internal.Use(middleware.RequestID(), middleware.Tracing(), middleware.AccessLog(logger), middleware.Metrics("internal"))
+
+	workers := background.NewGroup()
+	db := routes.Register(app, internal, cfg, workers)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listenErr := make(chan error, 2)
+	go func() {
+		if cfg.InternalTLSCert != "" && cfg.InternalClientCA != "" {
+			slog.Info("internal API listening", slog.String("port", cfg.InternalPort), slog.Bool("mtls", true))
+			listenErr <- internal.ListenMutualTLS(":"+cfg.InternalPort, cfg.InternalTLSCert, cfg.InternalTLSKey, cfg.InternalClientCA)
+		} else {
+			slog.Info("internal API listening", slog.String("port", cfg.InternalPort), slog.Bool("mtls", false))
+			listenErr <- internal.Listen(":" + cfg.InternalPort)
+		}
+	}()
+	go func() {
+		slog.Info("public API listening", slog.String("port", cfg.Port))
+		listenErr <- app.Listen(":" + cfg.Port)
+	}()
 
-	log.Printf("%s listening on :%s", cfg.ServiceName, cfg.Port)
-	if err := app.Listen(":" + cfg.Port); err != nil {
-		log.Fatal(err)
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining", slog.Int("timeout_seconds", cfg.ShutdownTimeoutSeconds))
+	case err := <-listenErr:
+		slog.Error("listener stopped, shutting down", slog.Any("error", err))
+		exitCode = 1
 	}
+	stop()
+
+	if !shutdown(cfg, db, workers, shutdownTracing, app, internal) {
+		exitCode = 1
+	}
+	os.Exit(exitCode)
+}
+
+// shutdown stops accepting requests, waits for in-flight requests and background work
+// until the configured deadline, then closes the DB pool and flushes traces. It reports
+// whether everything finished in time; unfinished work is logged so it can be checked
+// (pending payouts are resumed automatically on the next start).
+func shutdown(cfg config.Config, db *sql.DB, workers *background.Group, shutdownTracing func(context.Context) error, apps ...*fiber.App) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	clean := true
+
+	for _, a := range apps {
+		if err := a.ShutdownWithContext(ctx); err != nil {
+			slog.Warn("HTTP server did not drain in time", slog.Any("error", err))
+			clean = false
+		}
+	}
+
+	unfinished := workers.Shutdown(ctx)
+	for _, task := range unfinished {
+		slog.Warn("background work unfinished at shutdown", slog.String("task", task))
+	}
+	if len(unfinished) > 0 {
+		clean = false
+	}
+
+	if err := db.Close(); err != nil {
+		slog.Warn("failed to close database pool", slog.Any("error", err))
+	}
+	// Flushing traces gets its own short deadline so a slow drain doesn't drop them.
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("failed to flush traces", slog.Any("error", err))
+	}
+
+	if clean {
+		slog.Info("shutdown complete")
+	}
+	return clean
+}
+
+// publicConfig configures the public listener. Client addresses, used by the per-IP rate
+// limit and access log, come from cfg.ProxyHeader on connections from cfg.TrustedProxies
+// and from the connection otherwise.
+func publicConfig(cfg config.Config) fiber.Config {
+	var proxies []string
+	for _, p := range strings.Split(cfg.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	if cfg.ProxyHeader != "" && len(proxies) == 0 {
+		slog.Warn("PROXY_HEADER is set without TRUSTED_PROXIES, using connection addresses", slog.String("header", cfg.ProxyHeader))
+	}
+	return fiber.Config{
+		DisableStartupMessage:   true,
+		ErrorHandler:            middleware.ErrorHandler,
+		ProxyHeader:             cfg.ProxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          proxies,
+		EnableIPValidation:      true,
+	}
+}
+
+func fatal(err error) {
+	slog.Error("payout-service exiting", slog.Any("error", err))
+	os.Exit(1)
 }
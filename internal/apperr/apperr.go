@@ -0,0 +1,84 @@
+// Package apperr defines the typed errors services return. Each error has a kind, which
+// decides the HTTP status it is reported with, and a machine-readable code for clients.
+package apperr
+
+import "errors"
+
+type Kind string
+
+const (
+	KindValidation        Kind = "validation"
+	KindNotFound          Kind = "not_found"
+	KindConflict          Kind = "conflict"
+	KindInsufficientFunds Kind = "insufficient_funds"
+	KindUnavailable       Kind = "dependency_unavailable"
+	KindInternal          Kind = "internal"
+)
+
+// FieldError explains why one input field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error. Message is safe to show to API clients; Err is the underlying
+// cause, which is only logged.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Validation reports invalid input, with the offending fields when known.
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: message, Fields: fields}
+}
+
+func Field(field, message string) FieldError {
+	return FieldError{Field: field, Message: message}
+}
+
+// NotFound reports a missing resource, e.g. NotFound("payout_not_found", "payout not found").
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Conflict reports a request that clashes with the current state of a resource.
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func InsufficientFunds(message string) *Error {
+	return &Error{Kind: KindInsufficientFunds, Code: "insufficient_funds", Message: message}
+}
+
+// Unavailable reports that a dependency (another service or the database) failed, so the
+// request may succeed later.
+func Unavailable(message string, err error) *Error {
+	return &Error{Kind: KindUnavailable, Code: "dependency_unavailable", Message: message, Err: err}
+}
+
+// Internal wraps an unexpected failure. Clients only see a generic message.
+func Internal(message string, err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: message, Err: err}
+}
+
+// KindOf returns the kind of the first *Error in err's chain, or KindInternal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+)
+
+// fakeKeys is an APIKeyStore over a fixed set of keys, by hash.
+type fakeKeys map[string]*models.APIKey
+
+func (f fakeKeys) GetByHash(_ context.Context, hash string) (*models.APIKey, error) {
+	if k, ok := f[hash]; ok {
+		return k, nil
+	}
+	return nil, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	active, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, _, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedAt := time.Now().Add(-time.Hour)
+	keys := fakeKeys{
+		HashAPIKey(active):  {ID: 1, MerchantID: 42, Plan: "starter"},
+		HashAPIKey(revoked): {ID: 2, MerchantID: 42, RevokedAt: &revokedAt},
+	}
+	jwt, err := NewJWTVerifier("hmac-secret", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signToken(t, "HS256", "", validClaims(), []byte("hmac-secret"))
+
+	tests := []struct {
+		name       string
+		auth       *Authenticator
+		credential string
+		want       Principal
+		wantErr    error
+	}{
+		{"api key", NewAuthenticator(keys, jwt), active, Principal{MerchantID: 42, Plan: "starter", Method: MethodAPIKey, KeyID: 1}, nil},
+		{"api key with whitespace", NewAuthenticator(keys, jwt), " " + active + "\n", Principal{MerchantID: 42, Plan: "starter", Method: MethodAPIKey, KeyID: 1}, nil},
+		{"revoked api key", NewAuthenticator(keys, jwt), revoked, Principal{}, ErrUnauthenticated},
+		{"unknown api key", NewAuthenticator(keys, jwt), "kp_unknown", Principal{}, ErrUnauthenticated},
+		{"empty", NewAuthenticator(keys, jwt), "  ", Principal{}, ErrUnauthenticated},
+		{"jwt", NewAuthenticator(keys, jwt), token, Principal{MerchantID: 42, Plan: "growth", Method: MethodJWT}, nil},
+		{"jwt without verification keys", NewAuthenticator(keys, &JWTVerifier{}), token, Principal{}, ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.auth.Authenticate(context.Background(), tt.credential)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Fatalf("got %+v, %v; want %+v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, prefix, err := GenerateAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) || !strings.HasPrefix(key, prefix) || len(prefix) != len(apiKeyPrefix)+8 {
+		t.Fatalf("key %q, prefix %q", key, prefix)
+	}
+	other, _, _ := GenerateAPIKey()
+	if other == key || HashAPIKey(other) == HashAPIKey(key) {
+		t.Fatal("generated the same key twice")
+	}
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockSkew is the leeway applied to exp/nbf checks.
+const clockSkew = time.Minute
+
+var ErrInvalidToken = errors.New("invalid bearer token")
+
+// JWTVerifier validates HS256 and RS256 bearer tokens against a fixed set of keys.
+// Keys may be registered under a key ID ("kid"); tokens without a kid are tried
+// against every key of the matching algorithm.
+type JWTVerifier struct {
+	hmacKeys map[string][]byte
+	rsaKeys  map[string]*rsa.PublicKey
+	issuer   string
+	audience string
+}
+
+// NewJWTVerifier builds a verifier from comma-separated key lists. hmacSecrets entries are
+// "secret" or "kid:secret"; publicKeyFiles entries are "path" or "kid:path" to PEM encoded
+// RSA public keys. A verifier without keys rejects every token.
+func NewJWTVerifier(hmacSecrets, publicKeyFiles, issuer, audience string) (*JWTVerifier, error) {
+	v := &JWTVerifier{
+		hmacKeys: map[string][]byte{},
+		rsaKeys:  map[string]*rsa.PublicKey{},
+		issuer:   issuer,
+		audience: audience,
+	}
+	for i, entry := range splitList(hmacSecrets) {
+		kid, secret := splitKeyID(entry, i)
+		v.hmacKeys[kid] = []byte(secret)
+	}
+	for i, entry := range splitList(publicKeyFiles) {
+		kid, path := splitKeyID(entry, i)
+		key, err := loadRSAPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("load jwt public key %s: %w", path, err)
+		}
+		v.rsaKeys[kid] = key
+	}
+	return v, nil
+}
+
+// Enabled reports whether any verification key is configured.
+func (v *JWTVerifier) Enabled() bool {
+	return len(v.hmacKeys) > 0 || len(v.rsaKeys) > 0
+}
+
+// Claims are the registered claims we validate plus the merchant the token was issued for.
+type Claims struct {
+	Subject    string          `json:"sub"`
+	Issuer     string          `json:"iss"`
+	Audience   json.RawMessage `json:"aud"`
+	ExpiresAt  int64           `json:"exp"`
+	NotBefore  int64           `json:"nbf"`
+	MerchantID json.Number     `json:"merchant_id"`
+	Plan       string          `json:"plan"`
+
+	// Merchant is the validated merchant ID, from merchant_id or else sub.
+	Merchant int `json:"-"`
+}
+
+// Verify checks the token signature and claims and returns the validated claims.
+func (v *JWTVerifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+
+	if !v.verifySignature(header.Alg, header.Kid, signed, sig) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	merchantID, err := v.validateClaims(claims)
+	if err != nil {
+		return Claims{}, err
+	}
+	claims.Merchant = merchantID
+	return claims, nil
+}
+
+func (v *JWTVerifier) verifySignature(alg, kid string, signed, sig []byte) bool {
+	switch alg {
+	case "HS256":
+		for id, key := range v.hmacKeys {
+			if kid != "" && id != kid {
+				continue
+			}
+			mac := hmac.New(sha256.New, key)
+			mac.Write(signed)
+			if hmac.Equal(mac.Sum(nil), sig) {
+				return true
+			}
+		}
+	case "RS256":
+		digest := sha256.Sum256(signed)
+		for id, key := range v.rsaKeys {
+			if kid != "" && id != kid {
+				continue
+			}
+			if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil {
+				return true
+			}
+		}
+	}
+	// Any other algorithm, including "none", is rejected.
+	return false
+}
+
+func (v *JWTVerifier) validateClaims(c Claims) (int, error) {
+	now := time.Now()
+	if c.ExpiresAt == 0 || now.After(time.Unix(c.ExpiresAt, 0).Add(clockSkew)) {
+		return 0, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if c.NotBefore != 0 && now.Add(clockSkew).Before(time.Unix(c.NotBefore, 0)) {
+		return 0, fmt.Errorf("%w: token not yet valid", ErrInvalidToken)
+	}
+	if v.issuer != "" && c.Issuer != v.issuer {
+		return 0, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if v.audience != "" && !audienceContains(c.Audience, v.audience) {
+		return 0, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	raw := c.MerchantID.String()
+	if raw == "" {
+		raw = c.Subject
+	}
+	merchantID, err := strconv.Atoi(raw)
+	if err != nil || merchantID <= 0 {
+		return 0, fmt.Errorf("%w: token has no merchant", ErrInvalidToken)
+	}
+	return merchantID, nil
+}
+
+func audienceContains(raw json.RawMessage, want string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == want
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, a := range list {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func loadRSAPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		if key, ok := pub.(*rsa.PublicKey); ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("public key is not RSA")
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// splitKeyID separates an optional "kid:" prefix. Entries without one get a positional ID.
+func splitKeyID(entry string, i int) (string, string) {
+	if kid, value, ok := strings.Cut(entry, ":"); ok && kid != "" && !strings.ContainsAny(kid, "/\\") {
+		return kid, value
+	}
+	return fmt.Sprintf("key-%d", i), entry
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRSAKey writes a fresh RSA public key to a PEM file and returns the private key, the
+// file and its PEM content.
+func testRSAKey(t *testing.T) (*rsa.PrivateKey, string, []byte) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	path := filepath.Join(t.TempDir(), "jwt.pub")
+	if err := os.WriteFile(path, pemBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key, path, pemBytes
+}
+
+// signToken builds a compact JWT. key is an HMAC secret ([]byte) or an *rsa.PrivateKey.
+func signToken(t *testing.T, alg, kid string, claims map[string]any, key any) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	seg := func(v any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := seg(header) + "." + seg(claims)
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":         "merchant-user",
+		"merchant_id": 42,
+		"plan":        "growth",
+		"iss":         "https://auth.kodra.test",
+		"aud":         []string{"dashboard", "payout-service"},
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"nbf":         time.Now().Add(-time.Minute).Unix(),
+	}
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, pubPath, pubPEM := testRSAKey(t)
+	secret := []byte("hmac-secret")
+	v, err := NewJWTVerifier("hs1:hmac-secret", "rs1:"+pubPath, "https://auth.kodra.test", "payout-service")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaOnly, err := NewJWTVerifier("", pubPath, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	with := func(key string, value any) map[string]any {
+		c := validClaims()
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+	now := time.Now()
+	tests := []struct {
+		name     string
+		verifier *JWTVerifier
+		token    string
+		wantErr  bool
+	}{
+		{"HS256", v, signToken(t, "HS256", "hs1", validClaims(), secret), false},
+		{"HS256 without kid", v, signToken(t, "HS256", "", validClaims(), secret), false},
+		{"RS256", v, signToken(t, "RS256", "rs1", validClaims(), rsaKey), false},
+		{"wrong HMAC secret", v, signToken(t, "HS256", "hs1", validClaims(), []byte("guess")), true},
+		{"unknown kid", v, signToken(t, "HS256", "hs2", validClaims(), secret), true},
+		{"kid of another algorithm", v, signToken(t, "RS256", "hs1", validClaims(), rsaKey), true},
+		// The classic confusion attack: HMAC with the public key as the shared secret.
+		{"HS256 signed with the RSA public key", rsaOnly, signToken(t, "HS256", "", validClaims(), pubPEM), true},
+		{"HS256 signed with the RSA public key and kid", v, signToken(t, "HS256", "rs1", validClaims(), pubPEM), true},
+		{"alg none", v, signToken(t, "none", "", validClaims(), []byte{}), true},
+		{"expired", v, signToken(t, "HS256", "hs1", with("exp", now.Add(-2*clockSkew).Unix()), secret), true},
+		{"expired within the clock skew", v, signToken(t, "HS256", "hs1", with("exp", now.Add(-clockSkew/2).Unix()), secret), false},
+		{"no exp", v, signToken(t, "HS256", "hs1", with("exp", nil), secret), true},
+		{"not yet valid", v, signToken(t, "HS256", "hs1", with("nbf", now.Add(2*clockSkew).Unix()), secret), true},
+		{"valid within the clock skew", v, signToken(t, "HS256", "hs1", with("nbf", now.Add(clockSkew/2).Unix()), secret), false},
+		{"wrong audience", v, signToken(t, "HS256", "hs1", with("aud", "dashboard"), secret), true},
+		{"single audience", v, signToken(t, "HS256", "hs1", with("aud", "payout-service"), secret), false},
+		{"no audience", v, signToken(t, "HS256", "hs1", with("aud", nil), secret), true},
+		{"wrong issuer", v, signToken(t, "HS256", "hs1", with("iss", "https://evil.test"), secret), true},
+		{"no merchant", v, signToken(t, "HS256", "hs1", with("merchant_id", nil), secret), true},
+		{"malformed", v, "a.b.c", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := tt.verifier.Verify(tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Fatalf("got %+v, %v; want ErrInvalidToken", claims, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Merchant != 42 || claims.Plan != "growth" {
+				t.Fatalf("claims = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierTamperedClaims(t *testing.T) {
+	v, err := NewJWTVerifier("hmac-secret", "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := signToken(t, "HS256", "", validClaims(), []byte("hmac-secret"))
+	forged := signToken(t, "HS256", "", map[string]any{"merchant_id": 7, "exp": time.Now().Add(time.Hour).Unix()}, []byte("other"))
+
+	// The signature of one token doesn't cover the claims of another.
+	genuine, other := strings.Split(token, "."), strings.Split(forged, ".")
+	if _, err := v.Verify(genuine[0] + "." + other[1] + "." + genuine[2]); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("got %v, want ErrInvalidToken", err)
+	}
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers the nonces of signed requests so each can only be used once. Claim
+// records key until expires and reports whether it was unused; a key whose previous claim
+// has expired may be claimed again.
+type NonceStore interface {
+	Claim(ctx context.Context, key string, expires time.Time) (bool, error)
+}
+
+// MemoryNonceStore keeps nonces in process memory. It is only correct for single-instance
+// deployments; use the Postgres store when running several replicas, or a request replayed
+// against another replica is accepted.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	lastGC time.Time
+	now    func() time.Time
+}
+
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: map[string]time.Time{}, now: time.Now}
+}
+
+func (s *MemoryNonceStore) Claim(_ context.Context, key string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.gc(now)
+	if prev, ok := s.nonces[key]; ok && now.Before(prev) {
+		return false, nil
+	}
+	s.nonces[key] = expires
+	return true, nil
+}
+
+// gc drops expired nonces. It runs at most once a minute.
+func (s *MemoryNonceStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < time.Minute {
+		return
+	}
+	s.lastGC = now
+	for key, expires := range s.nonces {
+		if !now.Before(expires) {
+			delete(s.nonces, key)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package auth
+
+// Permissions checked on internal routes.
+const (
+	PermUpdateStatus = "payouts:update_status"
+	PermForceFail    = "payouts:force_fail"
+	PermRetry        = "payouts:retry"
+	PermReverse      = "payouts:reverse"
+	PermReconcile    = "reconciliation:manage"
+)
+
+// rolePermissions grants permissions to the roles internal callers are configured with.
+// "service" is for automated callers such as the payout provider integration; ops roles
+// are for the back-office tooling acting on behalf of staff.
+var rolePermissions = map[string][]string{
+	"service":   {PermUpdateStatus},
+	"ops":       {PermForceFail, PermRetry, PermReconcile},
+	"ops_admin": {PermUpdateStatus, PermForceFail, PermRetry, PermReverse, PermReconcile},
+}
+
+// ServiceCaller is an authenticated internal client.
+type ServiceCaller struct {
+	Name  string
+	Roles []string
+}
+
+// Can reports whether any of the caller's roles grants the permission.
+func (c ServiceCaller) Can(permission string) bool {
+	for _, role := range c.Roles {
+		for _, p := range rolePermissions[role] {
+			if p == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
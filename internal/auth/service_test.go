@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	a, err := NewServiceAuthenticator("ledger:s3cret:service,ops:0ps:ops", "", NewMemoryNonceStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	body := []byte(`{"status":"completed"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	sign := func(secret, ts, nonce string) string {
+		return SignRequest([]byte(secret), "PUT", "/internal/payouts/7/status", ts, nonce, body)
+	}
+	verify := func(client, ts, nonce, sig string) error {
+		_, err := a.VerifySignature(ctx, client, ts, nonce, sig, "PUT", "/internal/payouts/7/status", body)
+		return err
+	}
+
+	nonce := "6f1c0d0e-51b4-4d3e-9a5e-0c2f4b7d8e91"
+	caller, err := a.VerifySignature(ctx, "ledger", now, nonce, sign("s3cret", now, nonce), "PUT", "/internal/payouts/7/status", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller.Name != "ledger" || !caller.Can(PermUpdateStatus) {
+		t.Fatalf("caller = %+v", caller)
+	}
+
+	stale := strconv.FormatInt(time.Now().Add(-maxSignatureAge-time.Minute).Unix(), 10)
+	tests := []struct {
+		name                   string
+		client, ts, nonce, sig string
+	}{
+		{"replayed", "ledger", now, nonce, sign("s3cret", now, nonce)},
+		{"missing nonce", "ledger", now, "", sign("s3cret", now, "")},
+		{"short nonce", "ledger", now, "abc", sign("s3cret", now, "abc")},
+		{"nonce not covered by the signature", "ledger", now, "0123456789abcdef0123", sign("s3cret", now, nonce)},
+		{"wrong secret", "ledger", now, "fedcba9876543210fedc", sign("guess", now, "fedcba9876543210fedc")},
+		{"unknown client", "billing", now, "1111222233334444", sign("s3cret", now, "1111222233334444")},
+		{"stale timestamp", "ledger", stale, "5555666677778888", sign("s3cret", stale, "5555666677778888")},
+		{"invalid timestamp", "ledger", "yesterday", "9999000011112222", sign("s3cret", "yesterday", "9999000011112222")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verify(tt.client, tt.ts, tt.nonce, tt.sig); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+
+	// A rejected request doesn't use up its nonce, and nonces are per client.
+	if err := verify("ledger", now, "0123456789abcdef0123", sign("s3cret", now, "0123456789abcdef0123")); err != nil {
+		t.Fatalf("nonce of a rejected request: %v", err)
+	}
+	if err := verify("ops", now, nonce, SignRequest([]byte("0ps"), "PUT", "/internal/payouts/7/status", now, nonce, body)); err != nil {
+		t.Fatalf("another client's nonce: %v", err)
+	}
+}
+
+type failingNonces struct{}
+
+func (failingNonces) Claim(context.Context, string, time.Time) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestVerifySignatureFailsClosed(t *testing.T) {
+	a, err := NewServiceAuthenticator("ledger:s3cret:service", "", failingNonces{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := "6f1c0d0e51b44d3e9a5e"
+	sig := SignRequest([]byte("s3cret"), "GET", "/internal/payouts/7", now, nonce, nil)
+	_, err = a.VerifySignature(context.Background(), "ledger", now, nonce, sig, "GET", "/internal/payouts/7", nil)
+	if err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("got %v, want the store error", err)
+	}
+}
+
+func TestMemoryNonceStoreExpiry(t *testing.T) {
+	s := NewMemoryNonceStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	claim := func() bool {
+		ok, err := s.Claim(ctx, "ledger:n1", now.Add(time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+	if !claim() || claim() {
+		t.Fatal("want only the first claim to succeed")
+	}
+	now = now.Add(2 * time.Minute)
+	if !claim() {
+		t.Fatal("want an expired nonce to be claimable again")
+	}
+}
@@ -0,0 +1,112 @@
+// Package background tracks work that outlives the request that started it, so shutdown
+// can wait for it and report what didn't finish.
+package background
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Group runs and tracks background tasks.
+//
+// Shutdown happens in two steps: Stopping is closed as soon as it begins, so tasks that
+// haven't started real work yet (e.g. a payout waiting for its processing delay) can give
+// up and leave their work to be resumed; the context passed to each task is cancelled only
+// when the shutdown deadline passes.
+type Group struct {
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	tasks    map[uint64]string
+	nextID   uint64
+	stopping chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	closed   bool
+}
+
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{
+		tasks:    map[uint64]string{},
+		stopping: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Go runs fn in a new goroutine. name describes the work in shutdown logs. The context
+// passed to fn carries ctx's values and is cancelled when the shutdown deadline passes. Go
+// returns false without running fn once shutdown has begun.
+func (g *Group) Go(ctx context.Context, name string, fn func(ctx context.Context)) bool {
+	g.mu.Lock()
+	if g.closed {
+		g.mu.Unlock()
+		return false
+	}
+	g.nextID++
+	id := g.nextID
+	g.tasks[id] = name
+	g.wg.Add(1)
+	g.mu.Unlock()
+
+	taskCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(g.ctx, cancel)
+	go func() {
+		defer func() {
+			stop()
+			cancel()
+			g.mu.Lock()
+			delete(g.tasks, id)
+			g.mu.Unlock()
+			g.wg.Done()
+		}()
+		fn(taskCtx)
+	}()
+	return true
+}
+
+// Stopping is closed when shutdown begins.
+func (g *Group) Stopping() <-chan struct{} {
+	return g.stopping
+}
+
+// Running returns the names of the tasks still running, sorted.
+func (g *Group) Running() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	names := make([]string, 0, len(g.tasks))
+	for _, name := range g.tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shutdown stops accepting tasks, signals Stopping and waits for running tasks until ctx is
+// done. It then cancels the tasks' contexts and returns the names of those that hadn't
+// finished by the deadline.
+func (g *Group) Shutdown(ctx context.Context) []string {
+	g.mu.Lock()
+	if !g.closed {
+		g.closed = true
+		close(g.stopping)
+	}
+	g.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		g.cancel()
+		return nil
+	case <-ctx.Done():
+		unfinished := g.Running()
+		g.cancel()
+		return unfinished
+	}
+}
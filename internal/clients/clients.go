@@ -0,0 +1,75 @@
+// Package clients talks to the other KodraPay services a payout depends on. Amounts are in
+// minor units (kobo) throughout; the HTTP implementations convert to the currency units the
+// services expect on the wire.
+package clients
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kodra-pay/payout-service/internal/dto"
+)
+
+// ErrInsufficientBalance is returned by DeductBalance when the merchant's available balance
+// doesn't cover the amount.
+var ErrInsufficientBalance = errors.New("insufficient available balance")
+
+// MerchantClient reads and moves merchant balances in merchant-service.
+type MerchantClient interface {
+	// AvailableBalance returns the merchant's available balance in the currency.
+	AvailableBalance(ctx context.Context, merchantID int, currency string) (Balance, error)
+	// DeductBalance debits a completed payout from the merchant's available balance. It
+	// fails with ErrInsufficientBalance if the balance is too low.
+	DeductBalance(ctx context.Context, req dto.DeductBalanceRequest) error
+	// CreditBalance returns money to the merchant's available balance, e.g. a payout the
+	// recipient's bank sent back. merchant-service applies each credit reference once, so
+	// a credit can be repeated safely.
+	CreditBalance(ctx context.Context, credit BalanceCredit) error
+}
+
+// TransactionClient records ledger transactions in transaction-service.
+type TransactionClient interface {
+	RecordTransaction(ctx context.Context, req TransactionRequest) error
+}
+
+// NotificationClient tells merchants about their payouts through notification-service.
+type NotificationClient interface {
+	NotifyMerchant(ctx context.Context, n Notification) error
+}
+
+type Balance struct {
+	MerchantID int
+	Currency   string
+	Available  int64
+}
+
+// BalanceCredit is an amount, in minor units, to return to a merchant's balance.
+type BalanceCredit struct {
+	MerchantID int
+	Amount     int64
+	Currency   string
+	Reference  string // identifies the credit; repeating a reference doesn't credit twice
+}
+
+// Notification is a message to a merchant about one of their payouts. Amount is in minor
+// units.
+type Notification struct {
+	MerchantID      int
+	Event           string // e.g. "payout.returned"
+	PayoutID        int
+	PayoutReference string
+	Amount          int64
+	Currency        string
+	Message         string
+}
+
+// TransactionRequest is a transaction to record. Amount is in minor units.
+type TransactionRequest struct {
+	Reference     string
+	MerchantID    int
+	Amount        int64
+	Currency      string
+	PaymentMethod string
+	Status        string
+	Description   string
+}
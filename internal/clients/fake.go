@@ -0,0 +1,153 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/kodra-pay/payout-service/internal/dto"
+)
+
+// FakeMerchantClient is an in-memory MerchantClient for tests and local runs. Deductions
+// are applied to the stored balances and recorded in order; like merchant-service, it
+// refuses deductions the balance doesn't cover.
+type FakeMerchantClient struct {
+	mu         sync.Mutex
+	balances   map[string]int64
+	deductions []dto.DeductBalanceRequest
+	credits    map[string]BalanceCredit
+
+	// BalanceErr, DeductErr and CreditErr, when set, are returned by the corresponding calls.
+	BalanceErr error
+	DeductErr  error
+	CreditErr  error
+}
+
+func NewFakeMerchantClient() *FakeMerchantClient {
+	return &FakeMerchantClient{balances: map[string]int64{}, credits: map[string]BalanceCredit{}}
+}
+
+// SetBalance sets a merchant's available balance in minor units.
+func (f *FakeMerchantClient) SetBalance(merchantID int, currency string, available int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balances[balanceKey(merchantID, currency)] = available
+}
+
+func (f *FakeMerchantClient) AvailableBalance(_ context.Context, merchantID int, currency string) (Balance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.BalanceErr != nil {
+		return Balance{}, f.BalanceErr
+	}
+	return Balance{MerchantID: merchantID, Currency: currency, Available: f.balances[balanceKey(merchantID, currency)]}, nil
+}
+
+func (f *FakeMerchantClient) DeductBalance(_ context.Context, req dto.DeductBalanceRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.DeductErr != nil {
+		return f.DeductErr
+	}
+	key := balanceKey(req.MerchantID, req.Currency)
+	if f.balances[key] < req.Amount {
+		return ErrInsufficientBalance
+	}
+	f.balances[key] -= req.Amount
+	f.deductions = append(f.deductions, req)
+	return nil
+}
+
+// CreditBalance applies each credit reference once, as merchant-service does.
+func (f *FakeMerchantClient) CreditBalance(_ context.Context, credit BalanceCredit) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.CreditErr != nil {
+		return f.CreditErr
+	}
+	if _, ok := f.credits[credit.Reference]; ok {
+		return nil
+	}
+	f.credits[credit.Reference] = credit
+	f.balances[balanceKey(credit.MerchantID, credit.Currency)] += credit.Amount
+	return nil
+}
+
+// Credits returns the credits applied so far, keyed by reference.
+func (f *FakeMerchantClient) Credits() map[string]BalanceCredit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return maps.Clone(f.credits)
+}
+
+// Deductions returns the deductions made so far.
+func (f *FakeMerchantClient) Deductions() []dto.DeductBalanceRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]dto.DeductBalanceRequest(nil), f.deductions...)
+}
+
+func balanceKey(merchantID int, currency string) string {
+	return fmt.Sprintf("%d/%s", merchantID, currency)
+}
+
+// FakeTransactionClient is an in-memory TransactionClient that records every transaction.
+type FakeTransactionClient struct {
+	mu           sync.Mutex
+	transactions []TransactionRequest
+
+	// Err, when set, is returned by RecordTransaction.
+	Err error
+}
+
+func NewFakeTransactionClient() *FakeTransactionClient {
+	return &FakeTransactionClient{}
+}
+
+func (f *FakeTransactionClient) RecordTransaction(_ context.Context, req TransactionRequest) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.transactions = append(f.transactions, req)
+	return nil
+}
+
+// Transactions returns the transactions recorded so far.
+func (f *FakeTransactionClient) Transactions() []TransactionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]TransactionRequest(nil), f.transactions...)
+}
+
+// FakeNotificationClient is an in-memory NotificationClient that records every notification.
+type FakeNotificationClient struct {
+	mu            sync.Mutex
+	notifications []Notification
+
+	// Err, when set, is returned by NotifyMerchant.
+	Err error
+}
+
+func NewFakeNotificationClient() *FakeNotificationClient {
+	return &FakeNotificationClient{}
+}
+
+func (f *FakeNotificationClient) NotifyMerchant(_ context.Context, n Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.notifications = append(f.notifications, n)
+	return nil
+}
+
+// Notifications returns the notifications sent so far.
+func (f *FakeNotificationClient) Notifications() []Notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Notification(nil), f.notifications...)
+}
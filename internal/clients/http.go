@@ -0,0 +1,199 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/httpclient"
+	"github.com/kodra-pay/payout-service/internal/metrics"
+)
+
+// HTTPMerchantClient calls merchant-service over HTTP.
+type HTTPMerchantClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewHTTPMerchantClient builds a client for merchant-service at baseURL. client should be
+// the shared downstream client (see httpclient.New).
+func NewHTTPMerchantClient(client *http.Client, baseURL string) *HTTPMerchantClient {
+	return &HTTPMerchantClient{client: client, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (c *HTTPMerchantClient) AvailableBalance(ctx context.Context, merchantID int, currency string) (_ Balance, err error) {
+	defer observe(metrics.DependencyMerchantService, "get_balance", time.Now(), &err)
+
+	endpoint := fmt.Sprintf("%s/merchants/%d/balance?currency=%s", c.baseURL, merchantID, url.QueryEscape(currency))
+	var payload struct {
+		AvailableBalance float64 `json:"available_balance"`
+	}
+	if err := do(ctx, c.client, metrics.DependencyMerchantService, http.MethodGet, endpoint, "", nil, &payload, http.StatusOK); err != nil {
+		return Balance{}, err
+	}
+	return Balance{MerchantID: merchantID, Currency: currency, Available: toMinor(payload.AvailableBalance)}, nil
+}
+
+func (c *HTTPMerchantClient) DeductBalance(ctx context.Context, req dto.DeductBalanceRequest) (err error) {
+	defer observe(metrics.DependencyMerchantService, "deduct_balance", time.Now(), &err)
+
+	body := struct {
+		MerchantID int     `json:"merchant_id"`
+		Currency   string  `json:"currency"`
+		Amount     float64 `json:"amount"`
+	}{req.MerchantID, req.Currency, toMajor(req.Amount)}
+	err = do(ctx, c.client, metrics.DependencyMerchantService, http.MethodPost, c.baseURL+"/internal/balance/payout", "", body, nil, http.StatusOK, http.StatusNoContent)
+	// merchant-service refuses deductions the available balance doesn't cover with 402 or 422.
+	var status *StatusError
+	if errors.As(err, &status) && (status.StatusCode == http.StatusPaymentRequired || status.StatusCode == http.StatusUnprocessableEntity) {
+		return fmt.Errorf("%w: %w", ErrInsufficientBalance, err)
+	}
+	return err
+}
+
+// CreditBalance sends the credit reference as the Idempotency-Key, which also lets the
+// shared client retry the call.
+func (c *HTTPMerchantClient) CreditBalance(ctx context.Context, credit BalanceCredit) (err error) {
+	defer observe(metrics.DependencyMerchantService, "credit_balance", time.Now(), &err)
+
+	body := struct {
+		MerchantID int     `json:"merchant_id"`
+		Currency   string  `json:"currency"`
+		Amount     float64 `json:"amount"`
+		Reference  string  `json:"reference"`
+	}{credit.MerchantID, credit.Currency, toMajor(credit.Amount), credit.Reference}
+	return do(ctx, c.client, metrics.DependencyMerchantService, http.MethodPost, c.baseURL+"/internal/balance/payout-return", credit.Reference, body, nil, http.StatusOK, http.StatusNoContent)
+}
+
+// HTTPTransactionClient calls transaction-service over HTTP.
+type HTTPTransactionClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewHTTPTransactionClient(client *http.Client, baseURL string) *HTTPTransactionClient {
+	return &HTTPTransactionClient{client: client, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (c *HTTPTransactionClient) RecordTransaction(ctx context.Context, req TransactionRequest) (err error) {
+	defer observe(metrics.DependencyTransactionService, "record_transaction", time.Now(), &err)
+	if c.baseURL == "" {
+		return fmt.Errorf("transaction service URL not configured")
+	}
+
+	body := struct {
+		Reference     string  `json:"reference"`
+		MerchantID    int     `json:"merchant_id"`
+		Amount        float64 `json:"amount"`
+		Currency      string  `json:"currency"`
+		PaymentMethod string  `json:"payment_method"`
+		Status        string  `json:"status"`
+		Description   string  `json:"description"`
+	}{req.Reference, req.MerchantID, toMajor(req.Amount), req.Currency, req.PaymentMethod, req.Status, req.Description}
+	return do(ctx, c.client, metrics.DependencyTransactionService, http.MethodPost, c.baseURL+"/transactions", "", body, nil, http.StatusOK, http.StatusCreated)
+}
+
+// HTTPNotificationClient calls notification-service over HTTP.
+type HTTPNotificationClient struct {
+	client  *http.Client
+	baseURL string
+}
+
+func NewHTTPNotificationClient(client *http.Client, baseURL string) *HTTPNotificationClient {
+	return &HTTPNotificationClient{client: client, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (c *HTTPNotificationClient) NotifyMerchant(ctx context.Context, n Notification) (err error) {
+	defer observe(metrics.DependencyNotificationService, "notify_merchant", time.Now(), &err)
+	if c.baseURL == "" {
+		return fmt.Errorf("notification service URL not configured")
+	}
+
+	body := struct {
+		MerchantID      int     `json:"merchant_id"`
+		Event           string  `json:"event"`
+		PayoutID        int     `json:"payout_id"`
+		PayoutReference string  `json:"payout_reference"`
+		Amount          float64 `json:"amount"`
+		Currency        string  `json:"currency"`
+		Message         string  `json:"message"`
+	}{n.MerchantID, n.Event, n.PayoutID, n.PayoutReference, toMajor(n.Amount), n.Currency, n.Message}
+	return do(ctx, c.client, metrics.DependencyNotificationService, http.MethodPost, c.baseURL+"/notifications", "", body, nil, http.StatusOK, http.StatusCreated, http.StatusAccepted)
+}
+
+// StatusError is returned when a dependency answers with a status the call didn't expect.
+type StatusError struct {
+	Dependency string
+	StatusCode int
+	Body       string // the start of the response body
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned %d: %s", e.Dependency, e.StatusCode, e.Body)
+}
+
+// do sends a JSON request and decodes a JSON response into out (when not nil). A non-empty
+// idempotencyKey is sent as the Idempotency-Key header. Any status outside expected is a
+// *StatusError.
+func do(ctx context.Context, client *http.Client, dependency, method, endpoint, idempotencyKey string, in, out any, expected ...int) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(httpclient.WithDependency(ctx, dependency), method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	ok := false
+	for _, code := range expected {
+		ok = ok || resp.StatusCode == code
+	}
+	if !ok {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &StatusError{Dependency: dependency, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(b))}
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("decode %s response: %w", dependency, err)
+		}
+	}
+	return nil
+}
+
+func observe(dependency, operation string, start time.Time, err *error) {
+	metrics.ObserveDownstream(dependency, operation, start, *err)
+}
+
+func toMinor(major float64) int64 {
+	return int64(math.Round(major * 100))
+}
+
+func toMajor(minor int64) float64 {
+	return float64(minor) / 100
+}
@@ -0,0 +1,83 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kodra-pay/payout-service/internal/dto"
+)
+
+func TestHTTPMerchantClientConvertsAmounts(t *testing.T) {
+	var deducted map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/merchants/7/balance":
+			if got := r.URL.Query().Get("currency"); got != "NGN" {
+				t.Errorf("currency = %q, want NGN", got)
+			}
+			_, _ = w.Write([]byte(`{"available_balance": 1234.56}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/internal/balance/payout":
+			if err := json.NewDecoder(r.Body).Decode(&deducted); err != nil {
+				t.Error(err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewHTTPMerchantClient(srv.Client(), srv.URL+"/")
+	balance, err := c.AvailableBalance(context.Background(), 7, "NGN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Available != 123456 {
+		t.Fatalf("available = %d, want 123456", balance.Available)
+	}
+
+	if err := c.DeductBalance(context.Background(), dto.DeductBalanceRequest{MerchantID: 7, Amount: 15025, Currency: "NGN"}); err != nil {
+		t.Fatal(err)
+	}
+	if deducted["amount"] != 150.25 || deducted["merchant_id"] != float64(7) || deducted["currency"] != "NGN" {
+		t.Fatalf("deduct body = %v", deducted)
+	}
+}
+
+func TestHTTPClientsReportUnexpectedStatuses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "ledger unavailable", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := NewHTTPTransactionClient(srv.Client(), srv.URL).RecordTransaction(context.Background(), TransactionRequest{Reference: "payout-1", Amount: 100})
+	if err == nil || !strings.Contains(err.Error(), "returned 500: ledger unavailable") {
+		t.Fatalf("got %v, want status error with body", err)
+	}
+}
+
+func TestHTTPMerchantClientReportsInsufficientBalance(t *testing.T) {
+	status := http.StatusUnprocessableEntity
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "balance too low", status)
+	}))
+	defer srv.Close()
+
+	c := NewHTTPMerchantClient(srv.Client(), srv.URL)
+	req := dto.DeductBalanceRequest{MerchantID: 7, Amount: 15025, Currency: "NGN"}
+	if err := c.DeductBalance(context.Background(), req); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("422: got %v, want ErrInsufficientBalance", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	err := c.DeductBalance(context.Background(), req)
+	var statusErr *StatusError
+	if errors.Is(err, ErrInsufficientBalance) || !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("503: got %v, want a StatusError", err)
+	}
+}
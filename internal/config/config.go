@@ -2,15 +2,106 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 type Config struct {
-	ServiceName        string
-	Port               string
-	PostgresDSN        string
-	MerchantServiceURL string
+	ServiceName           string
+	Port                  string
+	PostgresDSN           string
+	MerchantServiceURL    string
 	TransactionServiceURL string
+	// NotificationServiceURL receives merchant notifications, e.g. of returned payouts.
+	NotificationServiceURL string
+	// ReconDateToleranceDays is how far a statement booking date may drift from the payout completion date.
+	ReconDateToleranceDays int
+	// ExportDir holds files produced by background export jobs.
+	ExportDir string
+	// ExportAsyncThreshold is the row count above which exports run as background jobs.
+	ExportAsyncThreshold int
+	// PageSizeDefault and PageSizeMax bound the page size of payout listings.
+	PageSizeDefault int
+	PageSizeMax     int
+	// PayoutMaxAttempts bounds how many times a payout is attempted; merchants can retry
+	// retryable failures until it is reached.
+	PayoutMaxAttempts int
+	// PayoutFeeFlat (minor units of the payout currency) and PayoutFeeBasisPoints price each
+	// payout; the fee is debited from the merchant with the amount.
+	PayoutFeeFlat        int
+	PayoutFeeBasisPoints int
+	// ReturnFeePolicy is "refund" to return the fee with the amount of a returned or
+	// reversed payout, or "keep" to only return the amount.
+	ReturnFeePolicy string
+	// JWT bearer token verification. Secrets and key files are comma-separated, each
+	// optionally prefixed with a key ID ("kid:value").
+	JWTHMACSecrets    string
+	JWTPublicKeyFiles string
+	JWTIssuer         string
+	JWTAudience       string
+	// InternalPort serves the internal-only API (status updates and ops actions).
+	InternalPort string
+	// InternalHMACClients lists signed-request clients as "name:secret:role|role", comma-separated.
+	InternalHMACClients string
+	// InternalCertClients lists mTLS clients as "common-name:role|role", comma-separated.
+	InternalCertClients string
+	// InternalTLSCert, InternalTLSKey and InternalClientCA enable mTLS on the internal listener.
+	InternalTLSCert  string
+	InternalTLSKey   string
+	InternalClientCA string
+	// InternalNonceStore selects where signed-request nonces live: "memory" (single
+	// instance) or "postgres".
+	InternalNonceStore string
+	// RateLimitStore selects where counters live: "memory" (single instance) or "postgres".
+	RateLimitStore string
+	// RateLimitPerIP applies to every public request by client IP, e.g. "300/m".
+	RateLimitPerIP string
+	// RateLimitRoutes holds per-route limits per merchant: "POST /payouts=30/m,GET /payouts/export=5/m".
+	RateLimitRoutes string
+	// RateLimitPlans holds overall per-merchant limits by plan: "standard=120/m,growth=600/m".
+	RateLimitPlans       string
+	RateLimitDefaultPlan string
+	// RateLimitExempt lists IPs/CIDRs of internal callers that bypass rate limiting.
+	RateLimitExempt string
+	// ProxyHeader names the header the load balancer puts the client address in, e.g.
+	// "X-Real-IP". It is only read on connections from TrustedProxies, and must be a header
+	// the load balancer overwrites: the first address in it is used, so with
+	// "X-Forwarded-For" the balancer must replace, not append to, what the client sent.
+	// Empty uses the connection's peer address, which behind a load balancer is the
+	// balancer's for every client.
+	ProxyHeader string
+	// TrustedProxies lists the IPs/CIDRs of the load balancers, comma-separated.
+	TrustedProxies string
+	// FieldEncryptionKeys are the key encryption keys for recipient account numbers, as
+	// "version:base64key" entries, optionally also read from FieldEncryptionKeysFile.
+	FieldEncryptionKeys          string
+	FieldEncryptionKeysFile      string
+	FieldEncryptionActiveVersion int
+	// BlindIndexKey (base64, 32 bytes) keys the searchable hash of account numbers.
+	BlindIndexKey string
+	// LogLevel is debug, info, warn or error; LogFormat is "json" or "text".
+	LogLevel  string
+	LogFormat string
+	// TraceExporter is "none", "stdout" or "otlp"; the OTLP exporter reads the standard
+	// OTEL_EXPORTER_OTLP_* variables. TraceSampleRatio applies to traces started here.
+	TraceExporter    string
+	TraceSampleRatio float64
+	// SchemaVersionRequired is the lowest migration version the readiness probe accepts;
+	// -1 requires the latest embedded migration and 0 only reports the version.
+	SchemaVersionRequired int
+	// AutoMigrate applies pending migrations at start-up, before pending payouts are resumed.
+	AutoMigrate bool
+	// ShutdownTimeoutSeconds bounds how long shutdown waits for in-flight requests and
+	// background processing.
+	ShutdownTimeoutSeconds int
+	// Downstream HTTP client: overall and connect timeouts, retries of idempotent calls, and
+	// the per-dependency circuit breaker (consecutive failures to open, cooldown before a trial).
+	HTTPTimeoutMS          int
+	HTTPConnectTimeoutMS   int
+	HTTPMaxRetries         int
+	BreakerFailures        int
+	BreakerCooldownSeconds int
 }
 
 func Load(serviceName, defaultPort string) Config {
@@ -29,6 +120,55 @@ func Load(serviceName, defaultPort string) Config {
 		PostgresDSN:            dsn,
 		MerchantServiceURL:     getEnv("MERCHANT_SERVICE_URL", "http://merchant-service:7002"),
 		TransactionServiceURL:  getEnv("TRANSACTION_SERVICE_URL", "http://transaction-service:7004"),
+		NotificationServiceURL: getEnv("NOTIFICATION_SERVICE_URL", "http://notification-service:7006"),
+		ReconDateToleranceDays: getEnvInt("RECON_DATE_TOLERANCE_DAYS", 3),
+		ExportDir:              getEnv("EXPORT_DIR", filepath.Join(os.TempDir(), "payout-exports")),
+		ExportAsyncThreshold:   getEnvInt("EXPORT_ASYNC_THRESHOLD", 10000),
+		PageSizeDefault:        getEnvInt("PAGE_SIZE_DEFAULT", 20),
+		PageSizeMax:            getEnvInt("PAGE_SIZE_MAX", 100),
+		PayoutMaxAttempts:      getEnvInt("PAYOUT_MAX_ATTEMPTS", 3),
+		PayoutFeeFlat:          getEnvInt("PAYOUT_FEE_FLAT", 0),
+		PayoutFeeBasisPoints:   getEnvInt("PAYOUT_FEE_BASIS_POINTS", 0),
+		ReturnFeePolicy:        getEnv("RETURN_FEE_POLICY", "keep"),
+		JWTHMACSecrets:         os.Getenv("JWT_HMAC_SECRETS"),
+		JWTPublicKeyFiles:      os.Getenv("JWT_PUBLIC_KEY_FILES"),
+		JWTIssuer:              os.Getenv("JWT_ISSUER"),
+		JWTAudience:            os.Getenv("JWT_AUDIENCE"),
+		InternalPort:           getEnv("INTERNAL_PORT", "7109"),
+		InternalHMACClients:    os.Getenv("INTERNAL_HMAC_CLIENTS"),
+		InternalCertClients:    os.Getenv("INTERNAL_CERT_CLIENTS"),
+		InternalTLSCert:        os.Getenv("INTERNAL_TLS_CERT"),
+		InternalTLSKey:         os.Getenv("INTERNAL_TLS_KEY"),
+		InternalClientCA:       os.Getenv("INTERNAL_CLIENT_CA"),
+		InternalNonceStore:     getEnv("INTERNAL_NONCE_STORE", "memory"),
+		RateLimitStore:         getEnv("RATE_LIMIT_STORE", "memory"),
+		RateLimitPerIP:         getEnv("RATE_LIMIT_PER_IP", "300/m"),
+		RateLimitRoutes:        getEnv("RATE_LIMIT_ROUTES", "POST /payouts=60/m,GET /payouts/export=10/m,GET /payouts/search=60/m"),
+		RateLimitPlans:         getEnv("RATE_LIMIT_PLANS", "standard=120/m,growth=600/m,enterprise=3000/m"),
+		RateLimitDefaultPlan:   getEnv("RATE_LIMIT_DEFAULT_PLAN", "standard"),
+		RateLimitExempt:        os.Getenv("RATE_LIMIT_EXEMPT"),
+		ProxyHeader:            os.Getenv("PROXY_HEADER"),
+		TrustedProxies:         os.Getenv("TRUSTED_PROXIES"),
+
+		FieldEncryptionKeys:          os.Getenv("FIELD_ENCRYPTION_KEYS"),
+		FieldEncryptionKeysFile:      os.Getenv("FIELD_ENCRYPTION_KEYS_FILE"),
+		FieldEncryptionActiveVersion: getEnvInt("FIELD_ENCRYPTION_ACTIVE_VERSION", 0),
+		BlindIndexKey:                os.Getenv("BLIND_INDEX_KEY"),
+
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+		LogFormat: getEnv("LOG_FORMAT", "json"),
+
+		TraceExporter:    getEnv("OTEL_TRACES_EXPORTER", "none"),
+		TraceSampleRatio: getEnvFloat("OTEL_TRACES_SAMPLER_ARG", 1),
+
+		SchemaVersionRequired:  getEnvInt("SCHEMA_VERSION_REQUIRED", -1),
+		AutoMigrate:            getEnvBool("DB_AUTO_MIGRATE", false),
+		ShutdownTimeoutSeconds: getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
+		HTTPTimeoutMS:          getEnvInt("HTTP_TIMEOUT_MS", 10000),
+		HTTPConnectTimeoutMS:   getEnvInt("HTTP_CONNECT_TIMEOUT_MS", 2000),
+		HTTPMaxRetries:         getEnvInt("HTTP_MAX_RETRIES", 2),
+		BreakerFailures:        getEnvInt("BREAKER_FAILURES", 5),
+		BreakerCooldownSeconds: getEnvInt("BREAKER_COOLDOWN_SECONDS", 30),
 	}
 }
 
@@ -38,3 +178,30 @@ func getEnv(key, def string) string {
 	}
 	return def
 }
+
+func getEnvBool(key string, def bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+func getEnvFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+func getEnvInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,54 @@
+// Package correlation carries the request ID of the work being done through
+// context.Context, so it can be logged and forwarded to downstream services. Trace context
+// is handled by OpenTelemetry (see the tracing package).
+package correlation
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is used on inbound and outbound requests.
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// NewRequestID returns a fresh request ID.
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+// ValidRequestID reports whether an inbound X-Request-ID can be reused as is. Anything that
+// isn't a short token of printable characters is replaced to keep logs and headers clean.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID stored in ctx, or "" if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Start returns ctx with a request ID, generating one if it is missing. Background work
+// that isn't triggered by a request calls it so its downstream calls can still be
+// correlated.
+func Start(ctx context.Context) context.Context {
+	if RequestID(ctx) == "" {
+		ctx = WithRequestID(ctx, NewRequestID())
+	}
+	return ctx
+}
@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/kodra-pay/payout-service/internal/apperr"
+
+// ErrorResponse is the body of every error response.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Code      string              `json:"code"`
+	Message   string              `json:"message"`
+	Details   []apperr.FieldError `json:"details,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+}
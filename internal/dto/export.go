@@ -0,0 +1,36 @@
+package dto
+
+import "time"
+
+// PayoutExportRecord is one row of a payout export. Amounts are in currency units.
+type PayoutExportRecord struct {
+	ID                int        `json:"id"`
+	MerchantID        int        `json:"merchant_id"`
+	Reference         string     `json:"reference"`
+	Amount            float64    `json:"amount"`
+	Fee               float64    `json:"fee"`
+	Currency          string     `json:"currency"`
+	RecipientName     string     `json:"recipient_name"`
+	RecipientAccount  string     `json:"recipient_account"` // masked
+	RecipientBank     string     `json:"recipient_bank"`
+	Status            string     `json:"status"`
+	FailureCode       string     `json:"failure_code"` // set while failed, returned or reversed
+	FailureMessage    string     `json:"failure_message"`
+	Attempts          int        `json:"attempts"`
+	Narration         string     `json:"narration"`
+	ProviderReference string     `json:"provider_reference"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	CompletedAt       *time.Time `json:"completed_at"`
+}
+
+type ExportJobResponse struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	RowCount    int        `json:"row_count"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+}
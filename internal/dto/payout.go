@@ -1,8 +1,10 @@
 package dto
 
+import "time"
+
 type PayoutRequest struct {
 	MerchantID       int     `json:"merchant_id"`
-	Reference        int     `json:"reference"`
+	Reference        string  `json:"reference"`
 	Amount           float64 `json:"amount"` // currency units (e.g., NGN)
 	Currency         string  `json:"currency"`
 	RecipientName    string  `json:"recipient_name"`
@@ -12,13 +14,98 @@ type PayoutRequest struct {
 }
 
 type PayoutResponse struct {
-	ID        int     `json:"id"`
-	Reference int     `json:"reference,omitempty"`
-	Status    string  `json:"status"`
-	Amount    float64 `json:"amount"` // currency units (e.g., NGN)
-	Currency  string  `json:"currency"`
+	ID                int                   `json:"id"`
+	Reference         string                `json:"reference,omitempty"`
+	Status            string                `json:"status"`
+	Amount            float64               `json:"amount"` // currency units (e.g., NGN)
+	Fee               float64               `json:"fee"`    // currency units
+	Currency          string                `json:"currency"`
+	Recipient         PayoutRecipient       `json:"recipient"`
+	Narration         string                `json:"narration,omitempty"`
+	ProviderReference string                `json:"provider_reference,omitempty"`
+	Failure           *PayoutFailure        `json:"failure,omitempty"`
+	Attempts          int                   `json:"attempts"`
+	CreatedAt         time.Time             `json:"created_at"`
+	UpdatedAt         time.Time             `json:"updated_at"`
+	CompletedAt       *time.Time            `json:"completed_at,omitempty"`
+	Events            []PayoutEventResponse `json:"events,omitempty"` // only with expand=events
+}
+
+type PayoutRecipient struct {
+	Name    string `json:"name"`
+	Account string `json:"account"` // masked
+	Bank    string `json:"bank"`
 }
 
+// PayoutFailure says why a failed payout failed and whether the merchant may retry it.
+type PayoutFailure struct {
+	Code      string `json:"code"`
+	Message   string `json:"message,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+type PayoutEventResponse struct {
+	Type        string    `json:"type"`
+	FromStatus  string    `json:"from_status,omitempty"`
+	ToStatus    string    `json:"to_status"`
+	FailureCode string    `json:"failure_code,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	Attempt     int       `json:"attempt"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Relations GET /payouts/{id} returns only when asked to with the expand parameter.
+const ExpandEvents = "events"
+
 type PayoutStatusUpdateRequest struct {
-	Status string `json:"status"`
+	Status            string `json:"status"`
+	ProviderReference string `json:"provider_reference,omitempty"`
+	FailureCode       string `json:"failure_code,omitempty"` // only with status failed
+	FailureMessage    string `json:"failure_message,omitempty"`
+}
+
+// ListPayoutsRequest carries the paging and ordering options of GET /payouts.
+type ListPayoutsRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+	Order  string
+}
+
+type PayoutListResponse struct {
+	Data       []PayoutResponse `json:"data"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	HasMore    bool             `json:"has_more"`
+}
+
+// PayoutSearchResult keeps the flat recipient fields search results had before
+// PayoutResponse carried the recipient.
+type PayoutSearchResult struct {
+	PayoutResponse
+	RecipientName    string            `json:"recipient_name"`
+	RecipientAccount string            `json:"recipient_account"` // masked
+	RecipientBank    string            `json:"recipient_bank"`
+	Rank             float64           `json:"rank"`
+	Highlights       map[string]string `json:"highlights,omitempty"`
+}
+
+type PayoutSearchResponse struct {
+	Data []PayoutSearchResult `json:"data"`
+}
+
+type ForceFailPayoutRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ReversePayoutRequest struct {
+	Code   string `json:"code"` // defaults to recalled
+	Reason string `json:"reason"`
+}
+
+// PayoutReturnWebhook is the provider's report that the recipient's bank sent a completed
+// payout back.
+type PayoutReturnWebhook struct {
+	ProviderReference string `json:"provider_reference"`
+	Code              string `json:"code"`
+	Reason            string `json:"reason"`
 }
@@ -0,0 +1,31 @@
+package dto
+
+import "time"
+
+// PayoutSummaryBucket aggregates payouts sharing one key (a status, bank or period start).
+type PayoutSummaryBucket struct {
+	Key            string  `json:"key"`
+	Count          int     `json:"count"`
+	Volume         float64 `json:"volume"` // currency units (e.g., NGN)
+	CompletedCount int     `json:"completed_count"`
+	FailedCount    int     `json:"failed_count"`
+	SuccessRate    float64 `json:"success_rate"`
+}
+
+type PayoutCurrencySummary struct {
+	Currency string `json:"currency"`
+	PayoutSummaryBucket
+	AvgTimeToCompleteSeconds *float64              `json:"avg_time_to_complete_seconds"`
+	ByStatus                 []PayoutSummaryBucket `json:"by_status"`
+	ByBank                   []PayoutSummaryBucket `json:"by_bank"`
+	ByPeriod                 []PayoutSummaryBucket `json:"by_period"`
+}
+
+type PayoutSummaryResponse struct {
+	MerchantID int                     `json:"merchant_id"`
+	From       *time.Time              `json:"from,omitempty"`
+	To         *time.Time              `json:"to,omitempty"`
+	Interval   string                  `json:"interval"`
+	TotalCount int                     `json:"total_count"`
+	Currencies []PayoutCurrencySummary `json:"currencies"`
+}
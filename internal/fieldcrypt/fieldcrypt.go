@@ -0,0 +1,225 @@
+// Package fieldcrypt implements envelope encryption for sensitive columns.
+//
+// Every value is encrypted with its own random data encryption key (DEK) using AES-256-GCM.
+// The DEK is in turn encrypted ("wrapped") with a versioned key encryption key (KEK).
+// Rotating the KEK only requires re-wrapping DEKs, not re-encrypting the data.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const keySize = 32
+
+var ErrUnknownKeyVersion = errors.New("unknown key encryption key version")
+
+// Field is an encrypted value as stored in the database.
+type Field struct {
+	Ciphertext []byte
+	WrappedKey []byte
+	KeyVersion int
+}
+
+// Keyring holds the key encryption keys and the blind index key.
+type Keyring struct {
+	keks     map[int][]byte
+	active   int
+	indexKey []byte
+}
+
+// NewKeyring parses KEKs given as comma or newline separated "version:base64key" entries,
+// read from keys and, if set, from the file at keysFile. active selects the KEK used for
+// new values (0 means the highest version). indexKey is the base64 key of the blind index.
+func NewKeyring(keys, keysFile string, active int, indexKey string) (*Keyring, error) {
+	if keysFile != "" {
+		data, err := os.ReadFile(keysFile)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption keys file: %w", err)
+		}
+		keys += "\n" + string(data)
+	}
+
+	kr := &Keyring{keks: map[int][]byte{}}
+	for _, entry := range strings.FieldsFunc(keys, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		v, k, ok := strings.Cut(entry, ":")
+		version, err := strconv.Atoi(strings.TrimSpace(v))
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid encryption key entry: expected version:base64key")
+		}
+		key, err := decodeKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key version %d: %w", version, err)
+		}
+		kr.keks[version] = key
+		if version > kr.active && active == 0 {
+			kr.active = version
+		}
+	}
+	if len(kr.keks) == 0 {
+		return nil, fmt.Errorf("no field encryption keys configured")
+	}
+	if active != 0 {
+		if _, ok := kr.keks[active]; !ok {
+			return nil, fmt.Errorf("active encryption key version %d is not configured", active)
+		}
+		kr.active = active
+	}
+
+	idx, err := decodeKey(indexKey)
+	if err != nil {
+		return nil, fmt.Errorf("blind index key: %w", err)
+	}
+	kr.indexKey = idx
+	return kr, nil
+}
+
+// ActiveVersion is the KEK version used for new values.
+func (k *Keyring) ActiveVersion() int {
+	return k.active
+}
+
+// Encrypt seals plaintext under a fresh DEK wrapped with the active KEK.
+func (k *Keyring) Encrypt(plaintext string) (Field, error) {
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return Field{}, err
+	}
+	ct, err := seal(dek, []byte(plaintext))
+	if err != nil {
+		return Field{}, err
+	}
+	wrapped, err := seal(k.keks[k.active], dek)
+	if err != nil {
+		return Field{}, err
+	}
+	return Field{Ciphertext: ct, WrappedKey: wrapped, KeyVersion: k.active}, nil
+}
+
+// Decrypt unwraps the field's DEK and opens the ciphertext.
+func (k *Keyring) Decrypt(f Field) (string, error) {
+	dek, err := k.unwrap(f)
+	if err != nil {
+		return "", err
+	}
+	pt, err := open(dek, f.Ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("decrypt field: %w", err)
+	}
+	return string(pt), nil
+}
+
+// Rewrap re-encrypts the field's DEK with the active KEK, leaving the ciphertext untouched.
+func (k *Keyring) Rewrap(f Field) (Field, error) {
+	if f.KeyVersion == k.active {
+		return f, nil
+	}
+	dek, err := k.unwrap(f)
+	if err != nil {
+		return Field{}, err
+	}
+	wrapped, err := seal(k.keks[k.active], dek)
+	if err != nil {
+		return Field{}, err
+	}
+	return Field{Ciphertext: f.Ciphertext, WrappedKey: wrapped, KeyVersion: k.active}, nil
+}
+
+// BlindIndex returns a keyed hash of the normalized value, so equal values can be found
+// and deduplicated without decrypting. Whitespace and dashes are ignored.
+func (k *Keyring) BlindIndex(value string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '\t' {
+			return -1
+		}
+		return r
+	}, value)
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(normalized))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (k *Keyring) unwrap(f Field) ([]byte, error) {
+	kek, ok := k.keks[f.KeyVersion]
+	if !ok {
+		return nil, fmt.Errorf("%w %d", ErrUnknownKeyVersion, f.KeyVersion)
+	}
+	dek, err := open(kek, f.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	return dek, nil
+}
+
+// seal encrypts with AES-256-GCM and prefixes the random nonce.
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func decodeKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key is not valid base64")
+	}
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	return key, nil
+}
+
+// Mask hides all but the last four characters of an account number ("******1234").
+func Mask(account string) string {
+	if len(account) <= 4 {
+		return strings.Repeat("*", len(account))
+	}
+	return strings.Repeat("*", 6) + account[len(account)-4:]
+}
+
+// Last4 returns the unmasked suffix shown by Mask.
+func Last4(account string) string {
+	if len(account) <= 4 {
+		return ""
+	}
+	return account[len(account)-4:]
+}
@@ -0,0 +1,183 @@
+package fieldcrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	key1     = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", keySize)))
+	key2     = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", keySize)))
+	indexKey = base64.StdEncoding.EncodeToString([]byte(strings.Repeat("i", keySize)))
+)
+
+func keyring(t *testing.T, keys string, active int, index string) *Keyring {
+	t.Helper()
+	kr, err := NewKeyring(keys, "", active, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	kr := keyring(t, "1:"+key1, 0, indexKey)
+	for _, plaintext := range []string{"0123456789", "", "ünïcode 🏦"} {
+		f, err := kr.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.KeyVersion != 1 {
+			t.Fatalf("key version = %d, want 1", f.KeyVersion)
+		}
+		if plaintext != "" && strings.Contains(string(f.Ciphertext), plaintext) {
+			t.Fatal("ciphertext contains the plaintext")
+		}
+		got, err := kr.Decrypt(f)
+		if err != nil || got != plaintext {
+			t.Fatalf("Decrypt = %q, %v; want %q", got, err, plaintext)
+		}
+	}
+
+	// Every value gets its own data key and nonce.
+	a, _ := kr.Encrypt("0123456789")
+	b, _ := kr.Encrypt("0123456789")
+	if string(a.Ciphertext) == string(b.Ciphertext) || string(a.WrappedKey) == string(b.WrappedKey) {
+		t.Fatal("encrypting the same value twice gave the same field")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := keyring(t, "1:"+key1, 0, indexKey)
+	f, err := old.Encrypt("0123456789")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Version 2 is added and becomes active; version 1 stays until everything is rewrapped.
+	rotated := keyring(t, "1:"+key1+"\n2:"+key2, 0, indexKey)
+	if rotated.ActiveVersion() != 2 {
+		t.Fatalf("active version = %d, want 2", rotated.ActiveVersion())
+	}
+	if got, err := rotated.Decrypt(f); err != nil || got != "0123456789" {
+		t.Fatalf("old field under the rotated keyring: %q, %v", got, err)
+	}
+	rewrapped, err := rotated.Rewrap(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rewrapped.KeyVersion != 2 || string(rewrapped.Ciphertext) != string(f.Ciphertext) {
+		t.Fatalf("rewrapped = %+v, want version 2 and the same ciphertext", rewrapped)
+	}
+	if again, err := rotated.Rewrap(rewrapped); err != nil || string(again.WrappedKey) != string(rewrapped.WrappedKey) {
+		t.Fatalf("rewrapping a current field changed it: %v", err)
+	}
+
+	// With version 1 retired only rewrapped fields can be read.
+	retired := keyring(t, "2:"+key2, 0, indexKey)
+	if got, err := retired.Decrypt(rewrapped); err != nil || got != "0123456789" {
+		t.Fatalf("rewrapped field after retiring version 1: %q, %v", got, err)
+	}
+	if _, err := retired.Decrypt(f); !errors.Is(err, ErrUnknownKeyVersion) {
+		t.Fatalf("old field after retiring version 1: got %v, want ErrUnknownKeyVersion", err)
+	}
+
+	// An explicit active version keeps new values on version 1 until it is switched.
+	pinned := keyring(t, "1:"+key1+",2:"+key2, 1, indexKey)
+	if f, _ := pinned.Encrypt("x"); f.KeyVersion != 1 {
+		t.Fatalf("pinned keyring encrypted under version %d", f.KeyVersion)
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	kr := keyring(t, "1:"+key1, 0, indexKey)
+	f, err := kr.Encrypt("0123456789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flip := func(b []byte, i int) []byte {
+		out := append([]byte(nil), b...)
+		out[i] ^= 0x01
+		return out
+	}
+	other, _ := kr.Encrypt("9876543210")
+
+	tests := []struct {
+		name  string
+		field Field
+	}{
+		{"ciphertext", Field{Ciphertext: flip(f.Ciphertext, len(f.Ciphertext)-1), WrappedKey: f.WrappedKey, KeyVersion: 1}},
+		{"nonce", Field{Ciphertext: flip(f.Ciphertext, 0), WrappedKey: f.WrappedKey, KeyVersion: 1}},
+		{"wrapped key", Field{Ciphertext: f.Ciphertext, WrappedKey: flip(f.WrappedKey, len(f.WrappedKey)-1), KeyVersion: 1}},
+		{"another value's data key", Field{Ciphertext: f.Ciphertext, WrappedKey: other.WrappedKey, KeyVersion: 1}},
+		{"truncated", Field{Ciphertext: f.Ciphertext[:4], WrappedKey: f.WrappedKey, KeyVersion: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := kr.Decrypt(tt.field); err == nil {
+				t.Fatalf("Decrypt = %q, want an authentication error", got)
+			}
+		})
+	}
+
+	wrongKEK := keyring(t, "1:"+key2, 0, indexKey)
+	if _, err := wrongKEK.Decrypt(f); err == nil {
+		t.Fatal("decrypted with the wrong key encryption key")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	kr := keyring(t, "1:"+key1, 0, indexKey)
+	same := keyring(t, "2:"+key2, 0, indexKey)
+	other := keyring(t, "1:"+key1, 0, base64.StdEncoding.EncodeToString([]byte(strings.Repeat("j", keySize))))
+
+	idx := kr.BlindIndex("0123456789")
+	if len(idx) != 64 || idx != kr.BlindIndex("0123456789") {
+		t.Fatalf("index %q is not a stable hex SHA-256", idx)
+	}
+	if kr.BlindIndex("0123-456 789") != idx || kr.BlindIndex("\t0123456789") != idx {
+		t.Fatal("formatting changed the index")
+	}
+	if kr.BlindIndex("0123456788") == idx {
+		t.Fatal("different accounts share an index")
+	}
+	// The index depends on the index key only, so KEK rotation doesn't change it.
+	if same.BlindIndex("0123456789") != idx {
+		t.Fatal("index changed with the key encryption key")
+	}
+	if other.BlindIndex("0123456789") == idx {
+		t.Fatal("index doesn't depend on the index key")
+	}
+}
+
+func TestNewKeyringRejectsBadKeys(t *testing.T) {
+	tests := []struct {
+		name, keys string
+		active     int
+		index      string
+	}{
+		{"no keys", "", 0, indexKey},
+		{"missing version", key1, 0, indexKey},
+		{"bad base64", "1:not-base64!", 0, indexKey},
+		{"short key", "1:" + base64.StdEncoding.EncodeToString([]byte("short")), 0, indexKey},
+		{"unknown active version", "1:" + key1, 2, indexKey},
+		{"bad index key", "1:" + key1, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewKeyring(tt.keys, "", tt.active, tt.index); err == nil {
+				t.Fatal("want an error")
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	for in, want := range map[string]string{"0123456789": "******6789", "1234": "****", "": ""} {
+		if got := Mask(in); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
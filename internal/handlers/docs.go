@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/openapi"
+)
+
+// DocsHandler serves an OpenAPI document and the reference page rendering it.
+type DocsHandler struct {
+	spec []byte
+}
+
+func NewDocsHandler(spec []byte) *DocsHandler { return &DocsHandler{spec: spec} }
+
+func (h *DocsHandler) Register(r fiber.Router) {
+	r.Get("/openapi.json", h.Spec)
+	r.Get("/docs", h.Docs)
+}
+
+func (h *DocsHandler) Spec(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(h.spec)
+}
+
+func (h *DocsHandler) Docs(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(openapi.DocsHTML())
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"net/url"
+
 	"github.com/gofiber/fiber/v2"
 
 	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/middleware"
 	"github.com/kodra-pay/payout-service/internal/services"
 )
 
@@ -18,49 +21,106 @@ func (h *PayoutHandler) Create(c *fiber.Ctx) error {
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
 	}
-	resp, err := h.svc.Create(c.Context(), req)
+	// Payouts are always created for the authenticated merchant, whatever the body says.
+	req.MerchantID = middleware.MerchantID(c)
+	resp, err := h.svc.Create(c.UserContext(), req)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 	return c.Status(fiber.StatusCreated).JSON(resp)
 }
 
 func (h *PayoutHandler) Get(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
+	id, err := payoutIDParam(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.Get(c.UserContext(), middleware.MerchantID(c), id, parseExpand(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// GetByReference looks a payout up by the merchant's own reference.
+func (h *PayoutHandler) GetByReference(c *fiber.Ctx) error {
+	reference, err := url.PathUnescape(c.Params("reference"))
+	if err != nil {
+		return invalidParam("reference", "must be a valid path segment")
+	}
+	resp, err := h.svc.GetByReference(c.UserContext(), middleware.MerchantID(c), reference, parseExpand(c))
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid payout ID")
+		return err
 	}
-	return c.JSON(h.svc.Get(c.Context(), id))
+	return c.JSON(resp)
 }
 
 func (h *PayoutHandler) List(c *fiber.Ctx) error {
-	merchantID := c.QueryInt("merchant_id", 0) // Use c.QueryInt for query parameters
-	if merchantID == 0 {
-		return fiber.NewError(fiber.StatusBadRequest, "merchant_id query parameter is required")
+	filter, err := parsePayoutFilter(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.List(c.UserContext(), filter, dto.ListPayoutsRequest{
+		Limit:  c.QueryInt("limit", 0),
+		Cursor: c.Query("cursor"),
+		Sort:   c.Query("sort"),
+		Order:  c.Query("order"),
+	})
+	if err != nil {
+		return err
 	}
-	return c.JSON(h.svc.List(c.Context(), merchantID))
+	return c.JSON(resp)
 }
 
-func (h *PayoutHandler) Cancel(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
+// Search ranks payouts against the free-text "q" parameter; the listing filters also apply.
+func (h *PayoutHandler) Search(c *fiber.Ctx) error {
+	filter, err := parsePayoutFilter(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.Search(c.UserContext(), filter, c.Query("q"), c.QueryInt("limit", 0))
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid payout ID")
+		return err
 	}
-	return c.JSON(h.svc.Cancel(c.Context(), id))
+	return c.JSON(resp)
 }
 
-func (h *PayoutHandler) UpdateStatus(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
+// Summary returns aggregate counts and volumes for the filtered payouts; "interval" picks
+// the period bucket size (day, week or month).
+func (h *PayoutHandler) Summary(c *fiber.Ctx) error {
+	filter, err := parsePayoutFilter(c)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "Invalid payout ID")
+		return err
 	}
-	var req dto.PayoutStatusUpdateRequest
-	if err := c.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	resp, err := h.svc.Summary(c.UserContext(), filter, c.Query("interval"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// Retry starts another attempt of a payout that failed for a retryable reason.
+func (h *PayoutHandler) Retry(c *fiber.Ctx) error {
+	id, err := payoutIDParam(c)
+	if err != nil {
+		return err
+	}
+	resp, err := h.svc.RetryFailed(c.UserContext(), middleware.MerchantID(c), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// Cancel withdraws a payout that is still pending.
+func (h *PayoutHandler) Cancel(c *fiber.Ctx) error {
+	id, err := payoutIDParam(c)
+	if err != nil {
+		return err
 	}
-	resp, err := h.svc.UpdateStatus(c.Context(), id, req.Status)
+	resp, err := h.svc.Cancel(c.UserContext(), middleware.MerchantID(c), id)
 	if err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return err
 	}
 	return c.JSON(resp)
 }
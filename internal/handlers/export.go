@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/middleware"
+	"github.com/kodra-pay/payout-service/internal/services"
+)
+
+type ExportHandler struct {
+	svc *services.ExportService
+}
+
+func NewExportHandler(svc *services.ExportService) *ExportHandler { return &ExportHandler{svc: svc} }
+
+// Export streams matching payouts as CSV or JSON Lines. Large result sets (or async=true)
+// are handed to a background job and answered with 202 and the job status.
+func (h *ExportHandler) Export(c *fiber.Ctx) error {
+	filter, err := parsePayoutFilter(c)
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(c.Query("format", services.ExportFormatCSV))
+	contentType, ext, err := services.ExportContentType(format)
+	if err != nil {
+		return err
+	}
+
+	background := c.QueryBool("async", false)
+	if !background {
+		if background, err = h.svc.NeedsBackgroundJob(c.UserContext(), filter); err != nil {
+			return err
+		}
+	}
+	if background {
+		job, err := h.svc.StartJob(c.UserContext(), format, filter)
+		if err != nil {
+			return err
+		}
+		return c.Status(fiber.StatusAccepted).JSON(job)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Attachment(fmt.Sprintf("payouts-%s.%s", time.Now().UTC().Format("20060102-150405"), ext))
+	// The stream writer runs after the handler returns, so it keeps the request's values
+	// (for logging) but not its cancellation.
+	ctx := context.WithoutCancel(c.UserContext())
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if _, err := h.svc.Write(ctx, w, format, filter); err != nil {
+			slog.ErrorContext(ctx, "payout export aborted", slog.Any("error", err))
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
+
+func (h *ExportHandler) GetJob(c *fiber.Ctx) error {
+	job, err := h.svc.GetJob(c.UserContext(), c.Params("id"), middleware.MerchantID(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(job)
+}
+
+func (h *ExportHandler) Download(c *fiber.Ctx) error {
+	path, name, err := h.svc.JobFile(c.UserContext(), c.Params("id"), middleware.MerchantID(c))
+	if err != nil {
+		return err
+	}
+	return c.Download(path, name)
+}
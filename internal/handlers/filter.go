@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/logging"
+	"github.com/kodra-pay/payout-service/internal/middleware"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// payoutIDParam reads the :id route parameter and attaches it to the request's log context
+// and span.
+func payoutIDParam(c *fiber.Ctx) (int, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Invalid payout ID")
+	}
+	tracing.SetAttributes(c.UserContext(), tracing.PayoutIDKey.Int(id))
+	c.SetUserContext(logging.With(c.UserContext(), slog.Int("payout_id", id)))
+	return id, nil
+}
+
+// parseExpand reads the comma-separated expand query parameter; the service rejects names
+// it doesn't know.
+func parseExpand(c *fiber.Ctx) []string {
+	var expand []string
+	for _, e := range strings.Split(c.Query("expand"), ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			expand = append(expand, e)
+		}
+	}
+	return expand
+}
+
+// parsePayoutFilter reads the payout filter query parameters shared by the listing and export
+// endpoints. The merchant always comes from the authenticated caller; a merchant_id query
+// parameter is ignored. Invalid parameters are reported as a validation error naming them.
+func parsePayoutFilter(c *fiber.Ctx) (repositories.PayoutFilter, error) {
+	f := repositories.PayoutFilter{MerchantID: middleware.MerchantID(c)}
+	if f.MerchantID == 0 {
+		return f, fiber.NewError(fiber.StatusUnauthorized, "authenticated merchant is required")
+	}
+
+	if v := c.Query("status"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.ToLower(strings.TrimSpace(s))
+			if s == "" {
+				continue
+			}
+			f.Statuses = append(f.Statuses, s)
+			// Payouts are stored as "processed" but displayed as "completed".
+			if s == "completed" {
+				f.Statuses = append(f.Statuses, "processed")
+			}
+		}
+	}
+	f.Currency = strings.ToUpper(strings.TrimSpace(c.Query("currency")))
+	f.RecipientAccount = strings.TrimSpace(c.Query("recipient_account"))
+	if v := strings.TrimSpace(c.Query("reference")); v != "" {
+		f.Reference = &v
+	}
+
+	var err error
+	if f.MinAmount, err = parseAmountParam(c.Query("min_amount")); err != nil {
+		return f, invalidParam("min_amount", err.Error())
+	}
+	if f.MaxAmount, err = parseAmountParam(c.Query("max_amount")); err != nil {
+		return f, invalidParam("max_amount", err.Error())
+	}
+	if f.CreatedFrom, err = parseTimeParam(c.Query("from", c.Query("created_from")), false); err != nil {
+		return f, invalidParam("from", err.Error())
+	}
+	if f.CreatedTo, err = parseTimeParam(c.Query("to", c.Query("created_to")), true); err != nil {
+		return f, invalidParam("to", err.Error())
+	}
+	if f.UpdatedFrom, err = parseTimeParam(c.Query("updated_from"), false); err != nil {
+		return f, invalidParam("updated_from", err.Error())
+	}
+	if f.UpdatedTo, err = parseTimeParam(c.Query("updated_to"), true); err != nil {
+		return f, invalidParam("updated_to", err.Error())
+	}
+	return f, nil
+}
+
+func invalidParam(name, message string) error {
+	return apperr.Validation("invalid "+name, apperr.Field(name, message))
+}
+
+// parseAmountParam converts an amount in currency units to minor units. NaN, infinities
+// and amounts too large for minor units to fit in an int64 are rejected.
+func parseAmountParam(v string) (*int64, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) || amount < 0 {
+		return nil, fmt.Errorf("expected a non-negative number")
+	}
+	// float64(math.MaxInt64) rounds up to 2^63, which is itself out of range.
+	rounded := math.Round(amount * 100)
+	if rounded >= math.MaxInt64 {
+		return nil, fmt.Errorf("amount is too large")
+	}
+	minor := int64(rounded)
+	return &minor, nil
+}
+
+// parseTimeParam accepts RFC 3339 timestamps or plain dates. A plain date used as an
+// upper bound covers the whole day, so "to=2026-01-31" includes payouts on the 31st.
+func parseTimeParam(v string, upper bool) (*time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, fmt.Errorf("expected YYYY-MM-DD or RFC 3339 timestamp")
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
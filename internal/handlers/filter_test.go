@@ -0,0 +1,45 @@
+package handlers
+
+import "testing"
+
+func TestParseAmountParam(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "150.25", want: 15025},
+		{in: " 0 ", want: 0},
+		{in: "0.005", want: 1},
+		{in: "1000000000000.50", want: 100000000000050},
+		{in: "-1", wantErr: true},
+		{in: "ten", wantErr: true},
+		{in: "NaN", wantErr: true},
+		{in: "nan", wantErr: true},
+		{in: "Inf", wantErr: true},
+		{in: "+Inf", wantErr: true},
+		{in: "-Inf", wantErr: true},
+		{in: "infinity", wantErr: true},
+		{in: "1e400", wantErr: true},
+		{in: "1e17", wantErr: true},
+		{in: "92233720368547758.07", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseAmountParam(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %d, want an error", *got)
+				}
+				return
+			}
+			if err != nil || got == nil || *got != tt.want {
+				t.Fatalf("got %v, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+
+	if got, err := parseAmountParam(""); got != nil || err != nil {
+		t.Fatalf("empty: got %v, %v; want no bound", got, err)
+	}
+}
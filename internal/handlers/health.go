@@ -1,19 +1,45 @@
 package handlers
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/health"
+)
 
 type HealthHandler struct {
 	Service string
+	checker *health.Checker
 }
 
-func NewHealthHandler(service string) *HealthHandler {
-	return &HealthHandler{Service: service}
+// NewHealthHandler builds the probe handlers. checker holds the readiness checks.
+func NewHealthHandler(service string, checker *health.Checker) *HealthHandler {
+	return &HealthHandler{Service: service, checker: checker}
 }
 
 func (h *HealthHandler) Register(r fiber.Router) {
 	r.Get("/health", h.Health)
+	r.Get("/health/live", h.Live)
+	r.Get("/health/ready", h.Ready)
 }
 
+// Health is kept for existing callers; it only reports that the process is up.
 func (h *HealthHandler) Health(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"status": "ok", "service": h.Service})
 }
+
+// Live reports that the process is running and able to serve requests. It doesn't check
+// dependencies, so an outage elsewhere never gets the service restarted.
+func (h *HealthHandler) Live(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"status": "alive", "service": h.Service})
+}
+
+// Ready runs every dependency check and answers 503 while any of them fails, so the
+// instance is taken out of rotation until it can serve payouts.
+func (h *HealthHandler) Ready(c *fiber.Ctx) error {
+	ok, checks := h.checker.Run(c.UserContext())
+	status, code := "ready", fiber.StatusOK
+	if !ok {
+		status, code = "degraded", fiber.StatusServiceUnavailable
+	}
+	return c.Status(code).JSON(fiber.Map{"status": status, "service": h.Service, "checks": checks})
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/health"
+)
+
+func TestReadinessFailsWithDependencyWhileLivenessPasses(t *testing.T) {
+	var merchantErr error
+	checker := health.NewChecker(time.Second)
+	checker.Add("database", func(context.Context) (string, error) { return "", nil })
+	checker.Add("merchant-service", func(context.Context) (string, error) { return "", merchantErr })
+
+	app := fiber.New()
+	NewHealthHandler("payout-service", checker).Register(app)
+	probe := func(path string) (int, map[string]any) {
+		t.Helper()
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, body
+	}
+
+	if code, body := probe("/health/ready"); code != fiber.StatusOK || body["status"] != "ready" {
+		t.Fatalf("ready with healthy dependencies: %d %v", code, body)
+	}
+
+	merchantErr = errors.New("circuit breaker is open")
+	code, body := probe("/health/ready")
+	if code != fiber.StatusServiceUnavailable || body["status"] != "degraded" {
+		t.Fatalf("ready with a failing dependency: %d %v, want 503 degraded", code, body)
+	}
+	checks, _ := body["checks"].(map[string]any)
+	merchant, _ := checks["merchant-service"].(map[string]any)
+	database, _ := checks["database"].(map[string]any)
+	if merchant["status"] != health.StatusDown || merchant["error"] != "circuit breaker is open" || database["status"] != health.StatusUp {
+		t.Fatalf("checks = %v", checks)
+	}
+
+	for _, path := range []string{"/health/live", "/health"} {
+		if code, _ := probe(path); code != fiber.StatusOK {
+			t.Fatalf("%s with a failing dependency: %d, want 200", path, code)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/middleware"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+	"github.com/kodra-pay/payout-service/internal/services"
+)
+
+// InternalPayoutHandler serves the service-to-service and ops endpoints, which are only
+// mounted on the internal listener.
+type InternalPayoutHandler struct {
+	svc *services.PayoutService
+}
+
+func NewInternalPayoutHandler(svc *services.PayoutService) *InternalPayoutHandler {
+	return &InternalPayoutHandler{svc: svc}
+}
+
+func (h *InternalPayoutHandler) UpdateStatus(c *fiber.Ctx) error {
+	id, err := payoutIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.PayoutStatusUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.UpdateStatus(c.UserContext(), id, req.Status, repositories.StatusUpdate{
+		ProviderReference: req.ProviderReference,
+		FailureCode:       req.FailureCode,
+		FailureMessage:    req.FailureMessage,
+	})
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+func (h *InternalPayoutHandler) ForceFail(c *fiber.Ctx) error {
+	id, err := payoutIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.ForceFailPayoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return apperr.Validation("reason is required", apperr.Field("reason", "is required"))
+	}
+	caller, _ := middleware.ServiceCaller(c)
+	resp, err := h.svc.ForceFail(c.UserContext(), id, caller.Name, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+func (h *InternalPayoutHandler) Retry(c *fiber.Ctx) error {
+	id, err := payoutIDParam(c)
+	if err != nil {
+		return err
+	}
+	caller, _ := middleware.ServiceCaller(c)
+	resp, err := h.svc.Retry(c.UserContext(), id, caller.Name)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+func (h *InternalPayoutHandler) Reverse(c *fiber.Ctx) error {
+	id, err := payoutIDParam(c)
+	if err != nil {
+		return err
+	}
+	var req dto.ReversePayoutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return apperr.Validation("reason is required", apperr.Field("reason", "is required"))
+	}
+	caller, _ := middleware.ServiceCaller(c)
+	resp, err := h.svc.Reverse(c.UserContext(), id, caller.Name, req.Code, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+// ReportReturn receives the provider's webhook for payouts the recipient's bank returned.
+func (h *InternalPayoutHandler) ReportReturn(c *fiber.Ctx) error {
+	var req dto.PayoutReturnWebhook
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.ReportReturn(c.UserContext(), req.ProviderReference, req.Code, req.Reason)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/services"
+	"github.com/kodra-pay/payout-service/internal/statements"
+)
+
+type ReconciliationHandler struct {
+	svc *services.ReconciliationService
+}
+
+func NewReconciliationHandler(svc *services.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{svc: svc}
+}
+
+// Import accepts a multipart upload with the statement in "file" and an optional
+// "format" field (csv, camt053, mt940); the format is detected when omitted.
+func (h *ReconciliationHandler) Import(c *fiber.Ctx) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "statement file is required")
+	}
+
+	var format statements.Format
+	if v := c.FormValue("format"); v != "" {
+		if format, err = statements.ParseFormat(v); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "unable to read statement file")
+	}
+	defer f.Close()
+
+	resp, err := h.svc.Import(c.UserContext(), fh.Filename, format, f)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+func (h *ReconciliationHandler) GetRun(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid reconciliation run ID")
+	}
+	resp, err := h.svc.GetRun(c.UserContext(), id)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+func (h *ReconciliationHandler) ListExceptions(c *fiber.Ctx) error {
+	runID := c.QueryInt("run_id", 0)
+	includeResolved := c.QueryBool("include_resolved", false)
+	resp, err := h.svc.ListExceptions(c.UserContext(), runID, includeResolved)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
+
+func (h *ReconciliationHandler) ResolveException(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid reconciliation exception ID")
+	}
+	var req dto.ResolveReconciliationExceptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+	}
+	resp, err := h.svc.ResolveException(c.UserContext(), id, req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
+}
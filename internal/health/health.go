@@ -0,0 +1,116 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/httpclient"
+)
+
+// Check statuses.
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check reports whether one dependency is usable. The returned detail (e.g. a schema
+// version) is included in the report; an error marks the dependency as down.
+type Check func(ctx context.Context) (detail string, err error)
+
+// Result is the outcome of one check.
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Checker runs a named set of checks concurrently, each under its own timeout.
+type Checker struct {
+	timeout time.Duration
+	names   []string
+	checks  map[string]Check
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: map[string]Check{}}
+}
+
+// Add registers a check. Checks are reported under their name.
+func (c *Checker) Add(name string, check Check) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Run executes every check and reports whether all of them passed.
+func (c *Checker) Run(ctx context.Context) (bool, map[string]Result) {
+	results := make(map[string]Result, len(c.names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range c.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			res := c.run(ctx, check)
+			mu.Lock()
+			results[name] = res
+			mu.Unlock()
+		}(name, c.checks[name])
+	}
+	wg.Wait()
+
+	ok := true
+	for _, res := range results {
+		if res.Status != StatusUp {
+			ok = false
+		}
+	}
+	return ok, results
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check(ctx)
+	res := Result{
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    detail,
+	}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// HTTPCheck checks a downstream service by calling its /health endpoint. The call goes
+// through the dependency's circuit breaker, so an open circuit reports the service as down.
+func HTTPCheck(client *http.Client, dependency, baseURL string) Check {
+	url := strings.TrimRight(baseURL, "/") + "/health"
+	return func(ctx context.Context) (string, error) {
+		ctx = httpclient.WithDependency(ctx, dependency)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return "", fmt.Errorf("%s returned %d", url, resp.StatusCode)
+		}
+		return "", nil
+	}
+}
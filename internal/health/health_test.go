@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckerReportsFailingDependency(t *testing.T) {
+	c := NewChecker(20 * time.Millisecond)
+	c.Add("database", func(context.Context) (string, error) { return "schema 12", nil })
+	c.Add("merchant-service", func(context.Context) (string, error) { return "", errors.New("connection refused") })
+	c.Add("notification-service", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+
+	ok, results := c.Run(context.Background())
+	if ok {
+		t.Fatal("Run reported every check passing")
+	}
+	want := map[string]Result{
+		"database":             {Status: StatusUp, Detail: "schema 12"},
+		"merchant-service":     {Status: StatusDown, Error: "connection refused"},
+		"notification-service": {Status: StatusDown, Error: context.DeadlineExceeded.Error()},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for name, w := range want {
+		got := results[name]
+		if got.Status != w.Status || got.Detail != w.Detail || got.Error != w.Error {
+			t.Errorf("%s = %+v, want %+v", name, got, w)
+		}
+	}
+
+	// Replacing the failing checks brings the dependencies back up.
+	c.Add("merchant-service", func(context.Context) (string, error) { return "", nil })
+	c.Add("notification-service", func(context.Context) (string, error) { return "", nil })
+	if ok, results := c.Run(context.Background()); !ok || len(results) != 3 {
+		t.Fatalf("after recovery: %v, %v", ok, results)
+	}
+}
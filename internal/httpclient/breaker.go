@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/metrics"
+)
+
+// ErrCircuitOpen is matched (with errors.Is) by the error returned for calls rejected
+// because their dependency's circuit is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned without calling the dependency while its circuit is open.
+type CircuitOpenError struct {
+	Dependency string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s is unavailable (circuit breaker open), retry in %s", e.Dependency, (e.RetryAfter + time.Second - 1).Truncate(time.Second))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// Breaker states, also the values of the circuit breaker state gauge.
+const (
+	stateClosed = iota
+	stateHalfOpen
+	stateOpen
+)
+
+var stateNames = [...]string{stateClosed: "closed", stateHalfOpen: "half_open", stateOpen: "open"}
+
+// breakerTransport keeps one circuit breaker per dependency.
+type breakerTransport struct {
+	base     http.RoundTripper
+	failures int
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func newBreakerTransport(base http.RoundTripper, failures int, cooldown time.Duration) *breakerTransport {
+	return &breakerTransport{base: base, failures: failures, cooldown: cooldown, breakers: map[string]*breaker{}}
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := t.breaker(dependency(req))
+	if err := b.allow(time.Now()); err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	b.record(attemptResult(req, resp, err))
+	return resp, err
+}
+
+func (t *breakerTransport) breaker(name string) *breaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.breakers[name]
+	if !ok {
+		b = &breaker{name: name, threshold: t.failures, cooldown: t.cooldown}
+		metrics.BreakerState.WithLabelValues(name).Set(stateClosed)
+		t.breakers[name] = b
+	}
+	return b
+}
+
+// Results of an attempt as the breaker sees them.
+const (
+	attemptSucceeded = iota
+	attemptFailed
+	attemptCancelled
+)
+
+// attemptResult classifies an attempt. Client errors mean the dependency is up and
+// answering; a cancelled caller says nothing about it.
+func attemptResult(req *http.Request, resp *http.Response, err error) int {
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		return attemptCancelled
+	case err != nil, resp.StatusCode >= http.StatusInternalServerError:
+		return attemptFailed
+	}
+	return attemptSucceeded
+}
+
+// breaker opens after threshold consecutive failures. Once cooldown has passed it lets a
+// single trial call through (half-open): success closes the circuit, failure re-opens it.
+// A cancelled attempt leaves the state as it was; in half-open the next call is the trial.
+type breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func (b *breaker) allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if wait := b.cooldown - now.Sub(b.openedAt); wait > 0 {
+			return &CircuitOpenError{Dependency: b.name, RetryAfter: wait}
+		}
+		b.transition(stateHalfOpen)
+		b.trial = true
+		return nil
+	case stateHalfOpen:
+		if b.trial {
+			return &CircuitOpenError{Dependency: b.name, RetryAfter: time.Second}
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+func (b *breaker) record(result int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == stateHalfOpen {
+		b.trial = false
+		switch result {
+		case attemptFailed:
+			b.open()
+		case attemptSucceeded:
+			b.failures = 0
+			b.transition(stateClosed)
+		}
+		return
+	}
+	switch result {
+	case attemptCancelled:
+		return
+	case attemptSucceeded:
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == stateClosed && b.failures >= b.threshold {
+		b.open()
+	}
+}
+
+func (b *breaker) open() {
+	b.openedAt = time.Now()
+	b.transition(stateOpen)
+}
+
+func (b *breaker) transition(state int) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	metrics.BreakerState.WithLabelValues(b.name).Set(float64(state))
+	metrics.BreakerTransitions.WithLabelValues(b.name, stateNames[state]).Inc()
+}
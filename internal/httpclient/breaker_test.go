@@ -0,0 +1,159 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testCooldown = 50 * time.Millisecond
+
+// flakyServer answers with status, or holds requests until release is closed when hold is set.
+type flakyServer struct {
+	*httptest.Server
+	status  atomic.Int32
+	hold    atomic.Bool
+	calls   atomic.Int32
+	entered chan struct{}
+	release chan struct{}
+}
+
+func newFlakyServer(t *testing.T) *flakyServer {
+	s := &flakyServer{entered: make(chan struct{}, 1), release: make(chan struct{})}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+		if s.hold.Load() {
+			s.entered <- struct{}{}
+			select {
+			case <-s.release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(int(s.status.Load()))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func get(ctx context.Context, client *http.Client, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// openCircuit fails calls until the breaker opens, then waits out the cooldown.
+func openCircuit(t *testing.T, client *http.Client, srv *flakyServer) {
+	t.Helper()
+	srv.status.Store(http.StatusInternalServerError)
+	for i := 0; i < 2; i++ {
+		if status, err := get(context.Background(), client, srv.URL); err != nil || status != http.StatusInternalServerError {
+			t.Fatalf("call %d: %d, %v", i+1, status, err)
+		}
+	}
+	if _, err := get(context.Background(), client, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen once the threshold is reached", err)
+	}
+	time.Sleep(testCooldown + 10*time.Millisecond)
+}
+
+func TestBreakerLetsOneTrialThroughAndCloses(t *testing.T) {
+	srv := newFlakyServer(t)
+	client := testClient(Options{BreakerFailures: 2, BreakerCooldown: testCooldown})
+	openCircuit(t, client, srv)
+	if got := srv.calls.Load(); got != 2 {
+		t.Fatalf("got %d calls, want the open circuit to reject the third", got)
+	}
+
+	srv.status.Store(http.StatusOK)
+	srv.hold.Store(true)
+	trial := make(chan error, 1)
+	go func() {
+		_, err := get(context.Background(), client, srv.URL)
+		trial <- err
+	}()
+	<-srv.entered
+
+	// Only the trial goes through while it is in flight.
+	if _, err := get(context.Background(), client, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v during the trial, want ErrCircuitOpen", err)
+	}
+	srv.hold.Store(false)
+	close(srv.release)
+	if err := <-trial; err != nil {
+		t.Fatal(err)
+	}
+
+	// The successful trial closed the circuit.
+	for i := 0; i < 3; i++ {
+		if status, err := get(context.Background(), client, srv.URL); err != nil || status != http.StatusOK {
+			t.Fatalf("call %d after closing: %d, %v", i+1, status, err)
+		}
+	}
+}
+
+func TestBreakerReopensWhenTrialFails(t *testing.T) {
+	srv := newFlakyServer(t)
+	client := testClient(Options{BreakerFailures: 2, BreakerCooldown: testCooldown})
+	openCircuit(t, client, srv)
+
+	if status, err := get(context.Background(), client, srv.URL); err != nil || status != http.StatusInternalServerError {
+		t.Fatalf("trial: %d, %v", status, err)
+	}
+	// A single failed trial re-opens the circuit, without counting up to the threshold.
+	if _, err := get(context.Background(), client, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v after the failed trial, want ErrCircuitOpen", err)
+	}
+}
+
+func TestBreakerIgnoresCancelledTrial(t *testing.T) {
+	srv := newFlakyServer(t)
+	client := testClient(Options{BreakerFailures: 2, BreakerCooldown: testCooldown})
+	openCircuit(t, client, srv)
+
+	srv.hold.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	trial := make(chan error, 1)
+	go func() {
+		_, err := get(ctx, client, srv.URL)
+		trial <- err
+	}()
+	<-srv.entered
+	cancel()
+	if err := <-trial; !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want the trial cancelled", err)
+	}
+	srv.hold.Store(false)
+
+	// The circuit is still half-open: the next call is the trial, and its failure re-opens
+	// the circuit straight away. Had the cancelled call closed it, two failures would be
+	// needed.
+	if status, err := get(context.Background(), client, srv.URL); err != nil || status != http.StatusInternalServerError {
+		t.Fatalf("second trial: %d, %v", status, err)
+	}
+	if _, err := get(context.Background(), client, srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v after the failed trial, want ErrCircuitOpen", err)
+	}
+}
+
+func TestBreakerIgnoresCancelledCallsWhileClosed(t *testing.T) {
+	b := &breaker{name: "merchant-service", threshold: 2, cooldown: time.Minute}
+	b.record(attemptFailed)
+	b.record(attemptCancelled)
+	b.record(attemptFailed)
+	if b.state != stateOpen {
+		t.Fatalf("state = %s, want open: the cancelled call must not reset the failure count", stateNames[b.state])
+	}
+}
@@ -0,0 +1,139 @@
+// Package httpclient provides the HTTP client used for calls to other KodraPay services.
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kodra-pay/payout-service/internal/correlation"
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// Options configures the client. Zero values fall back to the defaults noted per field.
+type Options struct {
+	// Timeout bounds a whole call, retries included (default 10s).
+	Timeout time.Duration
+	// ConnectTimeout bounds establishing a connection (default 2s).
+	ConnectTimeout time.Duration
+	// MaxRetries is how many times an idempotent call is retried; 0 disables retries.
+	MaxRetries int
+	// RetryBaseDelay and RetryMaxDelay bound the jittered exponential backoff (defaults
+	// 100ms and 2s).
+	RetryBaseDelay time.Duration
+	RetryMaxDelay  time.Duration
+	// BreakerFailures consecutive failures open a dependency's circuit (default 5); it stays
+	// open for BreakerCooldown (default 30s) before a single trial call is let through.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+func (o Options) withDefaults() Options {
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.ConnectTimeout <= 0 {
+		o.ConnectTimeout = 2 * time.Second
+	}
+	if o.RetryBaseDelay <= 0 {
+		o.RetryBaseDelay = 100 * time.Millisecond
+	}
+	if o.RetryMaxDelay <= 0 {
+		o.RetryMaxDelay = 2 * time.Second
+	}
+	if o.BreakerFailures <= 0 {
+		o.BreakerFailures = 5
+	}
+	if o.BreakerCooldown <= 0 {
+		o.BreakerCooldown = 30 * time.Second
+	}
+	return o
+}
+
+// New returns the client shared by all calls to other services. Every request:
+//   - carries the caller's request ID and trace context, in its own client span (requests
+//     made without a request ID, e.g. from work not started by a request, get a fresh one);
+//   - goes through the circuit breaker of its dependency (see WithDependency), failing fast
+//     with ErrCircuitOpen while the dependency is considered down;
+//   - is retried with jittered backoff on connection errors and 429/502/503/504 responses,
+//     but only if it is idempotent: GET, HEAD, OPTIONS, PUT, DELETE, or any request with an
+//     Idempotency-Key header.
+func New(opts Options) *http.Client {
+	opts = opts.withDefaults()
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{Timeout: opts.ConnectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	base.TLSHandshakeTimeout = opts.ConnectTimeout
+
+	var rt http.RoundTripper = newBreakerTransport(base, opts.BreakerFailures, opts.BreakerCooldown)
+	rt = &retryTransport{base: rt, maxRetries: opts.MaxRetries, baseDelay: opts.RetryBaseDelay, maxDelay: opts.RetryMaxDelay}
+	return &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: &Transport{Base: rt},
+	}
+}
+
+type dependencyKey struct{}
+
+// WithDependency names the service a request made with ctx goes to. The name selects the
+// circuit breaker and labels metrics; requests without one are grouped by host.
+func WithDependency(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, dependencyKey{}, name)
+}
+
+func dependency(req *http.Request) string {
+	if name, ok := req.Context().Value(dependencyKey{}).(string); ok && name != "" {
+		return name
+	}
+	return req.URL.Host
+}
+
+// Transport injects X-Request-ID and traceparent headers before delegating to Base.
+type Transport struct {
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := tracing.StartKind(req.Context(), trace.SpanKindClient, "HTTP "+req.Method,
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.ServerAddress(req.URL.Hostname()),
+		semconv.URLPath(req.URL.Path),
+		semconv.PeerService(dependency(req)),
+	)
+	defer span.End()
+
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(ctx)
+	if req.Header.Get(correlation.RequestIDHeader) == "" {
+		id := correlation.RequestID(ctx)
+		if id == "" {
+			id = correlation.NewRequestID()
+		}
+		req.Header.Set(correlation.RequestIDHeader, id)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", resp.StatusCode))
+	}
+	return resp, nil
+}
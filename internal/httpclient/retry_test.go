@@ -0,0 +1,146 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testClient retries quickly and keeps the breaker out of the way unless opts say otherwise.
+func testClient(opts Options) *http.Client {
+	if opts.RetryBaseDelay == 0 {
+		opts.RetryBaseDelay, opts.RetryMaxDelay = time.Millisecond, time.Millisecond
+	}
+	if opts.BreakerFailures == 0 {
+		opts.BreakerFailures = 100
+	}
+	return New(opts)
+}
+
+func TestRetriesOnlyIdempotentRequests(t *testing.T) {
+	tests := []struct {
+		method         string
+		idempotencyKey string
+		wantCalls      int32
+	}{
+		{method: http.MethodGet, wantCalls: 3},
+		{method: http.MethodHead, wantCalls: 3},
+		{method: http.MethodOptions, wantCalls: 3},
+		{method: http.MethodPut, wantCalls: 3},
+		{method: http.MethodDelete, wantCalls: 3},
+		{method: http.MethodPost, wantCalls: 1},
+		{method: http.MethodPatch, wantCalls: 1},
+		{method: http.MethodPost, idempotencyKey: "payout-1-return", wantCalls: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.idempotencyKey, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			req, err := http.NewRequest(tt.method, srv.URL, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.idempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", tt.idempotencyKey)
+			}
+			resp, err := testClient(Options{MaxRetries: 2}).Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503", resp.StatusCode)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Fatalf("got %d calls, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryReplaysBody(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		attempt := len(bodies)
+		mu.Unlock()
+		if attempt < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"amount":150.25}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Idempotency-Key", "payout-1-return")
+	resp, err := testClient(Options{MaxRetries: 3}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", resp.StatusCode)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(bodies))
+	}
+	for i, body := range bodies {
+		if body != `{"amount":150.25}` {
+			t.Errorf("attempt %d sent %q", i+1, body)
+		}
+	}
+
+	// A body that can't be read again isn't retried.
+	bodies = nil
+	req, err = http.NewRequest(http.MethodPut, srv.URL, io.NopCloser(strings.NewReader("once")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = testClient(Options{MaxRetries: 3}).Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 1 || resp.StatusCode != http.StatusBadGateway {
+		t.Fatalf("got %d attempts ending in %d, want a single 502", len(bodies), resp.StatusCode)
+	}
+}
+
+func TestNoRetryWhileCircuitOpen(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// The second failure opens the circuit; the retries left are not attempted.
+	client := testClient(Options{MaxRetries: 5, BreakerFailures: 2, BreakerCooldown: time.Minute})
+	_, err := client.Get(srv.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("got %v, want ErrCircuitOpen", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("got %d calls, want 2", got)
+	}
+}
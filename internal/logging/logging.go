@@ -0,0 +1,111 @@
+// Package logging configures the service's structured logger and carries request-scoped
+// attributes (request ID, merchant ID, payout ID) through context.Context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+)
+
+type ctxKey struct{}
+
+// New builds a logger writing to stdout. format is "json" (default) or "text"; level is
+// debug, info (default), warn or error. Attributes stored with With are added to every
+// record logged with a context, and recipient PII is redacted.
+func New(service, level, format string) *slog.Logger {
+	return NewWithWriter(os.Stdout, service, level, format)
+}
+
+func NewWithWriter(w io.Writer, service, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: parseLevel(level), ReplaceAttr: redact}
+	var h slog.Handler
+	if strings.EqualFold(format, "text") {
+		h = slog.NewTextHandler(w, opts)
+	} else {
+		h = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(contextHandler{h}).With(slog.String("service", service))
+}
+
+// With returns a context carrying attrs in addition to those already attached.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev := attrsFrom(ctx)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	merged = append(merged, prev...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// Attr returns the value of an attribute attached with With.
+func Attr(ctx context.Context, key string) (slog.Value, bool) {
+	attrs := attrsFrom(ctx)
+	for i := len(attrs) - 1; i >= 0; i-- {
+		if attrs[i].Key == key {
+			return attrs[i].Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the attributes attached to the record's context, plus the trace and
+// span IDs of the active span.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(attrsFrom(ctx)...)
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// redact masks recipient account numbers and hides recipient names wherever they are logged.
+func redact(_ []string, a slog.Attr) slog.Attr {
+	switch a.Key {
+	case "recipient_account", "account_number":
+		return slog.String(a.Key, fieldcrypt.Mask(a.Value.String()))
+	case "recipient_name":
+		return slog.String(a.Key, "[REDACTED]")
+	}
+	return a
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
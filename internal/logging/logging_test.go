@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactsRecipientDetails(t *testing.T) {
+	recipient := func() []any {
+		return []any{
+			slog.String("recipient_account", "0123456789"),
+			slog.String("account_number", "9876543210"),
+			slog.String("recipient_name", "Ada Obi"),
+		}
+	}
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		// path leads to the object holding the redacted keys in the JSON output.
+		path []string
+	}{
+		{
+			name: "top level",
+			log:  func(l *slog.Logger) { l.Info("payout created", recipient()...) },
+		},
+		{
+			name: "group attribute",
+			log:  func(l *slog.Logger) { l.Info("payout created", slog.Group("payout", recipient()...)) },
+			path: []string{"payout"},
+		},
+		{
+			name: "logger group",
+			log:  func(l *slog.Logger) { l.WithGroup("payout").With(recipient()...).Info("payout created") },
+			path: []string{"payout"},
+		},
+		{
+			name: "nested groups",
+			log: func(l *slog.Logger) {
+				l.WithGroup("request").Info("payout created", slog.Group("payout", slog.Group("recipient", recipient()...)))
+			},
+			path: []string{"request", "payout", "recipient"},
+		},
+		{
+			name: "context attributes",
+			log: func(l *slog.Logger) {
+				ctx := With(context.Background(), slog.String("recipient_account", "0123456789"), slog.String("account_number", "9876543210"), slog.String("recipient_name", "Ada Obi"))
+				l.InfoContext(ctx, "payout created")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(NewWithWriter(&buf, "payout-service", "info", "json"))
+
+			out := buf.String()
+			for _, secret := range []string{"0123456789", "9876543210", "Ada Obi"} {
+				if strings.Contains(out, secret) {
+					t.Fatalf("output contains %q: %s", secret, out)
+				}
+			}
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("%v: %s", err, out)
+			}
+			for _, key := range tt.path {
+				group, ok := record[key].(map[string]any)
+				if !ok {
+					t.Fatalf("no group %q in %s", key, out)
+				}
+				record = group
+			}
+			want := map[string]string{
+				"recipient_account": "******6789",
+				"account_number":    "******3210",
+				"recipient_name":    "[REDACTED]",
+			}
+			for key, value := range want {
+				if record[key] != value {
+					t.Errorf("%s = %v, want %q", key, record[key], value)
+				}
+			}
+		})
+	}
+}
+
+func TestRedactsRecipientDetailsInText(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter(&buf, "payout-service", "info", "text")
+	logger.Info("payout created", slog.Group("payout", slog.String("recipient_account", "0123456789"), slog.String("recipient_name", "Ada Obi")))
+
+	out := buf.String()
+	if strings.Contains(out, "0123456789") || strings.Contains(out, "Ada Obi") {
+		t.Fatalf("output is not redacted: %s", out)
+	}
+	if !strings.Contains(out, "payout.recipient_account=******6789") || !strings.Contains(out, "payout.recipient_name=[REDACTED]") {
+		t.Fatalf("output lacks the masked values: %s", out)
+	}
+}
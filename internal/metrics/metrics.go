@@ -0,0 +1,149 @@
+// Package metrics defines the service's Prometheus metrics. They are registered on Registry,
+// which the /metrics endpoint serves, rather than on the global default registry.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "payout"
+
+// Worker names used for the worker_queue_depth gauge.
+const (
+	WorkerPayoutProcessing = "payout_processing"
+	WorkerExport           = "export"
+)
+
+// Downstream dependencies.
+const (
+	DependencyMerchantService     = "merchant-service"
+	DependencyTransactionService  = "transaction-service"
+	DependencyNotificationService = "notification-service"
+)
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by listener, method, route and status.",
+	}, []string{"listener", "method", "route", "status"})
+
+	HTTPDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by listener, method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"listener", "method", "route", "status"})
+
+	PayoutsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payouts_created_total",
+		Help:      "Payouts created, by currency.",
+	}, []string{"currency"})
+
+	PayoutTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payout_status_transitions_total",
+		Help:      "Payout status changes, by previous status, new status and currency.",
+	}, []string{"from", "to", "currency"})
+
+	// PayoutAmount is in major currency units (e.g. naira, not kobo).
+	PayoutAmount = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "payout_amount",
+		Help:      "Amount of created payouts in major currency units, by currency.",
+		Buckets:   prometheus.ExponentialBuckets(100, 10, 7),
+	}, []string{"currency"})
+
+	PayoutTimeToCompletion = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "payout_time_to_completion_seconds",
+		Help:      "Time from payout creation to completion, by currency.",
+		Buckets:   []float64{1, 5, 10, 30, 60, 300, 900, 3600, 4 * 3600, 24 * 3600},
+	}, []string{"currency"})
+
+	DownstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "downstream_request_duration_seconds",
+		Help:      "Latency of calls to other services, by dependency, operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"dependency", "operation", "outcome"})
+
+	DownstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downstream_errors_total",
+		Help:      "Failed calls to other services (transport errors and unexpected statuses), by dependency and operation.",
+	}, []string{"dependency", "operation"})
+
+	DownstreamRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "downstream_retries_total",
+		Help:      "Retried attempts of idempotent calls to other services, by dependency.",
+	}, []string{"dependency"})
+
+	BreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state per dependency: 0 closed, 1 half-open, 2 open.",
+	}, []string{"dependency"})
+
+	BreakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_transitions_total",
+		Help:      "Circuit breaker state changes, by dependency and new state.",
+	}, []string{"dependency", "state"})
+
+	WorkerQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "worker_queue_depth",
+		Help:      "Background work scheduled or running, by worker.",
+	}, []string{"worker"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPDuration,
+		PayoutsCreated,
+		PayoutTransitions,
+		PayoutAmount,
+		PayoutTimeToCompletion,
+		DownstreamDuration,
+		DownstreamErrors,
+		DownstreamRetries,
+		BreakerState,
+		BreakerTransitions,
+		WorkerQueueDepth,
+	)
+}
+
+// RegisterDB exposes the connection pool statistics of db (sql.DB.Stats) as go_sql_* metrics.
+func RegisterDB(db *sql.DB, name string) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveDownstream records one call to a dependency. err is the transport error or an
+// unexpected-status error; either counts as a failure.
+func ObserveDownstream(dependency, operation string, start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		DownstreamErrors.WithLabelValues(dependency, operation).Inc()
+	}
+	DownstreamDuration.WithLabelValues(dependency, operation, outcome).Observe(time.Since(start).Seconds())
+}
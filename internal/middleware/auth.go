@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/auth"
+	"github.com/kodra-pay/payout-service/internal/logging"
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+const principalKey = "auth.principal"
+
+// Authenticate requires an API key (X-API-Key or "Authorization: Bearer <key>") or a JWT
+// bearer token, and stores the resolved merchant on the request.
+func Authenticate(a *auth.Authenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		credential := c.Get("X-API-Key")
+		if credential == "" {
+			if scheme, token, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " "); ok && strings.EqualFold(scheme, "Bearer") {
+				credential = token
+			}
+		}
+
+		p, err := a.Authenticate(c.UserContext(), credential)
+		if err != nil {
+			if errors.Is(err, auth.ErrUnauthenticated) || errors.Is(err, auth.ErrInvalidToken) {
+				c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="payouts"`)
+				return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+			}
+			return fiber.NewError(fiber.StatusInternalServerError, "authentication failed")
+		}
+
+		c.Locals(principalKey, p)
+		tracing.SetAttributes(c.UserContext(), tracing.MerchantIDKey.Int(p.MerchantID))
+		c.SetUserContext(logging.With(c.UserContext(), slog.Int("merchant_id", p.MerchantID)))
+		return c.Next()
+	}
+}
+
+// Principal returns the authenticated caller set by Authenticate.
+func Principal(c *fiber.Ctx) (auth.Principal, bool) {
+	p, ok := c.Locals(principalKey).(auth.Principal)
+	return p, ok
+}
+
+// MerchantID returns the authenticated merchant, or 0 when the route is unauthenticated.
+func MerchantID(c *fiber.Ctx) int {
+	p, _ := Principal(c)
+	return p.MerchantID
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/correlation"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/httpclient"
+)
+
+var kindStatus = map[apperr.Kind]int{
+	apperr.KindValidation:        fiber.StatusUnprocessableEntity,
+	apperr.KindNotFound:          fiber.StatusNotFound,
+	apperr.KindConflict:          fiber.StatusConflict,
+	apperr.KindInsufficientFunds: fiber.StatusPaymentRequired,
+	apperr.KindUnavailable:       fiber.StatusServiceUnavailable,
+	apperr.KindInternal:          fiber.StatusInternalServerError,
+}
+
+// ErrorHandler is the Fiber error handler of both listeners. It writes every error as a
+// dto.ErrorResponse: typed service errors get the status of their kind, Fiber errors keep
+// theirs, and anything else is logged and reported as a generic 500.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	status := statusOf(err)
+	body := dto.ErrorBody{RequestID: correlation.RequestID(c.UserContext())}
+
+	var appErr *apperr.Error
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &appErr) && appErr.Kind != apperr.KindInternal:
+		body.Code, body.Message, body.Details = appErr.Code, appErr.Message, appErr.Fields
+	case errors.As(err, &fiberErr):
+		body.Code, body.Message = statusCode(status), fiberErr.Message
+	case status == fiber.StatusServiceUnavailable:
+		body.Code, body.Message = "dependency_unavailable", "a dependency is unavailable, try again later"
+	default:
+		body.Code, body.Message = "internal_error", "internal server error"
+	}
+	if status >= fiber.StatusInternalServerError {
+		slog.ErrorContext(c.UserContext(), "request failed", slog.Int("status", status), slog.Any("error", err))
+	}
+
+	// Tell callers when a dependency's circuit breaker lets requests through again.
+	var open *httpclient.CircuitOpenError
+	if errors.As(err, &open) {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(open.RetryAfter.Seconds()))))
+	}
+	return c.Status(status).JSON(dto.ErrorResponse{Error: body})
+}
+
+// statusOf returns the status ErrorHandler responds to err with, so middleware running
+// before it can report the final status.
+func statusOf(err error) int {
+	var appErr *apperr.Error
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &appErr):
+		return kindStatus[appErr.Kind]
+	case errors.As(err, &fiberErr):
+		return fiberErr.Code
+	case errors.Is(err, httpclient.ErrCircuitOpen):
+		return fiber.StatusServiceUnavailable
+	default:
+		return fiber.StatusInternalServerError
+	}
+}
+
+// statusCode derives an error code from an HTTP status, e.g. "too_many_requests" for 429.
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(utils.StatusMessage(status)), " ", "_")
+}
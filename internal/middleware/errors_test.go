@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/httpclient"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantMsg    string
+		wantFields int
+	}{
+		{"validation", apperr.Validation("invalid payout request", apperr.Field("amount", "must be positive")), 422, "validation_failed", "invalid payout request", 1},
+		{"not found", apperr.NotFound("payout_not_found", "payout not found"), 404, "payout_not_found", "payout not found", 0},
+		{"conflict", fmt.Errorf("wrapped: %w", apperr.Conflict("status_conflict", "changed")), 409, "status_conflict", "changed", 0},
+		{"insufficient funds", apperr.InsufficientFunds("insufficient available balance"), 402, "insufficient_funds", "insufficient available balance", 0},
+		{"unavailable", apperr.Unavailable("failed to verify balance", errors.New("dial tcp: refused")), 503, "dependency_unavailable", "failed to verify balance", 0},
+		{"internal hides the cause", apperr.Internal("failed to create payout", errors.New("pq: password authentication failed")), 500, "internal_error", "internal server error", 0},
+		{"fiber error", fiber.NewError(fiber.StatusTooManyRequests, "rate limit exceeded"), 429, "too_many_requests", "rate limit exceeded", 0},
+		{"unknown error", errors.New("boom"), 500, "internal_error", "internal server error", 0},
+		{"open circuit", &httpclient.CircuitOpenError{Dependency: "merchant-service", RetryAfter: 1500 * time.Millisecond}, 503, "dependency_unavailable", "a dependency is unavailable, try again later", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+			app.Use(RequestID())
+			app.Get("/", func(c *fiber.Ctx) error { return tt.err })
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("X-Request-ID", "req-123")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := statusOf(tt.err); got != tt.wantStatus {
+				t.Errorf("statusOf = %d, want %d", got, tt.wantStatus)
+			}
+			var body dto.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			e := body.Error
+			if e.Code != tt.wantCode || e.Message != tt.wantMsg || len(e.Details) != tt.wantFields || e.RequestID != "req-123" {
+				t.Errorf("body = %+v, want code %q, message %q, %d details and the request ID", e, tt.wantCode, tt.wantMsg, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerSetsRetryAfterForOpenCircuits(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return apperr.Unavailable("failed to verify balance", &httpclient.CircuitOpenError{Dependency: "merchant-service", RetryAfter: 1500 * time.Millisecond})
+	})
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "2" {
+		t.Fatalf("Retry-After = %q, want 2", got)
+	}
+}
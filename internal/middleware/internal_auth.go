@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/auth"
+	"github.com/kodra-pay/payout-service/internal/logging"
+)
+
+const serviceCallerKey = "auth.service_caller"
+
+// InternalAuth authenticates service-to-service callers. A verified TLS client certificate
+// on the allow-list is accepted as is; otherwise the request must carry X-Client-ID,
+// X-Timestamp, X-Nonce and X-Signature headers (see auth.SignRequest). The nonce must be
+// new for every request, retries included.
+func InternalAuth(a *auth.ServiceAuthenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if state := c.Context().TLSConnectionState(); state != nil && len(state.VerifiedChains) > 0 {
+			caller, err := a.VerifyCertificate(state.VerifiedChains[0][0].Subject.CommonName)
+			if err != nil {
+				return fiber.NewError(fiber.StatusForbidden, err.Error())
+			}
+			return withServiceCaller(c, caller)
+		}
+
+		caller, err := a.VerifySignature(c.UserContext(),
+			c.Get("X-Client-ID"), c.Get("X-Timestamp"), c.Get("X-Nonce"), c.Get("X-Signature"),
+			c.Method(), c.OriginalURL(), c.Body(),
+		)
+		if errors.Is(err, auth.ErrInvalidSignature) {
+			return fiber.NewError(fiber.StatusUnauthorized, err.Error())
+		}
+		if err != nil {
+			return apperr.Unavailable("failed to verify request signature", err)
+		}
+		return withServiceCaller(c, caller)
+	}
+}
+
+func withServiceCaller(c *fiber.Ctx, caller auth.ServiceCaller) error {
+	c.Locals(serviceCallerKey, caller)
+	c.SetUserContext(logging.With(c.UserContext(), slog.String("client", caller.Name)))
+	return c.Next()
+}
+
+// RequirePermission rejects internal callers whose roles don't grant the permission.
+func RequirePermission(permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		caller, ok := ServiceCaller(c)
+		if !ok || !caller.Can(permission) {
+			return fiber.NewError(fiber.StatusForbidden, fmt.Sprintf("missing permission %s", permission))
+		}
+		return c.Next()
+	}
+}
+
+// ServiceCaller returns the internal caller set by InternalAuth.
+func ServiceCaller(c *fiber.Ctx) (auth.ServiceCaller, bool) {
+	caller, ok := c.Locals(serviceCallerKey).(auth.ServiceCaller)
+	return caller, ok
+}
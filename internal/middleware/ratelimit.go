@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/ratelimit"
+)
+
+// RateLimitOptions configures which limits apply to public requests.
+type RateLimitOptions struct {
+	// PerIP limits every request by client IP; nil disables it.
+	PerIP *ratelimit.Rule
+	// Routes limit a merchant's requests to specific routes.
+	Routes []ratelimit.RouteRule
+	// Plans limit a merchant's requests overall, by plan.
+	Plans       map[string]ratelimit.Rule
+	DefaultPlan string
+	// Exempt networks bypass every limit.
+	Exempt []*net.IPNet
+}
+
+type RateLimiter struct {
+	limiter *ratelimit.Limiter
+	opts    RateLimitOptions
+}
+
+func NewRateLimiter(limiter *ratelimit.Limiter, opts RateLimitOptions) *RateLimiter {
+	return &RateLimiter{limiter: limiter, opts: opts}
+}
+
+type limitCheck struct {
+	key  string
+	rule ratelimit.Rule
+}
+
+// PerIP limits requests by client IP. It runs before authentication so it also throttles
+// credential guessing.
+func (r *RateLimiter) PerIP() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if r.opts.PerIP == nil || r.exempt(c) {
+			return c.Next()
+		}
+		return r.enforce(c, []limitCheck{{key: "ip:" + c.IP(), rule: *r.opts.PerIP}})
+	}
+}
+
+// PerMerchant applies the merchant's plan limit and any matching route limit. It must run
+// after Authenticate.
+func (r *RateLimiter) PerMerchant() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p, ok := Principal(c)
+		if !ok || r.exempt(c) {
+			return c.Next()
+		}
+
+		var checks []limitCheck
+		plan := p.Plan
+		if plan == "" {
+			plan = r.opts.DefaultPlan
+		}
+		if rule, ok := r.opts.Plans[plan]; ok {
+			checks = append(checks, limitCheck{key: fmt.Sprintf("merchant:%d", p.MerchantID), rule: rule})
+		}
+		for _, rr := range r.opts.Routes {
+			if rr.Match(c.Method(), c.Path()) {
+				checks = append(checks, limitCheck{key: fmt.Sprintf("merchant:%d:%s", p.MerchantID, rr.Key()), rule: rr.Rule})
+			}
+		}
+		return r.enforce(c, checks)
+	}
+}
+
+// enforce records a hit against every check, reports the most constraining one in the
+// RateLimit-* headers and rejects the request if any check is exceeded. Store failures
+// fail open so a counter outage doesn't take the API down.
+func (r *RateLimiter) enforce(c *fiber.Ctx, checks []limitCheck) error {
+	var tightest *ratelimit.Result
+	var retryAfter time.Duration
+	denied := false
+
+	for _, chk := range checks {
+		res, err := r.limiter.Allow(c.UserContext(), chk.key, chk.rule)
+		if err != nil {
+			slog.WarnContext(c.UserContext(), "rate limit check failed, allowing request", slog.String("key", chk.key), slog.Any("error", err))
+			continue
+		}
+		if !res.Allowed {
+			denied = true
+			if res.RetryAfter > retryAfter {
+				retryAfter = res.RetryAfter
+			}
+		}
+		if tightest == nil || res.Remaining < tightest.Remaining {
+			res := res
+			tightest = &res
+		}
+	}
+
+	if tightest != nil {
+		c.Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.Reset)))
+	}
+	if denied {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(retryAfter)))
+		return fiber.NewError(fiber.StatusTooManyRequests, "rate limit exceeded")
+	}
+	return c.Next()
+}
+
+func (r *RateLimiter) exempt(c *fiber.Ctx) bool {
+	ip := net.ParseIP(c.IP())
+	if ip == nil {
+		return false
+	}
+	for _, n := range r.opts.Exempt {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
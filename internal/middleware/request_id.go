@@ -1,19 +1,27 @@
 package middleware
 
 import (
-	"fmt"
-	"time"
+	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/correlation"
+	"github.com/kodra-pay/payout-service/internal/logging"
 )
 
+// RequestID reuses the caller's X-Request-ID or generates a UUID, echoes it on the response
+// and stores it in the request's user context so services can log it and forward it
+// downstream.
 func RequestID() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		requestID := c.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = fmt.Sprintf("%d", time.Now().UnixNano())
+		requestID := c.Get(correlation.RequestIDHeader)
+		if !correlation.ValidRequestID(requestID) {
+			requestID = correlation.NewRequestID()
 		}
-		c.Set("X-Request-ID", requestID)
+		c.Set(correlation.RequestIDHeader, requestID)
+
+		ctx := correlation.WithRequestID(c.UserContext(), requestID)
+		c.SetUserContext(logging.With(ctx, slog.String("request_id", requestID)))
 		return c.Next()
 	}
 }
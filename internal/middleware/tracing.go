@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// Tracing starts a server span per request, continuing the caller's trace when it sent a
+// traceparent header. The span is renamed to the matched route once it is known.
+func Tracing() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		headers := make(propagation.MapCarrier)
+		c.Request().Header.VisitAll(func(k, v []byte) {
+			headers[strings.ToLower(string(k))] = string(v)
+		})
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), headers)
+
+		ctx, span := tracing.StartKind(ctx, trace.SpanKindServer, c.Method(),
+			semconv.HTTPRequestMethodKey.String(c.Method()),
+			semconv.URLPath(c.Path()),
+			semconv.ClientAddress(c.IP()),
+			semconv.UserAgentOriginal(c.Get(fiber.HeaderUserAgent)),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = statusOf(err)
+		}
+		route := c.Route().Path
+		span.SetName(c.Method() + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		return err
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/openapi"
+)
+
+// ValidateRequest rejects requests whose path parameters, query parameters or JSON body
+// don't match the operation in doc, with a validation error listing every offending field.
+// Requests the document doesn't describe are passed on unchecked.
+func ValidateRequest(doc *openapi.Document) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		op, params := doc.Find(c.Method(), c.Path())
+		if op == nil {
+			return c.Next()
+		}
+		args := c.Context().QueryArgs()
+		fields := op.ValidateParams(params, func(name string) (string, bool) {
+			if !args.Has(name) {
+				return "", false
+			}
+			return string(args.Peek(name)), true
+		})
+		fields = append(fields, op.ValidateBody(c.Get(fiber.HeaderContentType), c.Body())...)
+		if len(fields) > 0 {
+			return apperr.Validation("request validation failed", fields...)
+		}
+		return c.Next()
+	}
+}
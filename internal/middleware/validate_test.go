@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/openapi"
+)
+
+func TestValidateRequest(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(ValidateRequest(openapi.Embedded()))
+	ok := func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) }
+	app.Post("/payouts", ok)
+	app.Get("/payouts/:id", ok)
+	app.Get("/undocumented", ok)
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantFields []string
+	}{
+		{"valid body", "POST", "/payouts", `{"amount": 10, "currency": "NGN", "recipient_name": "Ada", "recipient_account": "0123456789", "recipient_bank": "058"}`, 204, nil},
+		{"invalid body", "POST", "/payouts", `{"amount": -1, "currency": "NGN"}`, 422, []string{"recipient_name", "recipient_account", "recipient_bank", "amount"}},
+		{"invalid path parameter", "GET", "/payouts/abc", "", 422, []string{"id"}},
+		{"undocumented route", "GET", "/undocumented?limit=x", "", 204, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantStatus != fiber.StatusUnprocessableEntity {
+				return
+			}
+			var body dto.ErrorResponse
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			var fields []string
+			for _, d := range body.Error.Details {
+				fields = append(fields, d.Field)
+			}
+			if body.Error.Code != "validation_failed" || strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Fatalf("error = %+v, want validation_failed on %v", body.Error, tt.wantFields)
+			}
+		})
+	}
+}
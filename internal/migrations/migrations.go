@@ -0,0 +1,327 @@
+// Package migrations holds the versioned database schema and applies it. Migrations are
+// SQL files embedded in the binary, named <version>_<name>.up.sql / .down.sql; applied
+// versions are recorded in schema_migrations.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockID identifies the advisory lock held while migrating, so replicas starting together
+// apply migrations one at a time.
+const lockID int64 = 7009_0044
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes a migration and whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	Dirty     bool
+	AppliedAt *time.Time
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var all = mustLoad(files)
+
+// All returns the embedded migrations in version order.
+func All() []Migration {
+	return append([]Migration(nil), all...)
+}
+
+// Latest returns the highest embedded migration version.
+func Latest() int64 {
+	if len(all) == 0 {
+		return 0
+	}
+	return all[len(all)-1].Version
+}
+
+func mustLoad(fsys fs.FS) []Migration {
+	list, err := load(fsys)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+// load reads the migrations in fsys. Every version needs both an up and a down file.
+func load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, name := range names {
+		m := fileName.FindStringSubmatch(path.Base(name))
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.up.sql or .down.sql", name)
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" || mig.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		list = append(list, *mig)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Migrator applies the embedded migrations to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func New(db *sql.DB) *Migrator {
+	return &Migrator{db: db, migrations: all}
+}
+
+// Up applies every pending migration in order and returns the ones it applied. Each
+// migration runs in its own transaction.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := apply(ctx, conn, mig); err != nil {
+				return err
+			}
+			slog.InfoContext(ctx, "applied migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if err := revert(ctx, conn, mig); err != nil {
+				return err
+			}
+			slog.InfoContext(ctx, "reverted migration", slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+			reverted = append(reverted, mig)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every embedded migration with its state in the database.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, dirty, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	type record struct {
+		dirty     bool
+		appliedAt time.Time
+	}
+	records := map[int64]record{}
+	for rows.Next() {
+		var version int64
+		var r record
+		if err := rows.Scan(&version, &r.dirty, &r.appliedAt); err != nil {
+			return nil, err
+		}
+		records[version] = r
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	list := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Migration: mig}
+		if r, ok := records[mig.Version]; ok {
+			s.Applied, s.Dirty = true, r.dirty
+			s.AppliedAt = &r.appliedAt
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// locked runs fn on a single connection holding the migration advisory lock. Other
+// instances wait for the lock and then find the migrations already applied.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// The lock is released with the session anyway; unlock explicitly because the
+		// connection goes back to the pool.
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			slog.WarnContext(ctx, "failed to release migration lock", slog.Any("error", err))
+		}
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			dirty      BOOLEAN     NOT NULL DEFAULT FALSE,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// appliedVersions returns the recorded versions. A dirty version means a previous run died
+// while applying it; the schema has to be checked by hand before migrating further.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]struct{}, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, dirty FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[int64]struct{}{}
+	for rows.Next() {
+		var version int64
+		var dirty bool
+		if err := rows.Scan(&version, &dirty); err != nil {
+			return nil, err
+		}
+		if dirty {
+			return nil, fmt.Errorf("migration %d is dirty: check the schema, then delete or fix its schema_migrations row", version)
+		}
+		done[version] = struct{}{}
+	}
+	return done, rows.Err()
+}
+
+// apply records the migration as dirty, then runs it and clears the flag in one
+// transaction. If the migration fails the transaction rolls back and the record is removed
+// again, so only a crash mid-migration leaves a dirty version behind.
+func apply(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, TRUE)`, mig.Version); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	err := inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE schema_migrations SET dirty = FALSE, applied_at = NOW() WHERE version = $1`, mig.Version)
+		return err
+	})
+	if err != nil {
+		if _, cleanupErr := conn.ExecContext(context.WithoutCancel(ctx), `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); cleanupErr != nil {
+			slog.ErrorContext(ctx, "failed to clear dirty migration", slog.Int64("version", mig.Version), slog.Any("error", cleanupErr))
+		}
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+// revert runs the down migration and removes its record in one transaction.
+func revert(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	err := inTx(ctx, conn, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("revert migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,213 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	list := All()
+	if len(list) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range list {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: versions must be consecutive from 1, want %d", m.Version, m.Name, i+1)
+		}
+	}
+	if Latest() != list[len(list)-1].Version {
+		t.Fatalf("Latest() = %d, want %d", Latest(), list[len(list)-1].Version)
+	}
+
+	// The search index has to match the document the repository queries with.
+	if !strings.Contains(list[0].Up, "payouts_search_idx") {
+		t.Fatal("initial schema doesn't create payouts_search_idx")
+	}
+}
+
+func TestLoadRejectsMalformedMigrations(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "bad name",
+			files: fstest.MapFS{"sql/create_payouts.up.sql": {Data: []byte("SELECT 1")}},
+			want:  "name must be",
+		},
+		{
+			name:  "missing down",
+			files: fstest.MapFS{"sql/0001_init.up.sql": {Data: []byte("SELECT 1")}},
+			want:  "needs both",
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"sql/0001_init.up.sql":    {Data: []byte("SELECT 1")},
+				"sql/0001_other.down.sql": {Data: []byte("SELECT 1")},
+			},
+			want: "two names",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOrdersByVersion(t *testing.T) {
+	files := fstest.MapFS{
+		"sql/0010_later.up.sql":   {Data: []byte("up 10")},
+		"sql/0010_later.down.sql": {Data: []byte("down 10")},
+		"sql/0002_early.up.sql":   {Data: []byte("up 2")},
+		"sql/0002_early.down.sql": {Data: []byte("down 2")},
+	}
+	list, err := load(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Version != 2 || list[1].Version != 10 {
+		t.Fatalf("got %+v, want versions 2 then 10", list)
+	}
+	if list[1].Up != "up 10" || list[1].Down != "down 10" || list[1].Name != "later" {
+		t.Fatalf("migration 10 = %+v", list[1])
+	}
+}
+
+// baselineSchema is the payouts table of databases that predate the migrations.
+const baselineSchema = `
+CREATE TABLE payouts (
+    id                BIGSERIAL PRIMARY KEY,
+    merchant_id       BIGINT      NOT NULL,
+    reference         BIGINT      NOT NULL,
+    amount            BIGINT      NOT NULL,
+    currency          TEXT        NOT NULL,
+    recipient_name    TEXT        NOT NULL,
+    recipient_account TEXT        NOT NULL,
+    recipient_bank    TEXT        NOT NULL,
+    status            TEXT        NOT NULL,
+    narration         TEXT        NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO payouts (merchant_id, reference, amount, currency, recipient_name, recipient_account, recipient_bank, status)
+VALUES (7, 1001, 15025, 'NGN', 'Ada Obi', '0123456789', '058', 'completed');
+`
+
+// baselineColumns are the payouts columns of databases that predate the migrations.
+var baselineColumns = []string{
+	"id", "merchant_id", "reference", "amount", "currency", "recipient_name",
+	"recipient_account", "recipient_bank", "status", "narration", "created_at", "updated_at",
+}
+
+// TestInitialSchemaAddsColumnsToBaselineTable checks that every payouts column created by
+// the initial schema is also added to a baseline table, which CREATE TABLE IF NOT EXISTS
+// leaves alone.
+func TestInitialSchemaAddsColumnsToBaselineTable(t *testing.T) {
+	up := All()[0].Up
+	start := strings.Index(up, "CREATE TABLE IF NOT EXISTS payouts (")
+	end := strings.Index(up[start:], "\n);")
+	if start < 0 || end < 0 {
+		t.Fatal("initial schema doesn't create payouts")
+	}
+	for _, line := range strings.Split(up[start:start+end], "\n")[1:] {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "--") || slices.Contains(baselineColumns, fields[0]) {
+			continue
+		}
+		if !strings.Contains(up, "ADD COLUMN IF NOT EXISTS "+fields[0]+" ") {
+			t.Errorf("column %s isn't added to a baseline payouts table", fields[0])
+		}
+	}
+}
+
+// TestUpFromBaselineSchema migrates a database that already has the baseline payouts
+// table. It runs in a schema of its own and needs TEST_POSTGRES_URL.
+func TestUpFromBaselineSchema(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+	ctx := context.Background()
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	schema := fmt.Sprintf("migrations_baseline_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE"); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(t, dsn, schema+",public"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, baselineSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := New(db).Up(ctx)
+	if err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	if len(applied) != len(All()) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(All()))
+	}
+
+	var (
+		reference, accountHash, providerReference string
+		fee                                       int64
+		attempts                                  int
+		completedAt                               sql.NullTime
+	)
+	err = db.QueryRowContext(ctx, `
+		SELECT reference, fee, recipient_account_hash, provider_reference, completed_at, attempts
+		FROM payouts WHERE merchant_id = 7
+	`).Scan(&reference, &fee, &accountHash, &providerReference, &completedAt, &attempts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reference != "1001" || fee != 0 || accountHash != "" || providerReference != "" || completedAt.Valid || attempts != 1 {
+		t.Fatalf("baseline payout after migrating: reference %q fee %d hash %q provider reference %q completed at %v attempts %d",
+			reference, fee, accountHash, providerReference, completedAt, attempts)
+	}
+}
+
+// withSearchPath adds a search_path run-time parameter to a URL or key=value DSN.
+func withSearchPath(t *testing.T, dsn, searchPath string) string {
+	t.Helper()
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + searchPath
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", searchPath)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS rate_limit_counters;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS payout_exports;
+DROP TABLE IF EXISTS reconciliation_items;
+DROP TABLE IF EXISTS reconciliation_runs;
+DROP TABLE IF EXISTS payouts;
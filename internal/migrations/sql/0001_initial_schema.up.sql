@@ -0,0 +1,121 @@
+-- Baseline schema. Tables are created only if missing so databases that predate the
+-- migrations can adopt them; their payouts table gets the columns added since then below.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS payouts (
+    id                            BIGSERIAL PRIMARY KEY,
+    merchant_id                   BIGINT      NOT NULL,
+    reference                     BIGINT      NOT NULL,
+    amount                        BIGINT      NOT NULL,
+    fee                           BIGINT      NOT NULL DEFAULT 0,
+    currency                      TEXT        NOT NULL,
+    recipient_name                TEXT        NOT NULL DEFAULT '',
+    -- Plaintext account numbers of rows written before encryption; emptied by reencrypt.
+    recipient_account             TEXT        NOT NULL DEFAULT '',
+    recipient_account_ciphertext  BYTEA,
+    recipient_account_dek         BYTEA,
+    recipient_account_key_version INTEGER     NOT NULL DEFAULT 0,
+    recipient_account_hash        TEXT        NOT NULL DEFAULT '',
+    recipient_account_last4       TEXT        NOT NULL DEFAULT '',
+    recipient_bank                TEXT        NOT NULL DEFAULT '',
+    status                        TEXT        NOT NULL DEFAULT 'pending',
+    narration                     TEXT        NOT NULL DEFAULT '',
+    provider_reference            TEXT        NOT NULL DEFAULT '',
+    created_at                    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at                    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at                  TIMESTAMPTZ
+);
+
+-- A payouts table from before the migrations only has id, merchant_id, reference, amount,
+-- currency, the plaintext recipient columns, status, narration and the timestamps.
+ALTER TABLE payouts
+    ADD COLUMN IF NOT EXISTS fee                           BIGINT      NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS recipient_account_ciphertext  BYTEA,
+    ADD COLUMN IF NOT EXISTS recipient_account_dek         BYTEA,
+    ADD COLUMN IF NOT EXISTS recipient_account_key_version INTEGER     NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS recipient_account_hash        TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS recipient_account_last4       TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS provider_reference            TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS completed_at                  TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS payouts_recipient_account_hash_idx ON payouts (merchant_id, recipient_account_hash);
+CREATE INDEX IF NOT EXISTS payouts_provider_reference_idx ON payouts (provider_reference);
+CREATE INDEX IF NOT EXISTS payouts_pending_idx ON payouts (updated_at) WHERE status = 'pending';
+-- Must match payoutSearchDocument in internal/repositories/search.go.
+CREATE INDEX IF NOT EXISTS payouts_search_idx ON payouts
+    USING GIN (to_tsvector('simple', recipient_name || ' ' || narration || ' ' || reference::text || ' ' || recipient_account_last4));
+CREATE INDEX IF NOT EXISTS payouts_recipient_name_trgm_idx ON payouts USING GIN (recipient_name gin_trgm_ops);
+
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id               BIGSERIAL PRIMARY KEY,
+    format           TEXT        NOT NULL,
+    file_name        TEXT        NOT NULL DEFAULT '',
+    statement_id     TEXT        NOT NULL DEFAULT '',
+    account_id       TEXT        NOT NULL DEFAULT '',
+    total_lines      INTEGER     NOT NULL DEFAULT 0,
+    matched_count    INTEGER     NOT NULL DEFAULT 0,
+    unmatched_count  INTEGER     NOT NULL DEFAULT 0,
+    mismatched_count INTEGER     NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at     TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_items (
+    id              BIGSERIAL PRIMARY KEY,
+    run_id          BIGINT      NOT NULL REFERENCES reconciliation_runs (id) ON DELETE CASCADE,
+    payout_id       BIGINT      REFERENCES payouts (id),
+    line_reference  TEXT        NOT NULL DEFAULT '',
+    bank_reference  TEXT        NOT NULL DEFAULT '',
+    line_amount     BIGINT      NOT NULL,
+    line_currency   TEXT        NOT NULL,
+    booking_date    DATE        NOT NULL,
+    description     TEXT        NOT NULL DEFAULT '',
+    result          TEXT        NOT NULL,
+    reason          TEXT        NOT NULL DEFAULT '',
+    resolved        BOOLEAN     NOT NULL DEFAULT FALSE,
+    resolved_by     TEXT        NOT NULL DEFAULT '',
+    resolution_note TEXT        NOT NULL DEFAULT '',
+    resolved_at     TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS reconciliation_items_run_idx ON reconciliation_items (run_id, id);
+CREATE INDEX IF NOT EXISTS reconciliation_items_matched_payout_idx ON reconciliation_items (payout_id) WHERE result = 'matched';
+CREATE INDEX IF NOT EXISTS reconciliation_items_exceptions_idx ON reconciliation_items (created_at DESC, id DESC) WHERE result <> 'matched';
+
+CREATE TABLE IF NOT EXISTS payout_exports (
+    id           UUID PRIMARY KEY,
+    merchant_id  BIGINT      NOT NULL,
+    format       TEXT        NOT NULL,
+    filter       JSONB       NOT NULL,
+    status       TEXT        NOT NULL,
+    row_count    INTEGER     NOT NULL DEFAULT 0,
+    file_path    TEXT        NOT NULL DEFAULT '',
+    error        TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           BIGSERIAL PRIMARY KEY,
+    merchant_id  BIGINT      NOT NULL,
+    name         TEXT        NOT NULL DEFAULT '',
+    prefix       TEXT        NOT NULL,
+    plan         TEXT        NOT NULL,
+    key_hash     TEXT        NOT NULL UNIQUE,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_merchant_idx ON api_keys (merchant_id);
+
+CREATE TABLE IF NOT EXISTS rate_limit_counters (
+    key          TEXT        NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    count        INTEGER     NOT NULL,
+    PRIMARY KEY (key, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_counters_window_idx ON rate_limit_counters (window_start);
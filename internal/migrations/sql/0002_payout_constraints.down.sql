@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS payouts_merchant_updated_idx;
+DROP INDEX IF EXISTS payouts_merchant_created_idx;
+
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_status_check;
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_merchant_reference_key;
@@ -0,0 +1,13 @@
+-- Constraints the service relies on. On a database that predates the migrations this fails
+-- if existing rows violate them; clean those up and run the migration again.
+
+ALTER TABLE payouts
+    ADD CONSTRAINT payouts_merchant_reference_key UNIQUE (merchant_id, reference);
+
+ALTER TABLE payouts
+    ADD CONSTRAINT payouts_status_check
+    CHECK (status IN ('pending', 'processing', 'processed', 'completed', 'failed'));
+
+-- Serves merchant listings and keyset pages, which order by the sort column and id.
+CREATE INDEX payouts_merchant_created_idx ON payouts (merchant_id, created_at, id);
+CREATE INDEX payouts_merchant_updated_idx ON payouts (merchant_id, updated_at, id);
@@ -0,0 +1,10 @@
+-- Fails once a payout has a reference that isn't a number.
+
+DROP INDEX IF EXISTS payouts_search_idx;
+
+ALTER TABLE payouts DROP CONSTRAINT IF EXISTS payouts_reference_check;
+
+ALTER TABLE payouts ALTER COLUMN reference TYPE BIGINT USING reference::bigint;
+
+CREATE INDEX payouts_search_idx ON payouts
+    USING GIN (to_tsvector('simple', recipient_name || ' ' || narration || ' ' || reference::text || ' ' || recipient_account_last4));
@@ -0,0 +1,14 @@
+-- Payout references become strings chosen by the merchant (e.g. "INV-2026-0042"); existing
+-- numeric references keep their digits. payouts_merchant_reference_key still makes them
+-- unique per merchant.
+
+DROP INDEX IF EXISTS payouts_search_idx;
+
+ALTER TABLE payouts ALTER COLUMN reference TYPE TEXT USING reference::text;
+
+ALTER TABLE payouts
+    ADD CONSTRAINT payouts_reference_check CHECK (char_length(reference) BETWEEN 1 AND 64);
+
+-- Must match payoutSearchDocument in internal/repositories/search.go.
+CREATE INDEX payouts_search_idx ON payouts
+    USING GIN (to_tsvector('simple', recipient_name || ' ' || narration || ' ' || reference || ' ' || recipient_account_last4));
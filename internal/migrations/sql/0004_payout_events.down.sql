@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS payout_events;
+
+ALTER TABLE payouts
+    DROP COLUMN IF EXISTS failure_code,
+    DROP COLUMN IF EXISTS failure_message;
@@ -0,0 +1,22 @@
+-- Failure details of failed payouts and the timeline returned by GET /payouts/{id}?expand=events.
+
+ALTER TABLE payouts
+    ADD COLUMN failure_code    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN failure_message TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE payout_events (
+    id           BIGSERIAL PRIMARY KEY,
+    payout_id    BIGINT      NOT NULL REFERENCES payouts (id) ON DELETE CASCADE,
+    type         TEXT        NOT NULL,
+    from_status  TEXT        NOT NULL DEFAULT '',
+    to_status    TEXT        NOT NULL,
+    failure_code TEXT        NOT NULL DEFAULT '',
+    message      TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX payout_events_payout_idx ON payout_events (payout_id, created_at, id);
+
+-- Every payout starts out pending; earlier status changes weren't recorded.
+INSERT INTO payout_events (payout_id, type, to_status, created_at)
+SELECT id, 'created', 'pending', created_at FROM payouts;
@@ -0,0 +1,3 @@
+ALTER TABLE payout_events DROP COLUMN IF EXISTS attempt;
+
+ALTER TABLE payouts DROP COLUMN IF EXISTS attempts;
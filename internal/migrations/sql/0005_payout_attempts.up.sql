@@ -0,0 +1,7 @@
+-- Processing attempts: merchants may retry failed payouts a limited number of times.
+
+ALTER TABLE payouts
+    ADD COLUMN attempts INTEGER NOT NULL DEFAULT 1 CONSTRAINT payouts_attempts_check CHECK (attempts >= 1);
+
+ALTER TABLE payout_events
+    ADD COLUMN attempt INTEGER NOT NULL DEFAULT 1;
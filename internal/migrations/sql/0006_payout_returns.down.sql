@@ -0,0 +1,7 @@
+-- Fails while payouts are returned or reversed.
+
+ALTER TABLE payouts DROP CONSTRAINT payouts_status_check;
+
+ALTER TABLE payouts
+    ADD CONSTRAINT payouts_status_check
+    CHECK (status IN ('pending', 'processing', 'processed', 'completed', 'failed'));
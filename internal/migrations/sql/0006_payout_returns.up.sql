@@ -0,0 +1,7 @@
+-- Completed payouts can come back: returned by the recipient's bank or reversed by ops.
+
+ALTER TABLE payouts DROP CONSTRAINT payouts_status_check;
+
+ALTER TABLE payouts
+    ADD CONSTRAINT payouts_status_check
+    CHECK (status IN ('pending', 'processing', 'processed', 'completed', 'failed', 'returned', 'reversed'));
@@ -0,0 +1,9 @@
+-- Nonces of signed internal requests, kept while the request's timestamp is valid so it
+-- can't be replayed.
+
+CREATE TABLE IF NOT EXISTS request_nonces (
+    key        TEXT        PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS request_nonces_expires_idx ON request_nonces (expires_at);
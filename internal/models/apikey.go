@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// APIKey is a merchant credential. Only the SHA-256 hash of the key is stored; Prefix
+// keeps the first characters so merchants can tell their keys apart.
+type APIKey struct {
+	ID         int        `json:"id"`
+	MerchantID int        `json:"merchant_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Plan       string     `json:"plan"`
+	KeyHash    string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Export job statuses.
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+// ExportJob is a background payout export whose result is written to a file.
+type ExportJob struct {
+	ID          string          `json:"id"`
+	MerchantID  int             `json:"merchant_id"`
+	Format      string          `json:"format"`
+	Filter      json.RawMessage `json:"filter"`
+	Status      string          `json:"status"`
+	RowCount    int             `json:"row_count"`
+	FilePath    string          `json:"-"`
+	Error       string          `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
@@ -3,16 +3,42 @@ package models
 import "time"
 
 type Payout struct {
-	ID             int       `json:"id"`
-	MerchantID     int       `json:"merchant_id"`
-	Reference      int       `json:"reference"`
-	Amount         int64     `json:"amount"`
-	Currency       string    `json:"currency"`
-	RecipientName  string    `json:"recipient_name"`
-	RecipientAccount string  `json:"recipient_account"`
-	RecipientBank  string    `json:"recipient_bank"`
-	Status         string    `json:"status"`
-	Narration      string    `json:"narration,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                int        `json:"id"`
+	MerchantID        int        `json:"merchant_id"`
+	Reference         string     `json:"reference"`
+	Amount            int64      `json:"amount"`
+	Fee               int64      `json:"fee"`
+	Currency          string     `json:"currency"`
+	RecipientName     string     `json:"recipient_name"`
+	RecipientAccount  string     `json:"-"` // plaintext in memory only; encrypted at rest, masked in responses
+	RecipientBank     string     `json:"recipient_bank"`
+	Status            string     `json:"status"`
+	Narration         string     `json:"narration,omitempty"`
+	ProviderReference string     `json:"provider_reference,omitempty"`
+	FailureCode       string     `json:"failure_code,omitempty"` // set while the payout is failed
+	FailureMessage    string     `json:"failure_message,omitempty"`
+	Attempts          int        `json:"attempts"` // processing attempts, 1 until the payout is retried
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	CompletedAt       *time.Time `json:"completed_at,omitempty"`
+}
+
+// Payout event types.
+const (
+	PayoutEventCreated       = "created"
+	PayoutEventStatusChanged = "status_changed"
+)
+
+// PayoutEvent is one entry of a payout's timeline. Stores record an event for every payout
+// created and every status change, in the same write.
+type PayoutEvent struct {
+	ID          int       `json:"id"`
+	PayoutID    int       `json:"payout_id"`
+	Type        string    `json:"type"`
+	FromStatus  string    `json:"from_status,omitempty"`
+	ToStatus    string    `json:"to_status"`
+	FailureCode string    `json:"failure_code,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	Attempt     int       `json:"attempt"` // the payout's attempt the event belongs to
+	CreatedAt   time.Time `json:"created_at"`
 }
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Reconciliation item results.
+const (
+	ReconMatched    = "matched"
+	ReconUnmatched  = "unmatched"
+	ReconMismatched = "mismatched"
+)
+
+// ReconciliationRun is one import of a bank statement file.
+type ReconciliationRun struct {
+	ID              int        `json:"id"`
+	Format          string     `json:"format"`
+	FileName        string     `json:"file_name"`
+	StatementID     string     `json:"statement_id,omitempty"`
+	AccountID       string     `json:"account_id,omitempty"`
+	TotalLines      int        `json:"total_lines"`
+	MatchedCount    int        `json:"matched_count"`
+	UnmatchedCount  int        `json:"unmatched_count"`
+	MismatchedCount int        `json:"mismatched_count"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
+
+// ReconciliationItem is the outcome of matching a single debit statement line.
+type ReconciliationItem struct {
+	ID             int        `json:"id"`
+	RunID          int        `json:"run_id"`
+	PayoutID       *int       `json:"payout_id,omitempty"`
+	LineReference  string     `json:"line_reference"`
+	BankReference  string     `json:"bank_reference,omitempty"`
+	LineAmount     int64      `json:"line_amount"`
+	LineCurrency   string     `json:"line_currency"`
+	BookingDate    time.Time  `json:"booking_date"`
+	Description    string     `json:"description,omitempty"`
+	Result         string     `json:"result"`
+	Reason         string     `json:"reason,omitempty"`
+	Resolved       bool       `json:"resolved"`
+	ResolvedBy     string     `json:"resolved_by,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
@@ -0,0 +1,9 @@
+package models
+
+// PayoutSearchHit is a payout matched by full-text search together with its rank and the
+// highlighted fragments of the fields that matched (keyed by field name).
+type PayoutSearchHit struct {
+	Payout     *Payout
+	Rank       float64
+	Highlights map[string]string
+}
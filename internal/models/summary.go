@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Summary dimensions a PayoutSummaryRow can be grouped by (in addition to currency).
+const (
+	SummaryTotal  = "total"
+	SummaryStatus = "status"
+	SummaryBank   = "bank"
+	SummaryPeriod = "period"
+)
+
+// PayoutSummaryRow is one aggregate bucket of payouts for a currency. Only the key field
+// matching Dimension is set. AvgCompletionSeconds is nil when no payout in the bucket completed.
+type PayoutSummaryRow struct {
+	Dimension            string
+	Currency             string
+	Status               string
+	Bank                 string
+	Period               time.Time
+	Count                int
+	Volume               int64
+	CompletedCount       int
+	FailedCount          int
+	AvgCompletionSeconds *float64
+}
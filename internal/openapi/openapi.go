@@ -0,0 +1,366 @@
+// Package openapi serves the service's OpenAPI 3 document and validates requests against
+// it. The document in openapi.json is written by hand; routes_test.go in the routes package
+// fails when it and the registered routes drift apart.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var document []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+// methods are the operation keys of a path item, in the order operations are listed.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Document is a parsed OpenAPI document. Only the parts used to route and validate requests
+// are modelled; JSON and PublicJSON serve the document as written.
+type Document struct {
+	Paths      map[string]*PathItem `json:"paths"`
+	Components struct {
+		Parameters map[string]*Parameter `json:"parameters"`
+		Schemas    map[string]*Schema    `json:"schemas"`
+	} `json:"components"`
+
+	raw        []byte
+	publicRaw  []byte
+	operations []*Operation
+}
+
+// Listeners a path is served on (x-listener); paths are public by default.
+const (
+	ListenerPublic   = "public"
+	ListenerInternal = "internal"
+	ListenerAll      = "all"
+)
+
+// PathItem holds the operations of one path.
+type PathItem struct {
+	Listener   string       `json:"x-listener"`
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Options    *Operation   `json:"options"`
+	Head       *Operation   `json:"head"`
+	Patch      *Operation   `json:"patch"`
+	Trace      *Operation   `json:"trace"`
+}
+
+func (p *PathItem) operation(method string) *Operation {
+	switch method {
+	case "get":
+		return p.Get
+	case "put":
+		return p.Put
+	case "post":
+		return p.Post
+	case "delete":
+		return p.Delete
+	case "options":
+		return p.Options
+	case "head":
+		return p.Head
+	case "patch":
+		return p.Patch
+	case "trace":
+		return p.Trace
+	}
+	return nil
+}
+
+// Operation is one method on one path. Parameters include those declared on the path.
+type Operation struct {
+	ID          string         `json:"operationId"`
+	Parameters  []*Parameter   `json:"parameters"`
+	RequestBody *RequestBody   `json:"requestBody"`
+	Responses   map[string]any `json:"responses"`
+	Method      string         `json:"-"`
+	Path        string         `json:"-"`
+	Listener    string         `json:"-"`
+	segments    []string
+}
+
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Explode  *bool   `json:"explode"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+var embedded = mustLoad(document)
+
+// Embedded returns the service's own document.
+func Embedded() *Document { return embedded }
+
+func mustLoad(data []byte) *Document {
+	d, err := Load(data)
+	if err != nil {
+		panic(fmt.Sprintf("openapi.json: %v", err))
+	}
+	return d
+}
+
+// Load parses a document and resolves its references. Every $ref must point into the
+// document and every pattern must compile.
+func Load(data []byte) (*Document, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if err := checkRefs(raw, raw); err != nil {
+		return nil, err
+	}
+	d := &Document{raw: data}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, err
+	}
+
+	for name, s := range d.Components.Schemas {
+		if err := d.resolve(s); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	for path, item := range d.Paths {
+		switch item.Listener {
+		case "":
+			item.Listener = ListenerPublic
+		case ListenerPublic, ListenerInternal, ListenerAll:
+		default:
+			return nil, fmt.Errorf("%s: x-listener must be public, internal or all", path)
+		}
+		shared, err := d.parameters(item.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, method := range methods {
+			op := item.operation(method)
+			if op == nil {
+				continue
+			}
+			own, err := d.parameters(op.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+			op.Parameters = mergeParameters(shared, own)
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					if err := d.resolve(media.Schema); err != nil {
+						return nil, fmt.Errorf("%s %s: %w", method, path, err)
+					}
+				}
+			}
+			op.Method, op.Path, op.Listener = strings.ToUpper(method), path, item.Listener
+			op.segments = strings.Split(strings.Trim(path, "/"), "/")
+			d.operations = append(d.operations, op)
+		}
+	}
+	// Paths with more literal segments win, so /payouts/search isn't taken for /payouts/{id}.
+	sort.SliceStable(d.operations, func(i, j int) bool {
+		a, b := d.operations[i], d.operations[j]
+		if la, lb := literalSegments(a.segments), literalSegments(b.segments); la != lb {
+			return la > lb
+		}
+		return a.Path+" "+a.Method < b.Path+" "+b.Method
+	})
+
+	public, err := publicDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+	d.publicRaw = public
+	return d, nil
+}
+
+// JSON returns the full document, as served on the internal listener.
+func (d *Document) JSON() []byte { return d.raw }
+
+// PublicJSON returns the document without internal paths, as served to merchants.
+func (d *Document) PublicJSON() []byte { return d.publicRaw }
+
+// DocsHTML returns the API reference page, which renders the openapi.json served next to it.
+func DocsHTML() []byte { return docsPage }
+
+// ServedOn reports whether the operation is served on the public or internal listener.
+func (o *Operation) ServedOn(listener string) bool {
+	return o.Listener == ListenerAll || o.Listener == listener
+}
+
+// Operations returns every operation, most specific path first.
+func (d *Document) Operations() []*Operation {
+	return append([]*Operation(nil), d.operations...)
+}
+
+// Find returns the operation serving method and path along with the path parameters, or
+// nil when the document doesn't describe the request.
+func (d *Document) Find(method, path string) (*Operation, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, op := range d.operations {
+		if op.Method != method || len(op.segments) != len(segments) {
+			continue
+		}
+		params := map[string]string{}
+		matched := true
+		for i, seg := range op.segments {
+			if name, ok := pathParam(seg); ok {
+				if segments[i] == "" {
+					matched = false
+					break
+				}
+				if v, err := url.PathUnescape(segments[i]); err == nil {
+					params[name] = v
+				} else {
+					params[name] = segments[i]
+				}
+			} else if seg != segments[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return op, params
+		}
+	}
+	return nil, nil
+}
+
+// parameters resolves parameter references.
+func (d *Document) parameters(list []*Parameter) ([]*Parameter, error) {
+	out := make([]*Parameter, 0, len(list))
+	for _, p := range list {
+		if p.Ref != "" {
+			name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+			if !ok || d.Components.Parameters[name] == nil {
+				return nil, fmt.Errorf("unresolvable parameter %s", p.Ref)
+			}
+			p = d.Components.Parameters[name]
+		}
+		if p.Schema != nil {
+			if err := d.resolve(p.Schema); err != nil {
+				return nil, fmt.Errorf("parameter %s: %w", p.Name, err)
+			}
+			if p.Schema.deref().Type == "array" && (p.Explode == nil || *p.Explode) {
+				return nil, fmt.Errorf("parameter %s: array parameters must be comma-separated (explode: false)", p.Name)
+			}
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// mergeParameters adds the path-level parameters an operation doesn't override.
+func mergeParameters(shared, own []*Parameter) []*Parameter {
+	out := append([]*Parameter(nil), own...)
+	for _, p := range shared {
+		overridden := false
+		for _, o := range own {
+			if o.Name == p.Name && o.In == p.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func pathParam(segment string) (string, bool) {
+	if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+func literalSegments(segments []string) int {
+	n := 0
+	for _, seg := range segments {
+		if _, ok := pathParam(seg); !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// checkRefs reports $ref values anywhere in node that don't resolve in root.
+func checkRefs(root map[string]any, node any) error {
+	switch v := node.(type) {
+	case map[string]any:
+		if ref, ok := v["$ref"].(string); ok {
+			if _, err := pointer(root, ref); err != nil {
+				return err
+			}
+		}
+		for _, child := range v {
+			if err := checkRefs(root, child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range v {
+			if err := checkRefs(root, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// pointer follows a local reference such as "#/components/schemas/PayoutRequest".
+func pointer(root map[string]any, ref string) (any, error) {
+	path, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil, fmt.Errorf("unsupported reference %s: only local references are allowed", ref)
+	}
+	var node any = root
+	for _, token := range strings.Split(path, "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("unresolvable reference %s", ref)
+		}
+		if node, ok = m[token]; !ok {
+			return nil, fmt.Errorf("unresolvable reference %s", ref)
+		}
+	}
+	return node, nil
+}
+
+// publicDocument drops the internal paths from the document.
+func publicDocument(raw map[string]any) ([]byte, error) {
+	public := make(map[string]any, len(raw))
+	for k, v := range raw {
+		public[k] = v
+	}
+	paths, _ := raw["paths"].(map[string]any)
+	publicPaths := make(map[string]any, len(paths))
+	for path, item := range paths {
+		if m, ok := item.(map[string]any); ok && m["x-listener"] == ListenerInternal {
+			continue
+		}
+		publicPaths[path] = item
+	}
+	public["paths"] = publicPaths
+	return json.Marshal(public)
+}
@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+)
+
+func TestFindPrefersLiteralSegments(t *testing.T) {
+	doc := Embedded()
+	tests := []struct {
+		method, path string
+		wantID       string
+		wantParams   map[string]string
+	}{
+		{"GET", "/payouts/search", "searchPayouts", map[string]string{}},
+		{"GET", "/payouts/42", "getPayout", map[string]string{"id": "42"}},
+		{"GET", "/payouts/exports/abc/download", "downloadExport", map[string]string{"id": "abc"}},
+		{"PUT", "/internal/payouts/7/status", "updatePayoutStatus", map[string]string{"id": "7"}},
+		{"POST", "/internal/webhooks/payout-returns", "reportPayoutReturn", map[string]string{}},
+		{"GET", "/payouts/by-reference/INV%3A42", "getPayoutByReference", map[string]string{"reference": "INV:42"}},
+		{"DELETE", "/payouts/42", "", nil},
+		{"GET", "/unknown", "", nil},
+	}
+	for _, tt := range tests {
+		op, params := doc.Find(tt.method, tt.path)
+		if tt.wantID == "" {
+			if op != nil {
+				t.Errorf("%s %s matched %s, want no operation", tt.method, tt.path, op.ID)
+			}
+			continue
+		}
+		if op == nil || op.ID != tt.wantID {
+			t.Errorf("%s %s matched %+v, want %s", tt.method, tt.path, op, tt.wantID)
+			continue
+		}
+		if len(params) != len(tt.wantParams) {
+			t.Errorf("%s %s params = %v, want %v", tt.method, tt.path, params, tt.wantParams)
+		}
+		for name, want := range tt.wantParams {
+			if params[name] != want {
+				t.Errorf("%s %s params = %v, want %v", tt.method, tt.path, params, tt.wantParams)
+			}
+		}
+	}
+}
+
+func TestPublicJSONLeavesOutInternalPaths(t *testing.T) {
+	var public struct {
+		Paths map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(Embedded().PublicJSON(), &public); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := public.Paths["/payouts"]; !ok {
+		t.Fatal("public document lacks /payouts")
+	}
+	for path := range public.Paths {
+		if strings.HasPrefix(path, "/internal") || path == "/metrics" {
+			t.Errorf("public document exposes %s", path)
+		}
+	}
+}
+
+func TestValidateBody(t *testing.T) {
+	op, _ := Embedded().Find("POST", "/payouts")
+	valid := `{"reference": "INV-2026-0042", "amount": 2500.5, "currency": "NGN", "recipient_name": "Ada Obi", "recipient_account": "0123456789", "recipient_bank": "058"}`
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []apperr.FieldError
+	}{
+		{"valid", "application/json", valid, nil},
+		{"charset parameter", "application/json; charset=utf-8", valid, nil},
+		{"missing body", "application/json", "", []apperr.FieldError{{Field: "body", Message: "is required"}}},
+		{"malformed", "application/json", `{"amount": `, []apperr.FieldError{{Field: "body", Message: "must be a valid JSON document"}}},
+		{"not an object", "application/json", `[1]`, []apperr.FieldError{{Field: "body", Message: "must be an object"}}},
+		{
+			name:        "field errors",
+			contentType: "application/json",
+			body:        `{"reference": 12, "amount": 0, "currency": "ngn", "recipient_name": "", "recipient_account": 123, "recipient_bank": "058"}`,
+			want: []apperr.FieldError{
+				{Field: "amount", Message: "must be greater than 0"},
+				{Field: "currency", Message: "must match ^[A-Z]{3}$"},
+				{Field: "recipient_account", Message: "must be a string"},
+				{Field: "recipient_name", Message: "must not be empty"},
+				{Field: "reference", Message: "must be a string"},
+			},
+		},
+		{
+			name:        "missing fields",
+			contentType: "application/json",
+			body:        `{"amount": 10, "currency": "NGN"}`,
+			want: []apperr.FieldError{
+				{Field: "recipient_name", Message: "is required"},
+				{Field: "recipient_account", Message: "is required"},
+				{Field: "recipient_bank", Message: "is required"},
+			},
+		},
+		{"form bodies are left to the handler", "application/x-www-form-urlencoded", "amount=0", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := op.ValidateBody(tt.contentType, []byte(tt.body))
+			if !equalFields(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateParams(t *testing.T) {
+	list, _ := Embedded().Find("GET", "/payouts")
+	search, _ := Embedded().Find("GET", "/payouts/search")
+	get, _ := Embedded().Find("GET", "/payouts/x")
+	tests := []struct {
+		name  string
+		op    *Operation
+		path  map[string]string
+		query map[string]string
+		want  []apperr.FieldError
+	}{
+		{"valid", list, nil, map[string]string{"limit": "20", "sort": "amount", "status": "pending,failed", "min_amount": "10.5"}, nil},
+		{"empty values are ignored", list, nil, map[string]string{"limit": "", "sort": ""}, nil},
+		{
+			name:  "invalid",
+			op:    list,
+			query: map[string]string{"limit": "ten", "order": "up", "min_amount": "-1", "reference": "INV-1"},
+			want: []apperr.FieldError{
+				{Field: "limit", Message: "must be an integer"},
+				{Field: "order", Message: "must be one of asc, desc"},
+				{Field: "min_amount", Message: "must be at least 0"},
+			},
+		},
+		{"required query parameter", search, nil, map[string]string{}, []apperr.FieldError{{Field: "q", Message: "is required"}}},
+		{"path parameter", get, map[string]string{"id": "x"}, nil, []apperr.FieldError{{Field: "id", Message: "must be an integer"}}},
+		{"expand", get, map[string]string{"id": "1"}, map[string]string{"expand": "events"}, nil},
+		{"unknown expand", get, map[string]string{"id": "1"}, map[string]string{"expand": "events,recipient"}, []apperr.FieldError{{Field: "expand[1]", Message: "must be one of events"}}},
+		{"path parameter bound", get, map[string]string{"id": "0"}, nil, []apperr.FieldError{{Field: "id", Message: "must be at least 1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.op.ValidateParams(tt.path, func(name string) (string, bool) {
+				v, ok := tt.query[name]
+				return v, ok
+			})
+			if !equalFields(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsBrokenDocuments(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{
+			name: "dangling reference",
+			doc:  `{"paths": {"/a": {"get": {"responses": {"200": {"$ref": "#/components/responses/Missing"}}}}}}`,
+			want: "unresolvable reference",
+		},
+		{
+			name: "remote reference",
+			doc:  `{"paths": {"/a": {"get": {"parameters": [{"$ref": "other.json#/p"}]}}}}`,
+			want: "only local references",
+		},
+		{
+			name: "bad pattern",
+			doc:  `{"components": {"schemas": {"A": {"type": "string", "pattern": "("}}}}`,
+			want: "pattern",
+		},
+		{
+			name: "exploded array parameter",
+			doc:  `{"paths": {"/a": {"get": {"parameters": [{"name": "s", "in": "query", "schema": {"type": "array"}}]}}}}`,
+			want: "explode: false",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// equalFields compares field errors ignoring order; parameters are checked in declaration
+// order and properties alphabetically, which tests shouldn't depend on.
+func equalFields(got, want []apperr.FieldError) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := map[apperr.FieldError]int{}
+	for _, f := range got {
+		seen[f]++
+	}
+	for _, f := range want {
+		if seen[f] == 0 {
+			return false
+		}
+		seen[f]--
+	}
+	return true
+}
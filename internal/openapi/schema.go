@@ -0,0 +1,255 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the validator enforces: types,
+// nullable, enum, numeric bounds, string length, pattern, date formats, required and
+// unknown properties, array items and allOf. Other keywords are documentation only.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	AllOf                []*Schema          `json:"allOf"`
+
+	target  *Schema // the schema $ref points to
+	pattern *regexp.Regexp
+}
+
+// resolve links references to their component schemas and compiles patterns. It is safe to
+// call more than once on shared schemas.
+func (d *Document) resolve(s *Schema) error {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		if s.target != nil {
+			return nil
+		}
+		name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/")
+		if !ok || d.Components.Schemas[name] == nil {
+			return fmt.Errorf("unresolvable schema %s", s.Ref)
+		}
+		s.target = d.Components.Schemas[name]
+		return nil
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", s.Pattern, err)
+		}
+		s.pattern = re
+	}
+	for _, child := range s.Properties {
+		if err := d.resolve(child); err != nil {
+			return err
+		}
+	}
+	for _, child := range s.AllOf {
+		if err := d.resolve(child); err != nil {
+			return err
+		}
+	}
+	return d.resolve(s.Items)
+}
+
+func (s *Schema) deref() *Schema {
+	for s.target != nil {
+		s = s.target
+	}
+	return s
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber (or converted from a
+// parameter) against the schema. Errors name the field by its path, e.g. "items[2].amount".
+func (s *Schema) Validate(field string, v any) []apperr.FieldError {
+	s = s.deref()
+	var errs []apperr.FieldError
+	fail := func(format string, args ...any) []apperr.FieldError {
+		return append(errs, apperr.Field(field, fmt.Sprintf(format, args...)))
+	}
+
+	for _, part := range s.AllOf {
+		errs = append(errs, part.Validate(field, v)...)
+	}
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return errs
+		}
+		return fail("must not be null")
+	}
+
+	switch s.Type {
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		n := len([]rune(str))
+		if s.MinLength != nil && n < *s.MinLength {
+			if *s.MinLength == 1 {
+				return fail("must not be empty")
+			}
+			return fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			return fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(str) {
+			return fail("must match %s", s.Pattern)
+		}
+		if msg := checkFormat(s.Format, str); msg != "" {
+			return fail("%s", msg)
+		}
+	case "integer", "number":
+		num, ok := toFloat(v)
+		if s.Type == "integer" && (!ok || num != math.Trunc(num)) {
+			return fail("must be an integer")
+		}
+		if !ok {
+			return fail("must be a number")
+		}
+		if s.Minimum != nil {
+			if s.ExclusiveMinimum && num <= *s.Minimum {
+				return fail("must be greater than %s", formatNumber(*s.Minimum))
+			}
+			if num < *s.Minimum {
+				return fail("must be at least %s", formatNumber(*s.Minimum))
+			}
+		}
+		if s.Maximum != nil {
+			if s.ExclusiveMaximum && num >= *s.Maximum {
+				return fail("must be less than %s", formatNumber(*s.Maximum))
+			}
+			if num > *s.Maximum {
+				return fail("must be at most %s", formatNumber(*s.Maximum))
+			}
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fail("must be a boolean")
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		if s.Items != nil {
+			for i, item := range items {
+				errs = append(errs, s.Items.Validate(fmt.Sprintf("%s[%d]", field, i), item)...)
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+		errs = append(errs, s.validateObject(field, obj)...)
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		return fail("must be one of %s", enumList(s.Enum))
+	}
+	return errs
+}
+
+func (s *Schema) validateObject(field string, obj map[string]any) []apperr.FieldError {
+	var errs []apperr.FieldError
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			errs = append(errs, apperr.Field(join(field, name), "is required"))
+		}
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	closed := string(s.AdditionalProperties) == "false"
+	for _, name := range names {
+		if prop, ok := s.Properties[name]; ok {
+			errs = append(errs, prop.Validate(join(field, name), obj[name])...)
+		} else if closed {
+			errs = append(errs, apperr.Field(join(field, name), "is not allowed"))
+		}
+	}
+	return errs
+}
+
+func join(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func checkFormat(format, v string) string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return "must be an RFC 3339 timestamp"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return "must be a date (YYYY-MM-DD)"
+		}
+	}
+	return ""
+}
+
+func inEnum(enum []any, v any) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func enumList(enum []any) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		parts[i] = fmt.Sprint(e)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type memoryCounter struct {
+	windowStart time.Time
+	window      time.Duration
+	current     int
+	previous    int
+}
+
+// MemoryStore keeps counters in process memory. It is only correct for single-instance
+// deployments; use the Postgres store when running several replicas.
+type MemoryStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+	lastGC   time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]*memoryCounter{}}
+}
+
+func (s *MemoryStore) Hit(_ context.Context, key string, windowStart time.Time, window time.Duration) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.gc(windowStart)
+
+	c, ok := s.counters[key]
+	if !ok {
+		c = &memoryCounter{windowStart: windowStart}
+		s.counters[key] = c
+	}
+	c.window = window
+	switch {
+	case c.windowStart.Equal(windowStart):
+	case c.windowStart.Add(window).Equal(windowStart):
+		c.previous, c.current, c.windowStart = c.current, 0, windowStart
+	default:
+		c.previous, c.current, c.windowStart = 0, 0, windowStart
+	}
+	c.current++
+	return c.current, c.previous, nil
+}
+
+// gc drops counters that no longer affect their sliding window. Each counter expires by
+// its own rule's window, not the window of the rule that happens to trigger the pass. It
+// runs at most once a minute.
+func (s *MemoryStore) gc(now time.Time) {
+	if now.Sub(s.lastGC) < time.Minute {
+		return
+	}
+	s.lastGC = now
+	for key, c := range s.counters {
+		if now.Sub(c.windowStart) > 2*c.window {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreExpiresCountersByTheirOwnWindow(t *testing.T) {
+	s := NewMemoryStore()
+	ctx := context.Background()
+	start := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	if _, _, err := s.Hit(ctx, "merchant:7", start, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// A per-second rule keeps collecting garbage well past two of its own windows.
+	for i := 1; i <= 10; i++ {
+		now := start.Add(time.Duration(i) * 5 * time.Minute)
+		if _, _, err := s.Hit(ctx, "ip:10.0.0.1", now, time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	current, previous, err := s.Hit(ctx, "merchant:7", start, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != 2 || previous != 0 {
+		t.Fatalf("hourly counter = %d, %d; want 2, 0", current, previous)
+	}
+
+	// Once the hourly counter is two windows old it goes too.
+	s.Hit(ctx, "ip:10.0.0.1", start.Add(3*time.Hour), time.Second)
+	s.mu.Lock()
+	_, kept := s.counters["merchant:7"]
+	s.mu.Unlock()
+	if kept {
+		t.Fatal("hourly counter kept after two windows")
+	}
+}
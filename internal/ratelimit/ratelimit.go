@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Store keeps fixed-window hit counters. Hit increments the counter of the window starting
+// at windowStart and returns it together with the count of the preceding window.
+type Store interface {
+	Hit(ctx context.Context, key string, windowStart time.Time, window time.Duration) (current, previous int, err error)
+}
+
+// Rule allows Limit requests per Window.
+type Rule struct {
+	Limit  int
+	Window time.Duration
+}
+
+// Result describes the state of a key after a hit.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the current window ends.
+	Reset time.Duration
+	// RetryAfter is how long a rejected caller should wait before trying again.
+	RetryAfter time.Duration
+}
+
+// Limiter applies sliding-window rate limits. The sliding window is approximated from
+// two fixed windows: the previous window's count is weighted by how much of it still
+// overlaps the sliding window. This needs only two counters per key, which keeps the
+// Postgres-backed store cheap while smoothing bursts at window boundaries.
+type Limiter struct {
+	store Store
+	now   func() time.Time
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store, now: time.Now}
+}
+
+// Allow records a hit for key and reports whether it is within the rule.
+func (l *Limiter) Allow(ctx context.Context, key string, rule Rule) (Result, error) {
+	now := l.now()
+	windowStart := now.Truncate(rule.Window)
+	elapsed := now.Sub(windowStart)
+
+	current, previous, err := l.store.Hit(ctx, key, windowStart, rule.Window)
+	if err != nil {
+		return Result{}, err
+	}
+
+	weight := 1 - float64(elapsed)/float64(rule.Window)
+	estimated := int(math.Floor(float64(previous)*weight)) + current
+
+	res := Result{
+		Allowed:   estimated <= rule.Limit,
+		Limit:     rule.Limit,
+		Remaining: rule.Limit - estimated,
+		Reset:     rule.Window - elapsed,
+	}
+	if res.Remaining < 0 {
+		res.Remaining = 0
+	}
+	if !res.Allowed {
+		res.RetryAfter = retryAfter(current, previous, rule, elapsed)
+	}
+	return res, nil
+}
+
+// retryAfter estimates when the weighted count drops back under the limit. If the current
+// window alone is over the limit the caller has to wait for the next window.
+func retryAfter(current, previous int, rule Rule, elapsed time.Duration) time.Duration {
+	if current >= rule.Limit || previous == 0 {
+		return rule.Window - elapsed
+	}
+	// previous * (1 - t/window) + current <= limit  =>  t >= window * (1 - (limit-current)/previous)
+	t := time.Duration(float64(rule.Window) * (1 - float64(rule.Limit-current)/float64(previous)))
+	if t <= elapsed {
+		return time.Second
+	}
+	return t - elapsed
+}
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseRule parses "<limit>/<window>" where window is s, m, h or a Go duration ("30s").
+func ParseRule(s string) (Rule, error) {
+	limit, window, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Rule{}, fmt.Errorf("invalid rate limit %q: expected limit/window", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || n <= 0 {
+		return Rule{}, fmt.Errorf("invalid rate limit %q: limit must be a positive integer", s)
+	}
+
+	var d time.Duration
+	switch w := strings.TrimSpace(window); w {
+	case "s", "sec", "second":
+		d = time.Second
+	case "m", "min", "minute":
+		d = time.Minute
+	case "h", "hour":
+		d = time.Hour
+	default:
+		if d, err = time.ParseDuration(w); err != nil || d <= 0 {
+			return Rule{}, fmt.Errorf("invalid rate limit %q: bad window", s)
+		}
+	}
+	return Rule{Limit: n, Window: d}, nil
+}
+
+// ParseRuleMap parses comma-separated "name=limit/window" entries.
+func ParseRuleMap(s string) (map[string]Rule, error) {
+	rules := map[string]Rule{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, spec, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit entry %q: expected name=limit/window", entry)
+		}
+		rule, err := ParseRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules[strings.TrimSpace(name)] = rule
+	}
+	return rules, nil
+}
+
+// RouteRule limits requests matching a method and path pattern. Pattern segments starting
+// with ":" or equal to "*" match any single segment.
+type RouteRule struct {
+	Method   string
+	Segments []string
+	Rule     Rule
+}
+
+// ParseRouteRules parses comma-separated "METHOD /path=limit/window" entries.
+func ParseRouteRules(s string) ([]RouteRule, error) {
+	m, err := ParseRuleMap(s)
+	if err != nil {
+		return nil, err
+	}
+	var out []RouteRule
+	for name, rule := range m {
+		method, path, ok := strings.Cut(name, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid route rate limit %q: expected \"METHOD /path\"", name)
+		}
+		out = append(out, RouteRule{
+			Method:   strings.ToUpper(strings.TrimSpace(method)),
+			Segments: splitPath(path),
+			Rule:     rule,
+		})
+	}
+	return out, nil
+}
+
+// Key identifies the route rule in counter keys.
+func (r RouteRule) Key() string {
+	return r.Method + " /" + strings.Join(r.Segments, "/")
+}
+
+// Match reports whether the request method and path match the rule.
+func (r RouteRule) Match(method, path string) bool {
+	if !strings.EqualFold(method, r.Method) {
+		return false
+	}
+	segs := splitPath(path)
+	if len(segs) != len(r.Segments) {
+		return false
+	}
+	for i, s := range r.Segments {
+		if s != "*" && !strings.HasPrefix(s, ":") && s != segs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func splitPath(p string) []string {
+	var segs []string
+	for _, s := range strings.Split(strings.TrimSpace(p), "/") {
+		if s != "" {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+// ParseNetworks parses a comma-separated list of IPs and CIDR ranges.
+func ParseNetworks(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exempt network %q", entry)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+)
+
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, k *models.APIKey) error {
+	query := `
+		INSERT INTO api_keys (merchant_id, name, prefix, plan, key_hash, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at
+	`
+	return r.db.QueryRowContext(ctx, query, k.MerchantID, k.Name, k.Prefix, k.Plan, k.KeyHash).Scan(&k.ID, &k.CreatedAt)
+}
+
+// GetByHash returns the active key with the given hash and records that it was used.
+// Revoked or unknown keys return nil.
+func (r *APIKeyRepository) GetByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	query := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING id, merchant_id, name, prefix, plan, key_hash, created_at, last_used_at, revoked_at
+	`
+	var k models.APIKey
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(
+		&k.ID, &k.MerchantID, &k.Name, &k.Prefix, &k.Plan, &k.KeyHash, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, id)
+	return err
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// ReencryptAccounts brings every stored account number under the active key encryption key.
+// Rows still holding a plaintext account (written before encryption existed) are encrypted
+// and indexed; rows under an older key version have their data key re-wrapped. Rows are
+// processed in id order in batches and each update is guarded by the key version it read,
+// so the command can be interrupted and re-run safely. It returns the number of rows changed.
+func (r *PayoutRepository) ReencryptAccounts(ctx context.Context, batchSize int) (_ int, err error) {
+	ctx, span := startQuery(ctx, "ReencryptAccounts")
+	defer func() { tracing.End(span, err) }()
+
+	active := r.keys.ActiveVersion()
+	updated, afterID := 0, 0
+
+	for {
+		rows, err := r.db.QueryContext(ctx, `
+			SELECT id, recipient_account, recipient_account_ciphertext, recipient_account_dek, recipient_account_key_version
+			FROM payouts
+			WHERE id > $1 AND (recipient_account <> '' OR recipient_account_key_version <> $2)
+			ORDER BY id
+			LIMIT $3
+		`, afterID, active, batchSize)
+		if err != nil {
+			return updated, err
+		}
+
+		type pending struct {
+			id        int
+			plaintext string
+			field     fieldcrypt.Field
+		}
+		var batch []pending
+		for rows.Next() {
+			var p pending
+			if err := rows.Scan(&p.id, &p.plaintext, &p.field.Ciphertext, &p.field.WrappedKey, &p.field.KeyVersion); err != nil {
+				rows.Close()
+				return updated, err
+			}
+			batch = append(batch, p)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return updated, err
+		}
+		if len(batch) == 0 {
+			return updated, nil
+		}
+
+		for _, p := range batch {
+			afterID = p.id
+			if p.field.Ciphertext == nil {
+				if err := r.encryptLegacyAccount(ctx, p.id, p.plaintext); err != nil {
+					return updated, err
+				}
+				updated++
+				continue
+			}
+
+			rewrapped, err := r.keys.Rewrap(p.field)
+			if err != nil {
+				return updated, fmt.Errorf("payout %d: %w", p.id, err)
+			}
+			res, err := r.db.ExecContext(ctx, `
+				UPDATE payouts
+				SET recipient_account_dek = $2, recipient_account_key_version = $3
+				WHERE id = $1 AND recipient_account_key_version = $4
+			`, p.id, rewrapped.WrappedKey, rewrapped.KeyVersion, p.field.KeyVersion)
+			if err != nil {
+				return updated, fmt.Errorf("payout %d: %w", p.id, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				updated++
+			}
+		}
+	}
+}
+
+func (r *PayoutRepository) encryptLegacyAccount(ctx context.Context, id int, plaintext string) error {
+	field, err := r.keys.Encrypt(plaintext)
+	if err != nil {
+		return fmt.Errorf("payout %d: %w", id, err)
+	}
+	_, err = r.db.ExecContext(ctx, `
+		UPDATE payouts
+		SET recipient_account = '',
+			recipient_account_ciphertext = $2,
+			recipient_account_dek = $3,
+			recipient_account_key_version = $4,
+			recipient_account_hash = $5,
+			recipient_account_last4 = $6
+		WHERE id = $1 AND recipient_account = $7
+	`, id, field.Ciphertext, field.WrappedKey, field.KeyVersion,
+		r.keys.BlindIndex(plaintext), fieldcrypt.Last4(plaintext), plaintext)
+	if err != nil {
+		return fmt.Errorf("payout %d: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package repositories_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// testRotatedKeys adds key version 2 to the test keys and makes active the active version.
+func testRotatedKeys(t *testing.T, active int) *fieldcrypt.Keyring {
+	t.Helper()
+	keys, err := fieldcrypt.NewKeyring(testEncryptionKeys+",2:OTg3NjU0MzIxMDk4NzY1NDMyMTA5ODc2NTQzMjEwOTg=", "", active, testBlindIndexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// TestReencryptAccounts rotates the key encryption key of a migrated database the way the
+// reencrypt command does. It needs TEST_POSTGRES_URL; the memory store keeps plaintext.
+func TestReencryptAccounts(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_URL")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_URL not set")
+	}
+	db, err := repositories.Open(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	p := mustCreate(t, repositories.NewPayoutRepository(db, testKeys(t)), newPayout(newMerchantID(), 100, "0123456789"))
+
+	rotated := repositories.NewPayoutRepository(db, testRotatedKeys(t, 2))
+	// Leave the shared database under version 1 for the other tests.
+	t.Cleanup(func() {
+		if _, err := repositories.NewPayoutRepository(db, testRotatedKeys(t, 1)).ReencryptAccounts(ctx, 100); err != nil {
+			t.Errorf("rotate back to version 1: %v", err)
+		}
+	})
+	if n, err := rotated.ReencryptAccounts(ctx, 2); err != nil || n == 0 {
+		t.Fatalf("ReencryptAccounts = %d, %v", n, err)
+	}
+	if n, err := rotated.ReencryptAccounts(ctx, 2); err != nil || n != 0 {
+		t.Fatalf("second ReencryptAccounts = %d, %v; want nothing left to do", n, err)
+	}
+
+	got, err := rotated.GetByID(ctx, p.ID)
+	if err != nil || got == nil || got.RecipientAccount != "0123456789" {
+		t.Fatalf("after reencrypt: %+v, %v", got, err)
+	}
+	onlyV1 := repositories.NewPayoutRepository(db, testKeys(t))
+	if _, err := onlyV1.GetByID(ctx, p.ID); err == nil {
+		t.Fatal("read a version 2 account with only version 1 configured")
+	}
+}
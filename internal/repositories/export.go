@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+)
+
+// ExportJobStore keeps background export jobs. ExportRepository stores them in Postgres;
+// MemoryExportJobStore keeps them in process memory.
+type ExportJobStore interface {
+	// Create sets the job's CreatedAt.
+	Create(ctx context.Context, job *models.ExportJob) error
+	// Get returns nil, nil when the job doesn't exist.
+	Get(ctx context.Context, id string) (*models.ExportJob, error)
+	SetStatus(ctx context.Context, id, status string) error
+	// Finish records the final state of a job. A non-empty errMsg marks it failed.
+	Finish(ctx context.Context, id string, rowCount int, filePath, errMsg string) error
+}
+
+var (
+	_ ExportJobStore = (*ExportRepository)(nil)
+	_ ExportJobStore = (*MemoryExportJobStore)(nil)
+)
+
+type ExportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// Create stores a new job. The filter is passed as text: lib/pq sends []byte as bytea,
+// which the JSONB column would reject.
+func (r *ExportRepository) Create(ctx context.Context, job *models.ExportJob) error {
+	query := `
+		INSERT INTO payout_exports (id, merchant_id, format, filter, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at
+	`
+	return r.db.QueryRowContext(ctx, query,
+		job.ID, job.MerchantID, job.Format, string(job.Filter), job.Status,
+	).Scan(&job.CreatedAt)
+}
+
+func (r *ExportRepository) Get(ctx context.Context, id string) (*models.ExportJob, error) {
+	query := `
+		SELECT id, merchant_id, format, filter, status, row_count, file_path, error, created_at, completed_at
+		FROM payout_exports
+		WHERE id = $1
+	`
+	var job models.ExportJob
+	var filter []byte
+	err := r.db.QueryRowContext(ctx, query, id).Scan(
+		&job.ID, &job.MerchantID, &job.Format, &filter, &job.Status,
+		&job.RowCount, &job.FilePath, &job.Error, &job.CreatedAt, &job.CompletedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.Filter = filter
+	return &job, nil
+}
+
+// Finish records the final state of a job. A non-empty errMsg marks it failed.
+func (r *ExportRepository) Finish(ctx context.Context, id string, rowCount int, filePath, errMsg string) error {
+	status := models.ExportCompleted
+	if errMsg != "" {
+		status = models.ExportFailed
+	}
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE payout_exports
+		SET status = $2, row_count = $3, file_path = $4, error = $5, completed_at = NOW()
+		WHERE id = $1
+	`, id, status, rowCount, filePath, errMsg)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("export job not found")
+	}
+	return nil
+}
+
+func (r *ExportRepository) SetStatus(ctx context.Context, id, status string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE payout_exports SET status = $2 WHERE id = $1`, id, status)
+	return err
+}
+
+// MemoryExportJobStore keeps export jobs in process memory.
+type MemoryExportJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]models.ExportJob
+}
+
+func NewMemoryExportJobStore() *MemoryExportJobStore {
+	return &MemoryExportJobStore{jobs: map[string]models.ExportJob{}}
+}
+
+func (s *MemoryExportJobStore) Create(_ context.Context, job *models.ExportJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[job.ID]; ok {
+		return fmt.Errorf("export job %s already exists", job.ID)
+	}
+	job.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *MemoryExportJobStore) Get(_ context.Context, id string) (*models.ExportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (s *MemoryExportJobStore) SetStatus(_ context.Context, id, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		job.Status = status
+		s.jobs[id] = job
+	}
+	return nil
+}
+
+func (s *MemoryExportJobStore) Finish(_ context.Context, id string, rowCount int, filePath, errMsg string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("export job not found")
+	}
+	job.Status = models.ExportCompleted
+	if errMsg != "" {
+		job.Status = models.ExportFailed
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	job.RowCount, job.FilePath, job.Error, job.CompletedAt = rowCount, filePath, errMsg, &now
+	s.jobs[id] = job
+	return nil
+}
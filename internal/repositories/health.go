@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WaitForDB pings db until it answers or ctx is done, backing off from one second up to
+// maxInterval between attempts. The service calls it in the background at start-up so an
+// unavailable database delays readiness instead of crashing the process.
+func WaitForDB(ctx context.Context, db *sql.DB, maxInterval time.Duration) error {
+	interval := time.Second
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			if attempt > 1 {
+				slog.InfoContext(ctx, "database connection established", slog.Int("attempts", attempt))
+			}
+			return nil
+		}
+		slog.WarnContext(ctx, "database not reachable, retrying",
+			slog.Int("attempt", attempt), slog.Duration("retry_in", interval), slog.Any("error", err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// SchemaVersion returns the latest applied migration and whether it was left dirty by a
+// failed run. ok is false when no migration has been recorded (or the schema_migrations
+// table doesn't exist yet).
+func SchemaVersion(ctx context.Context, db *sql.DB) (version int64, dirty, ok bool, err error) {
+	err = db.QueryRowContext(ctx, `
+		SELECT version, dirty
+		FROM schema_migrations
+		ORDER BY version DESC
+		LIMIT 1
+	`).Scan(&version, &dirty)
+	var pqErr *pq.Error
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, false, false, nil
+	case errors.As(err, &pqErr) && pqErr.Code == "42P01": // undefined_table
+		return 0, false, false, nil
+	case err != nil:
+		return 0, false, false, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, dirty, true, nil
+}
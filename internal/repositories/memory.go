@@ -0,0 +1,512 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/models"
+)
+
+// memoryPayout is a stored payout together with the columns that only exist in the table.
+type memoryPayout struct {
+	payout      models.Payout
+	accountHash string
+	last4       string
+}
+
+// MemoryPayoutStore keeps payouts in process memory. It follows the Postgres semantics of
+// PayoutRepository (sequential IDs, microsecond timestamps, keyset ordering, nil for missing
+// payouts, status compare-and-set) so services can be tested and run without a database.
+// Search approximates full-text matching with whole-word and substring matches.
+type MemoryPayoutStore struct {
+	mu      sync.Mutex
+	keys    *fieldcrypt.Keyring
+	payouts map[int]*memoryPayout
+	lastID  int
+	events  []models.PayoutEvent
+	now     func() time.Time
+}
+
+// NewMemoryPayoutStore returns an empty store. keys computes the blind index used to filter
+// by recipient account, as in the database.
+func NewMemoryPayoutStore(keys *fieldcrypt.Keyring) *MemoryPayoutStore {
+	return &MemoryPayoutStore{
+		keys:    keys,
+		payouts: map[int]*memoryPayout{},
+		now: func() time.Time {
+			// Postgres keeps microseconds; match it so keyset cursors round-trip the same way.
+			return time.Now().UTC().Truncate(time.Microsecond)
+		},
+	}
+}
+
+func (s *MemoryPayoutStore) Create(_ context.Context, p *models.Payout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.byReference(p.MerchantID, p.Reference) != nil {
+		return ErrDuplicateReference
+	}
+	s.lastID++
+	now := s.now()
+	p.ID, p.Attempts, p.CreatedAt, p.UpdatedAt = s.lastID, 1, now, now
+
+	stored := &memoryPayout{
+		payout:      clonePayout(p),
+		accountHash: s.keys.BlindIndex(p.RecipientAccount),
+		last4:       fieldcrypt.Last4(p.RecipientAccount),
+	}
+	s.payouts[p.ID] = stored
+	s.addEvent(models.PayoutEvent{PayoutID: p.ID, Type: models.PayoutEventCreated, ToStatus: p.Status, Attempt: p.Attempts, CreatedAt: now})
+	return nil
+}
+
+func (s *MemoryPayoutStore) GetByID(_ context.Context, id int) (*models.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.payouts[id]
+	if !ok {
+		return nil, nil
+	}
+	p := clonePayout(&stored.payout)
+	return &p, nil
+}
+
+func (s *MemoryPayoutStore) GetByReference(_ context.Context, merchantID int, reference string) (*models.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := s.byReference(merchantID, reference)
+	if stored == nil {
+		return nil, nil
+	}
+	p := clonePayout(&stored.payout)
+	return &p, nil
+}
+
+func (s *MemoryPayoutStore) GetByProviderReference(_ context.Context, providerReference string) (*models.Payout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var latest *memoryPayout
+	for _, stored := range s.payouts {
+		if stored.payout.ProviderReference == providerReference && (latest == nil || stored.payout.ID > latest.payout.ID) {
+			latest = stored
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	p := clonePayout(&latest.payout)
+	return &p, nil
+}
+
+// byReference finds the merchant's payout with the reference. The caller must hold s.mu.
+func (s *MemoryPayoutStore) byReference(merchantID int, reference string) *memoryPayout {
+	for _, stored := range s.payouts {
+		if stored.payout.MerchantID == merchantID && stored.payout.Reference == reference {
+			return stored
+		}
+	}
+	return nil
+}
+
+func (s *MemoryPayoutStore) ListPayouts(_ context.Context, f PayoutFilter, page PayoutPage) ([]*models.Payout, error) {
+	if _, _, _, err := page.keyset("", nil); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []*models.Payout
+	for _, stored := range s.matching(f) {
+		p := &stored.payout
+		if page.AfterValue != nil {
+			c := compareSortKey(p, page.Sort, page.AfterValue, page.AfterID)
+			if (!page.Desc && c <= 0) || (page.Desc && c >= 0) {
+				continue
+			}
+		}
+		copied := clonePayout(p)
+		list = append(list, &copied)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		c := compareSortKey(list[i], page.Sort, sortValue(list[j], page.Sort), list[j].ID)
+		if page.Desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	if page.Limit >= 0 && len(list) > page.Limit {
+		list = list[:page.Limit]
+	}
+	return list, nil
+}
+
+func (s *MemoryPayoutStore) UpdateStatus(_ context.Context, id int, from, to string, u StatusUpdate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.payouts[id]
+	if !ok {
+		return ErrPayoutNotFound
+	}
+	p := &stored.payout
+	if p.Status != from {
+		return ErrStatusConflict
+	}
+
+	now := s.now()
+	p.Status = to
+	if u.ProviderReference != "" {
+		p.ProviderReference = u.ProviderReference
+	}
+	p.FailureCode, p.FailureMessage = "", ""
+	if keepsFailure(to) {
+		p.FailureCode, p.FailureMessage = u.FailureCode, u.FailureMessage
+	}
+	if u.NewAttempt {
+		p.Attempts++
+	}
+	if isCompletedStatus(to) && p.CompletedAt == nil {
+		p.CompletedAt = &now
+	}
+	p.UpdatedAt = now
+	s.addEvent(models.PayoutEvent{
+		PayoutID:    id,
+		Type:        models.PayoutEventStatusChanged,
+		FromStatus:  from,
+		ToStatus:    to,
+		FailureCode: u.FailureCode,
+		Message:     u.FailureMessage,
+		Attempt:     p.Attempts,
+		CreatedAt:   now,
+	})
+	return nil
+}
+
+func (s *MemoryPayoutStore) ListEvents(_ context.Context, payoutID int) ([]models.PayoutEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Events are appended in time order, which is the order ListEvents returns.
+	events := []models.PayoutEvent{}
+	for _, e := range s.events {
+		if e.PayoutID == payoutID {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// addEvent numbers and stores an event. The caller must hold s.mu.
+func (s *MemoryPayoutStore) addEvent(e models.PayoutEvent) {
+	e.ID = len(s.events) + 1
+	s.events = append(s.events, e)
+}
+
+func (s *MemoryPayoutStore) ClaimStalePending(_ context.Context, olderThan time.Duration, limit int) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var ids []int
+	for id, stored := range s.payouts {
+		if stored.payout.Status == "pending" && stored.payout.UpdatedAt.Before(now.Add(-olderThan)) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	if limit >= 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		s.payouts[id].payout.UpdatedAt = now
+	}
+	return ids, nil
+}
+
+// SearchPayouts matches payouts whose recipient name, narration, reference and account
+// suffix contain every word of q, whose recipient name contains q, or whose reference or
+// account suffix equals q. Hits are ranked by how many of those tests they pass.
+func (s *MemoryPayoutStore) SearchPayouts(_ context.Context, f PayoutFilter, q string, limit int) ([]*models.PayoutSearchHit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	raw := strings.TrimSpace(q)
+	terms := strings.Fields(strings.ToLower(raw))
+	var hits []*models.PayoutSearchHit
+	for _, stored := range s.matching(f) {
+		p := &stored.payout
+		document := strings.Fields(strings.ToLower(strings.Join([]string{p.RecipientName, p.Narration, p.Reference, stored.last4}, " ")))
+
+		hit := &models.PayoutSearchHit{Highlights: map[string]string{}}
+		if len(terms) > 0 && containsAll(document, terms) {
+			hit.Rank++
+			if hl, ok := highlightWords(p.RecipientName, terms); ok {
+				hit.Highlights["recipient_name"] = hl
+			}
+			if hl, ok := highlightWords(p.Narration, terms); ok {
+				hit.Highlights["narration"] = hl
+			}
+		}
+		if raw != "" && strings.Contains(strings.ToLower(p.RecipientName), strings.ToLower(raw)) {
+			hit.Rank++
+		}
+		if p.Reference == q {
+			hit.Rank++
+			hit.Highlights["reference"] = markAll(q)
+		}
+		if stored.last4 != "" && stored.last4 == q {
+			hit.Rank++
+			hit.Highlights["recipient_account"] = "******" + markAll(q)
+		}
+		if hit.Rank == 0 {
+			continue
+		}
+		copied := clonePayout(p)
+		hit.Payout = &copied
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if !a.Payout.CreatedAt.Equal(b.Payout.CreatedAt) {
+			return a.Payout.CreatedAt.After(b.Payout.CreatedAt)
+		}
+		return a.Payout.ID > b.Payout.ID
+	})
+	if limit >= 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// SummarizePayouts computes the same rows, in the same order, as the grouping sets query of
+// PayoutRepository. Periods are truncated in UTC.
+func (s *MemoryPayoutStore) SummarizePayouts(_ context.Context, f PayoutFilter, interval string) ([]models.PayoutSummaryRow, error) {
+	switch interval {
+	case "day", "week", "month":
+	default:
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type bucket struct {
+		row              models.PayoutSummaryRow
+		completedSeconds float64
+		completed        int
+	}
+	buckets := map[models.PayoutSummaryRow]*bucket{}
+	add := func(key models.PayoutSummaryRow, p *models.Payout, status string) {
+		b, ok := buckets[key]
+		if !ok {
+			b = &bucket{row: key}
+			buckets[key] = b
+		}
+		b.row.Count++
+		b.row.Volume += p.Amount
+		switch status {
+		case "completed":
+			b.row.CompletedCount++
+		case "failed":
+			b.row.FailedCount++
+		}
+		if p.CompletedAt != nil {
+			b.completedSeconds += p.CompletedAt.Sub(p.CreatedAt).Seconds()
+			b.completed++
+		}
+	}
+
+	for _, stored := range s.matching(f) {
+		p := &stored.payout
+		status := p.Status
+		if status == "processed" {
+			status = "completed"
+		}
+		add(models.PayoutSummaryRow{Dimension: models.SummaryTotal, Currency: p.Currency}, p, status)
+		add(models.PayoutSummaryRow{Dimension: models.SummaryStatus, Currency: p.Currency, Status: status}, p, status)
+		add(models.PayoutSummaryRow{Dimension: models.SummaryBank, Currency: p.Currency, Bank: p.RecipientBank}, p, status)
+		add(models.PayoutSummaryRow{Dimension: models.SummaryPeriod, Currency: p.Currency, Period: truncatePeriod(p.CreatedAt, interval)}, p, status)
+	}
+
+	rows := make([]models.PayoutSummaryRow, 0, len(buckets))
+	for _, b := range buckets {
+		if b.completed > 0 {
+			avg := b.completedSeconds / float64(b.completed)
+			b.row.AvgCompletionSeconds = &avg
+		}
+		rows = append(rows, b.row)
+	}
+	// ORDER BY currency, period, status, bank with NULLs last: period rows first, then status,
+	// bank and finally the currency total.
+	rank := map[string]int{models.SummaryPeriod: 0, models.SummaryStatus: 1, models.SummaryBank: 2, models.SummaryTotal: 3}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch {
+		case a.Currency != b.Currency:
+			return a.Currency < b.Currency
+		case a.Dimension != b.Dimension:
+			return rank[a.Dimension] < rank[b.Dimension]
+		case !a.Period.Equal(b.Period):
+			return a.Period.Before(b.Period)
+		case a.Status != b.Status:
+			return a.Status < b.Status
+		default:
+			return a.Bank < b.Bank
+		}
+	})
+	return rows, nil
+}
+
+func (s *MemoryPayoutStore) CountPayouts(_ context.Context, f PayoutFilter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.matching(f)), nil
+}
+
+// StreamPayouts copies the matches before calling fn, so fn may use the store.
+func (s *MemoryPayoutStore) StreamPayouts(ctx context.Context, f PayoutFilter, fn func(*models.Payout) error) error {
+	list, err := s.ListPayouts(ctx, f, PayoutPage{Sort: SortCreatedAt, Limit: -1})
+	if err != nil {
+		return err
+	}
+	for _, p := range list {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemoryPayoutStore) AccountIndex(account string) string {
+	return s.keys.BlindIndex(account)
+}
+
+// matching returns the stored payouts matching the filter, mirroring where. The caller
+// must hold s.mu.
+func (s *MemoryPayoutStore) matching(f PayoutFilter) []*memoryPayout {
+	if f.RecipientAccount != "" {
+		f.RecipientAccountHash = s.keys.BlindIndex(f.RecipientAccount)
+	}
+	var list []*memoryPayout
+	for _, stored := range s.payouts {
+		p := &stored.payout
+		switch {
+		case p.MerchantID != f.MerchantID,
+			len(f.Statuses) > 0 && !slices.Contains(f.Statuses, p.Status),
+			f.Currency != "" && p.Currency != f.Currency,
+			f.MinAmount != nil && p.Amount < *f.MinAmount,
+			f.MaxAmount != nil && p.Amount > *f.MaxAmount,
+			f.CreatedFrom != nil && p.CreatedAt.Before(*f.CreatedFrom),
+			f.CreatedTo != nil && !p.CreatedAt.Before(*f.CreatedTo),
+			f.UpdatedFrom != nil && p.UpdatedAt.Before(*f.UpdatedFrom),
+			f.UpdatedTo != nil && !p.UpdatedAt.Before(*f.UpdatedTo),
+			f.Reference != nil && p.Reference != *f.Reference,
+			f.RecipientAccountHash != "" && stored.accountHash != f.RecipientAccountHash:
+			continue
+		}
+		list = append(list, stored)
+	}
+	return list
+}
+
+// sortValue returns the value of p's sort column in the form PayoutPage.AfterValue uses.
+func sortValue(p *models.Payout, column string) any {
+	switch column {
+	case SortUpdatedAt:
+		return p.UpdatedAt
+	case SortAmount:
+		return p.Amount
+	default:
+		return p.CreatedAt
+	}
+}
+
+// compareSortKey compares p's (sort column, id) with (value, id) like a SQL row comparison.
+func compareSortKey(p *models.Payout, column string, value any, id int) int {
+	var c int
+	switch v := sortValue(p, column).(type) {
+	case time.Time:
+		c = v.Compare(value.(time.Time))
+	case int64:
+		c = cmpInt64(v, value.(int64))
+	}
+	if c != 0 {
+		return c
+	}
+	return cmpInt64(int64(p.ID), int64(id))
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func truncatePeriod(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		// date_trunc weeks start on Monday.
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "month":
+		return day.AddDate(0, 0, 1-day.Day())
+	default:
+		return day
+	}
+}
+
+func containsAll(words, terms []string) bool {
+	for _, term := range terms {
+		if !slices.Contains(words, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// highlightWords wraps the words of text that equal one of terms in <mark> tags, like
+// ts_headline with HighlightAll followed by markHighlight. It reports whether any word was
+// highlighted.
+func highlightWords(text string, terms []string) (string, bool) {
+	words := strings.Fields(text)
+	marked := false
+	for i, w := range words {
+		if slices.Contains(terms, strings.ToLower(w)) {
+			words[i] = markAll(w)
+			marked = true
+		} else {
+			words[i] = html.EscapeString(w)
+		}
+	}
+	return strings.Join(words, " "), marked
+}
+
+func clonePayout(p *models.Payout) models.Payout {
+	c := *p
+	if p.CompletedAt != nil {
+		t := *p.CompletedAt
+		c.CompletedAt = &t
+	}
+	return c
+}
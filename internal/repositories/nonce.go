@@ -0,0 +1,64 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// NonceRepository stores the nonces of signed internal requests in Postgres so a request
+// can't be replayed against another replica.
+type NonceRepository struct {
+	db *sql.DB
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewNonceRepository(db *sql.DB) *NonceRepository {
+	return &NonceRepository{db: db}
+}
+
+// Claim records key until expires. It reports false if the key is already recorded and
+// hasn't expired.
+func (r *NonceRepository) Claim(ctx context.Context, key string, expires time.Time) (bool, error) {
+	r.maybeCleanup(time.Now())
+
+	query := `
+		INSERT INTO request_nonces (key, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET expires_at = EXCLUDED.expires_at
+			WHERE request_nonces.expires_at <= NOW()
+		RETURNING key
+	`
+	var claimed string
+	err := r.db.QueryRowContext(ctx, query, key, expires).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// maybeCleanup deletes expired nonces at most once a minute per instance, off the request path.
+func (r *NonceRepository) maybeCleanup(now time.Time) {
+	r.mu.Lock()
+	if now.Sub(r.lastCleanup) < time.Minute {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCleanup = now
+	r.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM request_nonces WHERE expires_at <= NOW()`); err != nil {
+			slog.Warn("failed to clean up request nonces", slog.Any("error", err))
+		}
+	}()
+}
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// RateLimitRepository stores rate limit window counters in Postgres so limits hold across replicas.
+type RateLimitRepository struct {
+	db *sql.DB
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewRateLimitRepository(db *sql.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db}
+}
+
+// Hit increments the counter for the window starting at windowStart and returns it with the
+// previous window's count, in a single round trip.
+func (r *RateLimitRepository) Hit(ctx context.Context, key string, windowStart time.Time, window time.Duration) (int, int, error) {
+	r.maybeCleanup(windowStart, window)
+
+	query := `
+		WITH hit AS (
+			INSERT INTO rate_limit_counters (key, window_start, count)
+			VALUES ($1, $2, 1)
+			ON CONFLICT (key, window_start) DO UPDATE SET count = rate_limit_counters.count + 1
+			RETURNING count
+		)
+		SELECT (SELECT count FROM hit),
+			COALESCE((SELECT count FROM rate_limit_counters WHERE key = $1 AND window_start = $3), 0)
+	`
+	var current, previous int
+	err := r.db.QueryRowContext(ctx, query, key, windowStart, windowStart.Add(-window)).Scan(&current, &previous)
+	return current, previous, err
+}
+
+// maybeCleanup deletes expired counters at most once a minute per instance, off the request path.
+func (r *RateLimitRepository) maybeCleanup(now time.Time, window time.Duration) {
+	r.mu.Lock()
+	if now.Sub(r.lastCleanup) < time.Minute {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCleanup = now
+	r.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		// Keep two windows' worth of counters (at least two days) so the previous window survives.
+		cutoff := now.Add(-2 * maxDuration(window, 24*time.Hour))
+		if _, err := r.db.ExecContext(ctx, `DELETE FROM rate_limit_counters WHERE window_start < $1`, cutoff); err != nil {
+			slog.Warn("failed to clean up rate limit counters", slog.Any("error", err))
+		}
+	}()
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
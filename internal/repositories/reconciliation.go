@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+)
+
+const reconItemColumns = `id, run_id, payout_id, line_reference, bank_reference, line_amount, line_currency, booking_date, description, result, reason, resolved, resolved_by, resolution_note, resolved_at, created_at`
+
+// ReconciliationStore keeps reconciliation runs and their items. ReconciliationRepository
+// stores them in Postgres.
+type ReconciliationStore interface {
+	CreateRun(ctx context.Context, run *models.ReconciliationRun, items []*models.ReconciliationItem) error
+	GetRun(ctx context.Context, id int) (*models.ReconciliationRun, error)
+	ListItems(ctx context.Context, runID int) ([]*models.ReconciliationItem, error)
+	ListExceptions(ctx context.Context, runID int, unresolvedOnly bool, limit int) ([]*models.ReconciliationItem, error)
+	GetItem(ctx context.Context, id int) (*models.ReconciliationItem, error)
+	ResolveItem(ctx context.Context, id int, resolvedBy, note string) error
+	// IsPayoutMatched reports whether an earlier run matched a line to the payout.
+	IsPayoutMatched(ctx context.Context, payoutID int) (bool, error)
+}
+
+var _ ReconciliationStore = (*ReconciliationRepository)(nil)
+
+type ReconciliationRepository struct {
+	db *sql.DB
+}
+
+func NewReconciliationRepository(db *sql.DB) *ReconciliationRepository {
+	return &ReconciliationRepository{db: db}
+}
+
+// CreateRun stores a finished run together with its items in a single transaction.
+func (r *ReconciliationRepository) CreateRun(ctx context.Context, run *models.ReconciliationRun, items []*models.ReconciliationItem) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO reconciliation_runs (format, file_name, statement_id, account_id, total_lines, matched_count, unmatched_count, mismatched_count, created_at, completed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING id, created_at, completed_at
+	`,
+		run.Format, run.FileName, run.StatementID, run.AccountID,
+		run.TotalLines, run.MatchedCount, run.UnmatchedCount, run.MismatchedCount,
+	).Scan(&run.ID, &run.CreatedAt, &run.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("insert reconciliation run: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO reconciliation_items (run_id, payout_id, line_reference, bank_reference, line_amount, line_currency, booking_date, description, result, reason, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		RETURNING id, created_at
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, it := range items {
+		it.RunID = run.ID
+		if err := stmt.QueryRowContext(ctx,
+			it.RunID, it.PayoutID, it.LineReference, it.BankReference, it.LineAmount,
+			it.LineCurrency, it.BookingDate, it.Description, it.Result, it.Reason,
+		).Scan(&it.ID, &it.CreatedAt); err != nil {
+			return fmt.Errorf("insert reconciliation item: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *ReconciliationRepository) GetRun(ctx context.Context, id int) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, format, file_name, statement_id, account_id, total_lines, matched_count, unmatched_count, mismatched_count, created_at, completed_at
+		FROM reconciliation_runs
+		WHERE id = $1
+	`, id).Scan(
+		&run.ID, &run.Format, &run.FileName, &run.StatementID, &run.AccountID,
+		&run.TotalLines, &run.MatchedCount, &run.UnmatchedCount, &run.MismatchedCount,
+		&run.CreatedAt, &run.CompletedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *ReconciliationRepository) ListItems(ctx context.Context, runID int) ([]*models.ReconciliationItem, error) {
+	return r.queryItems(ctx, `
+		SELECT `+reconItemColumns+`
+		FROM reconciliation_items
+		WHERE run_id = $1
+		ORDER BY id
+	`, runID)
+}
+
+// ListExceptions returns unmatched and mismatched items, optionally limited to one run
+// (runID 0 means all runs) and to unresolved items only.
+func (r *ReconciliationRepository) ListExceptions(ctx context.Context, runID int, unresolvedOnly bool, limit int) ([]*models.ReconciliationItem, error) {
+	return r.queryItems(ctx, `
+		SELECT `+reconItemColumns+`
+		FROM reconciliation_items
+		WHERE result <> $1
+		  AND ($2 = 0 OR run_id = $2)
+		  AND (NOT $3 OR resolved = FALSE)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4
+	`, models.ReconMatched, runID, unresolvedOnly, limit)
+}
+
+func (r *ReconciliationRepository) GetItem(ctx context.Context, id int) (*models.ReconciliationItem, error) {
+	items, err := r.queryItems(ctx, `
+		SELECT `+reconItemColumns+`
+		FROM reconciliation_items
+		WHERE id = $1
+	`, id)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	return items[0], nil
+}
+
+// ResolveItem marks an exception as handled by ops.
+func (r *ReconciliationRepository) ResolveItem(ctx context.Context, id int, resolvedBy, note string) error {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE reconciliation_items
+		SET resolved = TRUE, resolved_by = $2, resolution_note = $3, resolved_at = NOW()
+		WHERE id = $1
+	`, id, resolvedBy, note)
+	if err != nil {
+		return err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("reconciliation item not found")
+	}
+	return nil
+}
+
+// IsPayoutMatched reports whether a payout was already matched by an earlier run.
+func (r *ReconciliationRepository) IsPayoutMatched(ctx context.Context, payoutID int) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM reconciliation_items WHERE payout_id = $1 AND result = $2)
+	`, payoutID, models.ReconMatched).Scan(&exists)
+	return exists, err
+}
+
+func (r *ReconciliationRepository) queryItems(ctx context.Context, query string, args ...any) ([]*models.ReconciliationItem, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.ReconciliationItem
+	for rows.Next() {
+		var it models.ReconciliationItem
+		var payoutID sql.NullInt64
+		if err := rows.Scan(
+			&it.ID, &it.RunID, &payoutID, &it.LineReference, &it.BankReference, &it.LineAmount,
+			&it.LineCurrency, &it.BookingDate, &it.Description, &it.Result, &it.Reason,
+			&it.Resolved, &it.ResolvedBy, &it.ResolutionNote, &it.ResolvedAt, &it.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if payoutID.Valid {
+			id := int(payoutID.Int64)
+			it.PayoutID = &id
+		}
+		list = append(list, &it)
+	}
+	return list, rows.Err()
+}
@@ -3,72 +3,178 @@ package repositories
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
 	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/tracing"
 )
 
-type PayoutRepository struct {
-	db *sql.DB
+// payoutColumns selects a payout. recipient_account only holds a value for rows written
+// before account numbers were encrypted; see ReencryptAccounts.
+const payoutColumns = `id, merchant_id, reference, amount, fee, currency, recipient_name, recipient_account, recipient_account_ciphertext, recipient_account_dek, recipient_account_key_version, recipient_bank, status, narration, provider_reference, failure_code, failure_message, attempts, created_at, updated_at, completed_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPayout scans payoutColumns followed by any extra columns and decrypts the account number.
+func (r *PayoutRepository) scanPayout(row rowScanner, extra ...any) (*models.Payout, error) {
+	var p models.Payout
+	var account fieldcrypt.Field
+	dest := []any{
+		&p.ID, &p.MerchantID, &p.Reference, &p.Amount, &p.Fee, &p.Currency,
+		&p.RecipientName, &p.RecipientAccount, &account.Ciphertext, &account.WrappedKey, &account.KeyVersion, &p.RecipientBank,
+		&p.Status, &p.Narration, &p.ProviderReference, &p.FailureCode, &p.FailureMessage, &p.Attempts, &p.CreatedAt, &p.UpdatedAt, &p.CompletedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	if account.Ciphertext != nil {
+		plain, err := r.keys.Decrypt(account)
+		if err != nil {
+			return nil, fmt.Errorf("payout %d: %w", p.ID, err)
+		}
+		p.RecipientAccount = plain
+	}
+	return &p, nil
 }
 
-func NewPayoutRepository(dsn string) (*PayoutRepository, error) {
+// Open configures the shared connection pool. It doesn't connect: use WaitForDB (or the
+// first query) to find out whether Postgres is reachable.
+func Open(dsn string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("ping db: %w", err)
-	}
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(5)
 	db.SetConnMaxLifetime(5 * time.Minute)
-	return &PayoutRepository{db: db}, nil
+	return db, nil
+}
+
+// PayoutRepository stores payouts. Recipient account numbers are envelope-encrypted with
+// keys and indexed by a blind index hash plus their last four digits; plaintext account
+// numbers never reach the table.
+type PayoutRepository struct {
+	db   *sql.DB
+	keys *fieldcrypt.Keyring
+}
+
+func NewPayoutRepository(db *sql.DB, keys *fieldcrypt.Keyring) *PayoutRepository {
+	return &PayoutRepository{db: db, keys: keys}
 }
 
-func (r *PayoutRepository) Create(ctx context.Context, p *models.Payout) error {
+func (r *PayoutRepository) Create(ctx context.Context, p *models.Payout) (err error) {
+	ctx, span := startQuery(ctx, "Create", tracing.MerchantIDKey.Int(p.MerchantID))
+	defer func() { tracing.End(span, err) }()
+
+	account, err := r.keys.Encrypt(p.RecipientAccount)
+	if err != nil {
+		return fmt.Errorf("encrypt recipient account: %w", err)
+	}
+	// The created event is written by the same statement, so a payout never exists without it.
 	query := `
-		INSERT INTO payouts (merchant_id, reference, amount, currency, recipient_name, recipient_account, recipient_bank, status, narration, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
-		RETURNING id, created_at, updated_at
+		WITH created AS (
+			INSERT INTO payouts (merchant_id, reference, amount, fee, currency, recipient_name,
+				recipient_account_ciphertext, recipient_account_dek, recipient_account_key_version, recipient_account_hash, recipient_account_last4,
+				recipient_bank, status, narration, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
+			RETURNING id, status, attempts, created_at, updated_at
+		), event AS (
+			INSERT INTO payout_events (payout_id, type, to_status, attempt, created_at)
+			SELECT id, 'created', status, attempts, created_at FROM created
+		)
+		SELECT id, attempts, created_at, updated_at FROM created
 	`
-	return r.db.QueryRowContext(ctx, query,
-		p.MerchantID, p.Reference, p.Amount, p.Currency,
-		p.RecipientName, p.RecipientAccount, p.RecipientBank,
-		p.Status, p.Narration,
-	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	err = r.db.QueryRowContext(ctx, query,
+		p.MerchantID, p.Reference, p.Amount, p.Fee, p.Currency, p.RecipientName,
+		account.Ciphertext, account.WrappedKey, account.KeyVersion,
+		r.keys.BlindIndex(p.RecipientAccount), fieldcrypt.Last4(p.RecipientAccount),
+		p.RecipientBank, p.Status, p.Narration,
+	).Scan(&p.ID, &p.Attempts, &p.CreatedAt, &p.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "payouts_merchant_reference_key" { // unique_violation
+		return ErrDuplicateReference
+	}
+	return err
 }
 
-func (r *PayoutRepository) GetByID(ctx context.Context, id int) (*models.Payout, error) {
+func (r *PayoutRepository) GetByID(ctx context.Context, id int) (_ *models.Payout, err error) {
+	ctx, span := startQuery(ctx, "GetByID", tracing.PayoutIDKey.Int(id))
+	defer func() { tracing.End(span, err) }()
+
 	query := `
-		SELECT id, merchant_id, reference, amount, currency, recipient_name, recipient_account, recipient_bank, status, narration, created_at, updated_at
+		SELECT ` + payoutColumns + `
 		FROM payouts
 		WHERE id = $1
 	`
-	var p models.Payout
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&p.ID, &p.MerchantID, &p.Reference, &p.Amount, &p.Currency,
-		&p.RecipientName, &p.RecipientAccount, &p.RecipientBank,
-		&p.Status, &p.Narration, &p.CreatedAt, &p.UpdatedAt,
-	)
+	p, err := r.scanPayout(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func (r *PayoutRepository) GetByReference(ctx context.Context, merchantID int, reference string) (_ *models.Payout, err error) {
+	ctx, span := startQuery(ctx, "GetByReference", tracing.MerchantIDKey.Int(merchantID))
+	defer func() { tracing.End(span, err) }()
+
+	query := `
+		SELECT ` + payoutColumns + `
+		FROM payouts
+		WHERE merchant_id = $1 AND reference = $2
+	`
+	p, err := r.scanPayout(r.db.QueryRowContext(ctx, query, merchantID, reference))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	return &p, err
+	return p, err
 }
 
-func (r *PayoutRepository) ListByMerchant(ctx context.Context, merchantID int, limit int) ([]*models.Payout, error) {
+// GetByProviderReference looks a payout up by the reference assigned by the payout provider/bank.
+func (r *PayoutRepository) GetByProviderReference(ctx context.Context, providerReference string) (_ *models.Payout, err error) {
+	ctx, span := startQuery(ctx, "GetByProviderReference")
+	defer func() { tracing.End(span, err) }()
+
 	query := `
-		SELECT id, merchant_id, reference, amount, currency, recipient_name, recipient_account, recipient_bank, status, narration, created_at, updated_at
+		SELECT ` + payoutColumns + `
 		FROM payouts
-		WHERE merchant_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2
+		WHERE provider_reference = $1
+		ORDER BY id DESC
+		LIMIT 1
 	`
-	rows, err := r.db.QueryContext(ctx, query, merchantID, limit)
+	p, err := r.scanPayout(r.db.QueryRowContext(ctx, query, providerReference))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ListPayouts returns up to page.Limit payouts matching the filter, ordered and seeked by the page.
+func (r *PayoutRepository) ListPayouts(ctx context.Context, f PayoutFilter, page PayoutPage) (_ []*models.Payout, err error) {
+	ctx, span := startQuery(ctx, "ListPayouts", tracing.MerchantIDKey.Int(f.MerchantID))
+	defer func() { tracing.End(span, err) }()
+
+	where, args := r.where(f)
+	where, order, args, err := page.keyset(where, args)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, page.Limit)
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM payouts
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d
+	`, payoutColumns, where, order, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -76,27 +182,40 @@ func (r *PayoutRepository) ListByMerchant(ctx context.Context, merchantID int, l
 
 	var list []*models.Payout
 	for rows.Next() {
-		var p models.Payout
-		if err := rows.Scan(
-			&p.ID, &p.MerchantID, &p.Reference, &p.Amount, &p.Currency,
-			&p.RecipientName, &p.RecipientAccount, &p.RecipientBank,
-			&p.Status, &p.Narration, &p.CreatedAt, &p.UpdatedAt,
-		); err != nil {
+		p, err := r.scanPayout(rows)
+		if err != nil {
 			return nil, err
 		}
-		list = append(list, &p)
+		list = append(list, p)
 	}
 	return list, rows.Err()
 }
 
-// UpdateStatus updates the payout status and refreshed updated_at.
-func (r *PayoutRepository) UpdateStatus(ctx context.Context, id int, status string) error {
+// UpdateStatus moves the payout from status from to status to and refreshes updated_at,
+// stamping completed_at the first time the payout reaches a completed status. The update
+// only applies while the payout is still in from, so concurrent writers can't overwrite
+// each other's transitions, and writes the status_changed event in the same statement.
+func (r *PayoutRepository) UpdateStatus(ctx context.Context, id int, from, to string, u StatusUpdate) (err error) {
+	ctx, span := startQuery(ctx, "UpdateStatus", tracing.PayoutIDKey.Int(id))
+	defer func() { tracing.End(span, err) }()
+
 	query := `
-		UPDATE payouts
-		SET status = $2, updated_at = NOW()
-		WHERE id = $1
+		WITH updated AS (
+			UPDATE payouts
+			SET status = $2,
+				provider_reference = COALESCE(NULLIF($3, ''), provider_reference),
+				failure_code = CASE WHEN $2 IN ('failed', 'returned', 'reversed') THEN $5 ELSE '' END,
+				failure_message = CASE WHEN $2 IN ('failed', 'returned', 'reversed') THEN $6 ELSE '' END,
+				attempts = CASE WHEN $7 THEN attempts + 1 ELSE attempts END,
+				completed_at = CASE WHEN $2 IN ('processed', 'completed') THEN COALESCE(completed_at, NOW()) ELSE completed_at END,
+				updated_at = NOW()
+			WHERE id = $1 AND status = $4
+			RETURNING id, attempts, updated_at
+		)
+		INSERT INTO payout_events (payout_id, type, from_status, to_status, failure_code, message, attempt, created_at)
+		SELECT id, 'status_changed', $4, $2, $5, $6, attempts, updated_at FROM updated
 	`
-	res, err := r.db.ExecContext(ctx, query, id, status)
+	res, err := r.db.ExecContext(ctx, query, id, to, u.ProviderReference, from, u.FailureCode, u.FailureMessage, u.NewAttempt)
 	if err != nil {
 		return err
 	}
@@ -104,8 +223,123 @@ func (r *PayoutRepository) UpdateStatus(ctx context.Context, id int, status stri
 	if err != nil {
 		return err
 	}
-	if affected == 0 {
-		return fmt.Errorf("payout not found")
+	if affected > 0 {
+		return nil
 	}
-	return nil
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM payouts WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPayoutNotFound
+	}
+	return ErrStatusConflict
+}
+
+func (r *PayoutRepository) ListEvents(ctx context.Context, payoutID int) (_ []models.PayoutEvent, err error) {
+	ctx, span := startQuery(ctx, "ListEvents", tracing.PayoutIDKey.Int(payoutID))
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, payout_id, type, from_status, to_status, failure_code, message, attempt, created_at
+		FROM payout_events
+		WHERE payout_id = $1
+		ORDER BY created_at, id
+	`, payoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.PayoutEvent{}
+	for rows.Next() {
+		var e models.PayoutEvent
+		if err := rows.Scan(&e.ID, &e.PayoutID, &e.Type, &e.FromStatus, &e.ToStatus, &e.FailureCode, &e.Message, &e.Attempt, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// ClaimStalePending returns up to limit payouts that have been pending for longer than
+// olderThan, touching their updated_at so other callers don't claim them again right away.
+func (r *PayoutRepository) ClaimStalePending(ctx context.Context, olderThan time.Duration, limit int) (_ []int, err error) {
+	ctx, span := startQuery(ctx, "ClaimStalePending")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, `
+		UPDATE payouts
+		SET updated_at = NOW()
+		WHERE id IN (
+			SELECT id
+			FROM payouts
+			WHERE status = 'pending' AND updated_at < NOW() - make_interval(secs => $1)
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`, olderThan.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CountPayouts returns how many payouts match the filter.
+func (r *PayoutRepository) CountPayouts(ctx context.Context, f PayoutFilter) (n int, err error) {
+	ctx, span := startQuery(ctx, "CountPayouts", tracing.MerchantIDKey.Int(f.MerchantID))
+	defer func() { tracing.End(span, err) }()
+
+	where, args := r.where(f)
+	err = r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM payouts WHERE `+where, args...).Scan(&n)
+	return n, err
+}
+
+// StreamPayouts calls fn for every payout matching the filter, oldest first, without
+// buffering the result set. Iteration stops at the first error returned by fn.
+func (r *PayoutRepository) StreamPayouts(ctx context.Context, f PayoutFilter, fn func(*models.Payout) error) (err error) {
+	ctx, span := startQuery(ctx, "StreamPayouts", tracing.MerchantIDKey.Int(f.MerchantID))
+	defer func() { tracing.End(span, err) }()
+
+	where, args := r.where(f)
+	query := `
+		SELECT ` + payoutColumns + `
+		FROM payouts
+		WHERE ` + where + `
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := r.scanPayout(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// AccountIndex returns the blind index hash used to look payouts up by account number.
+func (r *PayoutRepository) AccountIndex(account string) string {
+	return r.keys.BlindIndex(account)
 }
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// payoutSearchDocument is the text indexed for full-text search. Only the last four digits
+// of the (encrypted) account number are available to it.
+// It must stay identical to the expression of the payouts_search_idx GIN index created by
+// migration 0001_initial_schema; recipient names are additionally covered by its
+// payouts_recipient_name_trgm_idx pg_trgm index for fuzzy matching.
+const payoutSearchDocument = `to_tsvector('simple', recipient_name || ' ' || narration || ' ' || reference || ' ' || recipient_account_last4)`
+
+// ts_headline marks matches with private-use characters rather than <mark> tags so the
+// stored text can be HTML-escaped before the tags are added (see markHighlight).
+const (
+	highlightStart   = "\ue000"
+	highlightStop    = "\ue001"
+	highlightOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", HighlightAll=true"
+)
+
+var highlightMarks = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// markHighlight turns a ts_headline result into HTML: the text is escaped, then the
+// highlighted words are wrapped in <mark> tags. It reports whether any word was highlighted.
+func markHighlight(headline string) (string, bool) {
+	if !strings.Contains(headline, highlightStart) {
+		return "", false
+	}
+	return highlightMarks.Replace(html.EscapeString(headline)), true
+}
+
+// markAll wraps the whole, HTML-escaped text in <mark> tags.
+func markAll(text string) string {
+	return "<mark>" + html.EscapeString(text) + "</mark>"
+}
+
+// SearchPayouts ranks the merchant's payouts matching the filter against a free-text query.
+// Matches come from the full-text document, trigram word similarity on the recipient name,
+// or an exact reference / account suffix match.
+func (r *PayoutRepository) SearchPayouts(ctx context.Context, f PayoutFilter, q string, limit int) (_ []*models.PayoutSearchHit, err error) {
+	ctx, span := startQuery(ctx, "SearchPayouts", tracing.MerchantIDKey.Int(f.MerchantID))
+	defer func() { tracing.End(span, err) }()
+
+	where, args := r.where(f)
+	args = append(args, q, limit)
+	qArg, limitArg := len(args)-1, len(args)
+
+	query := fmt.Sprintf(`
+		WITH q AS (SELECT plainto_tsquery('simple', $%[1]d) AS tsq, $%[1]d::text AS raw)
+		SELECT %[2]s,
+			ts_rank(%[3]s, q.tsq) + word_similarity(q.raw, recipient_name) AS rank,
+			ts_headline('simple', recipient_name, q.tsq, '%[4]s'),
+			ts_headline('simple', narration, q.tsq, '%[4]s'),
+			reference = q.raw,
+			recipient_account_last4 = q.raw
+		FROM payouts, q
+		WHERE %[5]s
+		  AND (%[3]s @@ q.tsq
+		       OR q.raw <%% recipient_name
+		       OR reference = q.raw
+		       OR recipient_account_last4 = q.raw)
+		ORDER BY rank DESC, created_at DESC, id DESC
+		LIMIT $%[6]d
+	`, qArg, payoutColumns, payoutSearchDocument, highlightOptions, where, limitArg)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []*models.PayoutSearchHit
+	for rows.Next() {
+		var hit models.PayoutSearchHit
+		var nameHL, narrationHL string
+		var refMatch, accountMatch bool
+		p, err := r.scanPayout(rows, &hit.Rank, &nameHL, &narrationHL, &refMatch, &accountMatch)
+		if err != nil {
+			return nil, err
+		}
+
+		hit.Payout = p
+		hit.Highlights = map[string]string{}
+		if hl, ok := markHighlight(nameHL); ok {
+			hit.Highlights["recipient_name"] = hl
+		}
+		if hl, ok := markHighlight(narrationHL); ok {
+			hit.Highlights["narration"] = hl
+		}
+		if refMatch {
+			hit.Highlights["reference"] = markAll(q)
+		}
+		if accountMatch {
+			hit.Highlights["recipient_account"] = "******" + markAll(q)
+		}
+		hits = append(hits, &hit)
+	}
+	return hits, rows.Err()
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+)
+
+var (
+	// ErrPayoutNotFound is returned by writes to a payout that doesn't exist. Reads return
+	// a nil payout instead.
+	ErrPayoutNotFound = errors.New("payout not found")
+	// ErrStatusConflict is returned by UpdateStatus when the payout is no longer in the
+	// status the caller read, i.e. another writer changed it first.
+	ErrStatusConflict = errors.New("payout status was changed concurrently")
+	// ErrDuplicateReference is returned by Create when the merchant already has a payout
+	// with the same reference.
+	ErrDuplicateReference = errors.New("payout reference already used")
+)
+
+// PayoutStore is the payout persistence used by PayoutService. PayoutRepository stores
+// payouts in Postgres; MemoryPayoutStore keeps them in process memory for tests and local
+// runs. Both must behave identically, which the conformance tests in store_test.go check.
+type PayoutStore interface {
+	// Create assigns the payout its ID and timestamps and starts its first attempt. References are unique per merchant;
+	// reusing one fails with ErrDuplicateReference.
+	Create(ctx context.Context, p *models.Payout) error
+	// GetByID returns nil, nil when the payout doesn't exist.
+	GetByID(ctx context.Context, id int) (*models.Payout, error)
+	// GetByReference returns the merchant's payout with the reference, or nil, nil.
+	GetByReference(ctx context.Context, merchantID int, reference string) (*models.Payout, error)
+	// GetByProviderReference returns the latest payout with the provider's reference, or
+	// nil, nil.
+	GetByProviderReference(ctx context.Context, providerReference string) (*models.Payout, error)
+	ListPayouts(ctx context.Context, f PayoutFilter, page PayoutPage) ([]*models.Payout, error)
+	// UpdateStatus moves the payout from status from to status to and records the change
+	// as an event. It fails with ErrStatusConflict if the payout is no longer in from, and
+	// ErrPayoutNotFound if it doesn't exist.
+	UpdateStatus(ctx context.Context, id int, from, to string, u StatusUpdate) error
+	// ListEvents returns the payout's timeline, oldest first.
+	ListEvents(ctx context.Context, payoutID int) ([]models.PayoutEvent, error)
+	ClaimStalePending(ctx context.Context, olderThan time.Duration, limit int) ([]int, error)
+	SearchPayouts(ctx context.Context, f PayoutFilter, q string, limit int) ([]*models.PayoutSearchHit, error)
+	SummarizePayouts(ctx context.Context, f PayoutFilter, interval string) ([]models.PayoutSummaryRow, error)
+	// CountPayouts and StreamPayouts serve exports. StreamPayouts calls fn for every match,
+	// oldest first, and stops at the first error fn returns.
+	CountPayouts(ctx context.Context, f PayoutFilter) (int, error)
+	StreamPayouts(ctx context.Context, f PayoutFilter, fn func(*models.Payout) error) error
+	// AccountIndex returns the blind index hash PayoutFilter.RecipientAccountHash matches.
+	AccountIndex(account string) string
+}
+
+// StatusUpdate holds what UpdateStatus writes besides the status. A non-empty
+// ProviderReference replaces the stored one; an empty one keeps it. The failure code and
+// message are kept on the payout while it is failed, returned or reversed and cleared when
+// it leaves those statuses; the event of the change keeps them either way. NewAttempt counts the change as
+// another processing attempt, as retries do.
+type StatusUpdate struct {
+	ProviderReference string
+	FailureCode       string
+	FailureMessage    string
+	NewAttempt        bool
+}
+
+var (
+	_ PayoutStore = (*PayoutRepository)(nil)
+	_ PayoutStore = (*MemoryPayoutStore)(nil)
+)
+
+// keepsFailure reports whether a payout in status keeps the failure code and message of
+// the change that put it there.
+func keepsFailure(status string) bool {
+	return status == "failed" || status == "returned" || status == "reversed"
+}
+
+// isCompletedStatus reports whether reaching status stamps completed_at.
+func isCompletedStatus(status string) bool {
+	return status == "processed" || status == "completed"
+}
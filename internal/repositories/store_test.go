@@ -0,0 +1,616 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// Base64 test keys; never use these outside tests.
+const (
+	testEncryptionKeys = "1:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="
+	testBlindIndexKey  = "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXoxMjM0NTY="
+)
+
+func testKeys(t *testing.T) *fieldcrypt.Keyring {
+	t.Helper()
+	keys, err := fieldcrypt.NewKeyring(testEncryptionKeys, "", 1, testBlindIndexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// stores returns every PayoutStore implementation to run the conformance tests against.
+// Postgres is included when TEST_POSTGRES_URL points at a migrated database
+// (payout-service migrate up).
+func stores(t *testing.T) map[string]func(t *testing.T) repositories.PayoutStore {
+	t.Helper()
+	impls := map[string]func(t *testing.T) repositories.PayoutStore{
+		"memory": func(t *testing.T) repositories.PayoutStore {
+			return repositories.NewMemoryPayoutStore(testKeys(t))
+		},
+	}
+	if dsn := os.Getenv("TEST_POSTGRES_URL"); dsn != "" {
+		impls["postgres"] = func(t *testing.T) repositories.PayoutStore {
+			db, err := repositories.Open(dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+			if err := db.Ping(); err != nil {
+				t.Fatalf("ping test database: %v", err)
+			}
+			return repositories.NewPayoutRepository(db, testKeys(t))
+		}
+	}
+	return impls
+}
+
+// newMerchantID hands out a merchant per test so tests sharing a database don't see each
+// other's payouts.
+var nextMerchantID = int(time.Now().UnixNano() % 1_000_000_000)
+
+func newMerchantID() int {
+	nextMerchantID++
+	return nextMerchantID
+}
+
+// nextReference keeps the references newPayout hands out unique, as Create requires per
+// merchant.
+var nextReference int
+
+func newPayout(merchantID int, amount int64, account string) *models.Payout {
+	nextReference++
+	return &models.Payout{
+		MerchantID:       merchantID,
+		Reference:        "REF-" + strconv.Itoa(nextReference),
+		Amount:           amount,
+		Currency:         "NGN",
+		RecipientName:    "Ada Obi",
+		RecipientAccount: account,
+		RecipientBank:    "058",
+		Status:           "pending",
+		Narration:        "salary june",
+	}
+}
+
+// mustCreate stores p. It waits a moment first so payouts created in a row get distinct
+// timestamps and the time filters are deterministic.
+func mustCreate(t *testing.T, store repositories.PayoutStore, p *models.Payout) *models.Payout {
+	t.Helper()
+	time.Sleep(time.Millisecond)
+	if err := store.Create(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func ids(list []*models.Payout) []int {
+	out := make([]int, len(list))
+	for i, p := range list {
+		out[i] = p.ID
+	}
+	return out
+}
+
+func equalIDs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPayoutStoreConformance(t *testing.T) {
+	for name, open := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			t.Run("CreateAssignsIncreasingIDs", func(t *testing.T) { testCreateAssignsIDs(t, open(t)) })
+			t.Run("GetByIDReturnsNilWhenMissing", func(t *testing.T) { testGetByIDMissing(t, open(t)) })
+			t.Run("GetByIDRoundTrips", func(t *testing.T) { testGetByIDRoundTrips(t, open(t)) })
+			t.Run("ReferencesAreUniquePerMerchant", func(t *testing.T) { testReferences(t, open(t)) })
+			t.Run("UpdateStatusComparesAndSets", func(t *testing.T) { testUpdateStatus(t, open(t)) })
+			t.Run("FailuresAndEvents", func(t *testing.T) { testFailuresAndEvents(t, open(t)) })
+			t.Run("Returns", func(t *testing.T) { testReturns(t, open(t)) })
+			t.Run("ListPayoutsFilters", func(t *testing.T) { testListFilters(t, open(t)) })
+			t.Run("ListPayoutsPagesByKeyset", func(t *testing.T) { testListKeyset(t, open(t)) })
+			t.Run("CountAndStreamPayouts", func(t *testing.T) { testCountAndStream(t, open(t)) })
+			t.Run("ClaimStalePending", func(t *testing.T) { testClaimStalePending(t, open(t)) })
+			t.Run("SearchPayouts", func(t *testing.T) { testSearch(t, open(t)) })
+			t.Run("SummarizePayouts", func(t *testing.T) { testSummarize(t, open(t)) })
+		})
+	}
+}
+
+func testReferences(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant, other := newMerchantID(), newMerchantID()
+	p := mustCreate(t, store, newPayout(merchant, 100, "0123456789"))
+
+	dup := newPayout(merchant, 100, "0123456789")
+	dup.Reference = p.Reference
+	if err := store.Create(ctx, dup); !errors.Is(err, repositories.ErrDuplicateReference) {
+		t.Fatalf("same reference for the same merchant: got %v, want ErrDuplicateReference", err)
+	}
+	elsewhere := newPayout(other, 100, "0123456789")
+	elsewhere.Reference = p.Reference
+	mustCreate(t, store, elsewhere)
+
+	got, err := store.GetByReference(ctx, merchant, p.Reference)
+	if err != nil || got == nil || got.ID != p.ID {
+		t.Fatalf("GetByReference = %+v, %v; want payout %d", got, err, p.ID)
+	}
+	for _, tc := range []struct {
+		merchant  int
+		reference string
+	}{{merchant, "REF-missing"}, {newMerchantID(), p.Reference}} {
+		got, err := store.GetByReference(ctx, tc.merchant, tc.reference)
+		if err != nil || got != nil {
+			t.Fatalf("GetByReference(%d, %q) = %+v, %v; want nil, nil", tc.merchant, tc.reference, got, err)
+		}
+	}
+}
+
+func testCreateAssignsIDs(t *testing.T, store repositories.PayoutStore) {
+	merchant := newMerchantID()
+	a := mustCreate(t, store, newPayout(merchant, 100, "0123456789"))
+	b := mustCreate(t, store, newPayout(merchant, 200, "0123456789"))
+	if a.ID <= 0 || b.ID <= a.ID {
+		t.Fatalf("ids = %d, %d; want positive and increasing", a.ID, b.ID)
+	}
+	if a.CreatedAt.IsZero() || !a.UpdatedAt.Equal(a.CreatedAt) {
+		t.Fatalf("timestamps = %v / %v; want set and equal", a.CreatedAt, a.UpdatedAt)
+	}
+}
+
+func testGetByIDMissing(t *testing.T, store repositories.PayoutStore) {
+	p, err := store.GetByID(context.Background(), 2_000_000_000)
+	if err != nil || p != nil {
+		t.Fatalf("got %v, %v; want nil, nil", p, err)
+	}
+}
+
+func testGetByIDRoundTrips(t *testing.T, store repositories.PayoutStore) {
+	created := mustCreate(t, store, newPayout(newMerchantID(), 15025, "0123456789"))
+	got, err := store.GetByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MerchantID != created.MerchantID || got.Amount != 15025 || got.RecipientAccount != "0123456789" ||
+		got.Status != "pending" || got.CompletedAt != nil || !got.CreatedAt.Equal(created.CreatedAt) {
+		t.Fatalf("got %+v, want %+v", got, created)
+	}
+
+	// Returned payouts are copies; changing one must not change the store.
+	got.Status = "failed"
+	again, _ := store.GetByID(context.Background(), created.ID)
+	if again.Status != "pending" {
+		t.Fatalf("status = %q after mutating a returned payout, want pending", again.Status)
+	}
+}
+
+func testUpdateStatus(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	p := mustCreate(t, store, newPayout(newMerchantID(), 100, "0123456789"))
+
+	if err := store.UpdateStatus(ctx, p.ID, "processing", "completed", repositories.StatusUpdate{}); !errors.Is(err, repositories.ErrStatusConflict) {
+		t.Fatalf("stale from status: got %v, want ErrStatusConflict", err)
+	}
+	if err := store.UpdateStatus(ctx, 2_000_000_000, "pending", "failed", repositories.StatusUpdate{}); !errors.Is(err, repositories.ErrPayoutNotFound) {
+		t.Fatalf("missing payout: got %v, want ErrPayoutNotFound", err)
+	}
+
+	if err := store.UpdateStatus(ctx, p.ID, "pending", "processed", repositories.StatusUpdate{ProviderReference: "PRV-1"}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := store.GetByID(ctx, p.ID)
+	if got.Status != "processed" || got.ProviderReference != "PRV-1" || got.CompletedAt == nil || got.UpdatedAt.Before(p.UpdatedAt) {
+		t.Fatalf("after processed: %+v", got)
+	}
+	completedAt := *got.CompletedAt
+
+	// An empty provider reference keeps the stored one, and completed_at is only set once.
+	if err := store.UpdateStatus(ctx, p.ID, "processed", "completed", repositories.StatusUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = store.GetByID(ctx, p.ID)
+	if got.Status != "completed" || got.ProviderReference != "PRV-1" || !got.CompletedAt.Equal(completedAt) {
+		t.Fatalf("after completed: %+v", got)
+	}
+}
+
+func testFailuresAndEvents(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	p := mustCreate(t, store, newPayout(newMerchantID(), 100, "0123456789"))
+	if p.Attempts != 1 {
+		t.Fatalf("attempts = %d after Create, want 1", p.Attempts)
+	}
+
+	failure := repositories.StatusUpdate{FailureCode: "bank_rejected", FailureMessage: "account closed"}
+	if err := store.UpdateStatus(ctx, p.ID, "pending", "failed", failure); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := store.GetByID(ctx, p.ID)
+	if got.FailureCode != "bank_rejected" || got.FailureMessage != "account closed" {
+		t.Fatalf("after failed: %+v", got)
+	}
+
+	// Leaving failed clears the failure; the event keeps it. A retry starts a new attempt.
+	if err := store.UpdateStatus(ctx, p.ID, "failed", "pending", repositories.StatusUpdate{NewAttempt: true}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = store.GetByID(ctx, p.ID)
+	if got.FailureCode != "" || got.FailureMessage != "" || got.Attempts != 2 {
+		t.Fatalf("after retry: %+v", got)
+	}
+
+	// A conflicting update records nothing.
+	if err := store.UpdateStatus(ctx, p.ID, "failed", "processed", repositories.StatusUpdate{}); !errors.Is(err, repositories.ErrStatusConflict) {
+		t.Fatalf("stale from status: got %v, want ErrStatusConflict", err)
+	}
+
+	events, err := store.ListEvents(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.PayoutEvent{
+		{PayoutID: p.ID, Type: models.PayoutEventCreated, ToStatus: "pending", Attempt: 1},
+		{PayoutID: p.ID, Type: models.PayoutEventStatusChanged, FromStatus: "pending", ToStatus: "failed", FailureCode: "bank_rejected", Message: "account closed", Attempt: 1},
+		{PayoutID: p.ID, Type: models.PayoutEventStatusChanged, FromStatus: "failed", ToStatus: "pending", Attempt: 2},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want %d", events, len(want))
+	}
+	for i, e := range events {
+		if e.ID == 0 || e.CreatedAt.IsZero() || (i > 0 && e.CreatedAt.Before(events[i-1].CreatedAt)) {
+			t.Errorf("event %d = %+v, want an ID and increasing timestamps", i, e)
+		}
+		e.ID, e.CreatedAt = 0, time.Time{}
+		if e != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+	if !events[0].CreatedAt.Equal(p.CreatedAt) {
+		t.Errorf("created event at %v, payout created at %v", events[0].CreatedAt, p.CreatedAt)
+	}
+
+	if events, err := store.ListEvents(ctx, 2_000_000_000); err != nil || len(events) != 0 {
+		t.Fatalf("events of a missing payout = %+v, %v", events, err)
+	}
+}
+
+func testReturns(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant := newMerchantID()
+	providerRef := "PRV-" + strconv.Itoa(merchant)
+	p := mustCreate(t, store, newPayout(merchant, 100, "0123456789"))
+	if err := store.UpdateStatus(ctx, p.ID, "pending", "completed", repositories.StatusUpdate{ProviderReference: providerRef}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetByProviderReference(ctx, providerRef)
+	if err != nil || got == nil || got.ID != p.ID {
+		t.Fatalf("GetByProviderReference = %+v, %v; want payout %d", got, err, p.ID)
+	}
+	if got, err := store.GetByProviderReference(ctx, providerRef+"-missing"); got != nil || err != nil {
+		t.Fatalf("GetByProviderReference(missing) = %+v, %v; want nil, nil", got, err)
+	}
+
+	returned := repositories.StatusUpdate{FailureCode: "account_closed", FailureMessage: "beneficiary account closed"}
+	if err := store.UpdateStatus(ctx, p.ID, "completed", "returned", returned); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = store.GetByID(ctx, p.ID)
+	if got.Status != "returned" || got.FailureCode != "account_closed" || got.FailureMessage != "beneficiary account closed" || got.ProviderReference != providerRef {
+		t.Fatalf("after returned: %+v", got)
+	}
+}
+
+func testListFilters(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant := newMerchantID()
+	small := mustCreate(t, store, newPayout(merchant, 100, "0123456789"))
+	medium := mustCreate(t, store, newPayout(merchant, 500, "9876543210"))
+	large := newPayout(merchant, 900, "0123456789")
+	large.Currency, large.Reference = "USD", "REF-900"
+	mustCreate(t, store, large)
+	mustCreate(t, store, newPayout(newMerchantID(), 100, "0123456789")) // another merchant
+	if err := store.UpdateStatus(ctx, medium.ID, "pending", "failed", repositories.StatusUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+
+	min, max := int64(100), int64(500)
+	ref := "REF-900"
+	later := large.CreatedAt.Add(time.Hour)
+	tests := []struct {
+		name   string
+		filter repositories.PayoutFilter
+		want   []int
+	}{
+		{"merchant", repositories.PayoutFilter{}, []int{small.ID, medium.ID, large.ID}},
+		{"statuses", repositories.PayoutFilter{Statuses: []string{"failed"}}, []int{medium.ID}},
+		{"currency", repositories.PayoutFilter{Currency: "USD"}, []int{large.ID}},
+		{"amount range is inclusive", repositories.PayoutFilter{MinAmount: &min, MaxAmount: &max}, []int{small.ID, medium.ID}},
+		{"created from is inclusive", repositories.PayoutFilter{CreatedFrom: &large.CreatedAt}, []int{large.ID}},
+		{"created to is exclusive", repositories.PayoutFilter{CreatedTo: &medium.CreatedAt}, []int{small.ID}},
+		{"updated to", repositories.PayoutFilter{UpdatedTo: &later}, []int{small.ID, medium.ID, large.ID}},
+		{"reference", repositories.PayoutFilter{Reference: &ref}, []int{large.ID}},
+		{"recipient account", repositories.PayoutFilter{RecipientAccount: "0123-456789"}, []int{small.ID, large.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.MerchantID = merchant
+			list, err := store.ListPayouts(ctx, tt.filter, repositories.PayoutPage{Limit: 10, Sort: repositories.SortCreatedAt})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(list); !equalIDs(got, tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := store.ListPayouts(ctx, repositories.PayoutFilter{MerchantID: merchant}, repositories.PayoutPage{Limit: 10, Sort: "status"}); err == nil {
+		t.Fatal("unsupported sort: expected an error")
+	}
+}
+
+func testCountAndStream(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant := newMerchantID()
+	first := mustCreate(t, store, newPayout(merchant, 100, "0123456789"))
+	second := mustCreate(t, store, newPayout(merchant, 500, "9876543210"))
+	third := mustCreate(t, store, newPayout(merchant, 900, "0123456789"))
+	mustCreate(t, store, newPayout(newMerchantID(), 100, "0123456789")) // another merchant
+
+	tests := []struct {
+		name   string
+		filter repositories.PayoutFilter
+		want   []int
+	}{
+		{"merchant", repositories.PayoutFilter{MerchantID: merchant}, []int{first.ID, second.ID, third.ID}},
+		{"recipient account", repositories.PayoutFilter{MerchantID: merchant, RecipientAccount: "0123456789"}, []int{first.ID, third.ID}},
+		// Persisted export jobs filter by the blind index instead of the account number.
+		{"account index", repositories.PayoutFilter{MerchantID: merchant, RecipientAccountHash: store.AccountIndex("0123456789")}, []int{first.ID, third.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := store.CountPayouts(ctx, tt.filter)
+			if err != nil || n != len(tt.want) {
+				t.Fatalf("CountPayouts = %d, %v; want %d", n, err, len(tt.want))
+			}
+			var streamed []*models.Payout
+			if err := store.StreamPayouts(ctx, tt.filter, func(p *models.Payout) error {
+				streamed = append(streamed, p)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(streamed); !equalIDs(got, tt.want) {
+				t.Fatalf("streamed ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err := store.StreamPayouts(ctx, repositories.PayoutFilter{MerchantID: merchant}, func(*models.Payout) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("got %v after %d calls, want the callback's error after the first", err, calls)
+	}
+}
+
+func testListKeyset(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant := newMerchantID()
+	// Equal amounts check that ties are broken by ID.
+	var created []*models.Payout
+	for _, amount := range []int64{300, 100, 300, 200} {
+		created = append(created, mustCreate(t, store, newPayout(merchant, amount, "0123456789")))
+	}
+	f := repositories.PayoutFilter{MerchantID: merchant}
+
+	tests := []struct {
+		name string
+		sort string
+		desc bool
+		want []int
+	}{
+		{"created asc", repositories.SortCreatedAt, false, []int{created[0].ID, created[1].ID, created[2].ID, created[3].ID}},
+		{"created desc", repositories.SortCreatedAt, true, []int{created[3].ID, created[2].ID, created[1].ID, created[0].ID}},
+		{"amount asc", repositories.SortAmount, false, []int{created[1].ID, created[3].ID, created[0].ID, created[2].ID}},
+		{"amount desc", repositories.SortAmount, true, []int{created[2].ID, created[0].ID, created[3].ID, created[1].ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := repositories.PayoutPage{Limit: 3, Sort: tt.sort, Desc: tt.desc}
+			var got []int
+			for {
+				list, err := store.ListPayouts(ctx, f, page)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, ids(list)...)
+				if len(list) < page.Limit {
+					break
+				}
+				last := list[len(list)-1]
+				page.AfterID = last.ID
+				if tt.sort == repositories.SortAmount {
+					page.AfterValue = last.Amount
+				} else {
+					page.AfterValue = last.CreatedAt
+				}
+			}
+			if !equalIDs(got, tt.want) {
+				t.Fatalf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func testClaimStalePending(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant := newMerchantID()
+	pending := mustCreate(t, store, newPayout(merchant, 100, "0123456789"))
+	failed := mustCreate(t, store, newPayout(merchant, 200, "0123456789"))
+	if err := store.UpdateStatus(ctx, failed.ID, "pending", "failed", repositories.StatusUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// Nothing has been pending for an hour.
+	claimed, err := store.ClaimStalePending(ctx, time.Hour, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contains(claimed, pending.ID) {
+		t.Fatalf("claimed %v, want the fresh payout %d left alone", claimed, pending.ID)
+	}
+
+	claimed, err = store.ClaimStalePending(ctx, 5*time.Millisecond, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !contains(claimed, pending.ID) || contains(claimed, failed.ID) {
+		t.Fatalf("claimed %v, want pending %d and not failed %d", claimed, pending.ID, failed.ID)
+	}
+
+	// Claiming touches updated_at, so the payout isn't claimed again right away.
+	claimed, err = store.ClaimStalePending(ctx, 5*time.Millisecond, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contains(claimed, pending.ID) {
+		t.Fatalf("claimed %v again, want %d skipped", claimed, pending.ID)
+	}
+}
+
+func contains(list []int, id int) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func testSearch(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant := newMerchantID()
+	ada := newPayout(merchant, 4711, "0123456789")
+	ada.Reference = "REF-4711"
+	ada = mustCreate(t, store, ada)
+	other := newPayout(merchant, 100, "5555554321")
+	other.RecipientName, other.Narration = "Bola Ade", "rent"
+	other = mustCreate(t, store, other)
+	f := repositories.PayoutFilter{MerchantID: merchant}
+
+	tests := []struct {
+		name      string
+		q         string
+		want      int
+		highlight string
+	}{
+		{"word", "salary", ada.ID, "narration"},
+		{"name", "bola", other.ID, "recipient_name"},
+		{"reference", "REF-4711", ada.ID, "reference"},
+		{"account suffix", "4321", other.ID, "recipient_account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := store.SearchPayouts(ctx, f, tt.q, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hits) == 0 || hits[0].Payout.ID != tt.want {
+				t.Fatalf("hits = %+v, want %d first", hits, tt.want)
+			}
+			if _, ok := hits[0].Highlights[tt.highlight]; !ok {
+				t.Fatalf("highlights = %v, want %s", hits[0].Highlights, tt.highlight)
+			}
+		})
+	}
+
+	hits, err := store.SearchPayouts(ctx, f, "zzzz", 10)
+	if err != nil || len(hits) != 0 {
+		t.Fatalf("got %v, %v; want no hits", hits, err)
+	}
+
+	// Stored text and the query are HTML-escaped; only the <mark> tags are markup.
+	markup := newPayout(newMerchantID(), 100, "0123456789")
+	markup.Reference, markup.Narration = "<i>REF</i>", "<img src=x onerror=alert(1)> bonus"
+	markup = mustCreate(t, store, markup)
+	f = repositories.PayoutFilter{MerchantID: markup.MerchantID}
+	for q, want := range map[string][2]string{
+		"bonus":      {"narration", "&lt;img src=x onerror=alert(1)&gt; <mark>bonus</mark>"},
+		"<i>REF</i>": {"reference", "<mark>&lt;i&gt;REF&lt;/i&gt;</mark>"},
+	} {
+		hits, err := store.SearchPayouts(ctx, f, q, 10)
+		if err != nil || len(hits) != 1 {
+			t.Fatalf("search %q: got %v, %v; want one hit", q, hits, err)
+		}
+		if got := hits[0].Highlights[want[0]]; got != want[1] {
+			t.Errorf("search %q: %s highlight = %q, want %q", q, want[0], got, want[1])
+		}
+	}
+}
+
+func testSummarize(t *testing.T, store repositories.PayoutStore) {
+	ctx := context.Background()
+	merchant := newMerchantID()
+	a := mustCreate(t, store, newPayout(merchant, 100, "0123456789"))
+	b := mustCreate(t, store, newPayout(merchant, 300, "0123456789"))
+	usd := newPayout(merchant, 50, "0123456789")
+	usd.Currency = "USD"
+	mustCreate(t, store, usd)
+	if err := store.UpdateStatus(ctx, a.ID, "pending", "processed", repositories.StatusUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateStatus(ctx, b.ID, "pending", "failed", repositories.StatusUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := store.SummarizePayouts(ctx, repositories.PayoutFilter{MerchantID: merchant}, "day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Per currency: one period row, one row per status, one bank row and the total.
+	var dims []string
+	for _, row := range rows {
+		if row.Currency == "NGN" {
+			dims = append(dims, row.Dimension+":"+row.Status)
+		}
+	}
+	want := []string{"period:", "status:completed", "status:failed", "bank:", "total:"}
+	if len(dims) != len(want) {
+		t.Fatalf("NGN rows = %v, want %v", dims, want)
+	}
+	for i := range want {
+		if dims[i] != want[i] {
+			t.Fatalf("NGN rows = %v, want %v", dims, want)
+		}
+	}
+	if rows[0].Currency != "NGN" || rows[len(rows)-1].Currency != "USD" {
+		t.Fatalf("rows are not ordered by currency: %+v", rows)
+	}
+
+	total := rows[4]
+	if total.Count != 2 || total.Volume != 400 || total.CompletedCount != 1 || total.FailedCount != 1 || total.AvgCompletionSeconds == nil {
+		t.Fatalf("NGN total = %+v", total)
+	}
+}
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// SummarizePayouts aggregates the payouts matching the filter in a single pass using
+// grouping sets: per currency, and per currency by status, bank and period (truncated to
+// interval: day, week or month). "processed" is folded into "completed".
+func (r *PayoutRepository) SummarizePayouts(ctx context.Context, f PayoutFilter, interval string) (_ []models.PayoutSummaryRow, err error) {
+	ctx, span := startQuery(ctx, "SummarizePayouts", tracing.MerchantIDKey.Int(f.MerchantID))
+	defer func() { tracing.End(span, err) }()
+
+	where, args := r.where(f)
+	args = append(args, interval)
+
+	query := fmt.Sprintf(`
+		SELECT s.currency, s.status, s.bank, s.period,
+			GROUPING(s.status, s.bank, s.period),
+			COUNT(*),
+			COALESCE(SUM(s.amount), 0),
+			COUNT(*) FILTER (WHERE s.status = 'completed'),
+			COUNT(*) FILTER (WHERE s.status = 'failed'),
+			AVG(EXTRACT(EPOCH FROM s.completed_at - s.created_at)) FILTER (WHERE s.completed_at IS NOT NULL)
+		FROM (
+			SELECT currency,
+				CASE WHEN status = 'processed' THEN 'completed' ELSE status END AS status,
+				recipient_bank AS bank,
+				date_trunc($%d, created_at) AS period,
+				amount, created_at, completed_at
+			FROM payouts
+			WHERE %s
+		) s
+		GROUP BY GROUPING SETS ((s.currency), (s.currency, s.status), (s.currency, s.bank), (s.currency, s.period))
+		ORDER BY s.currency, s.period, s.status, s.bank
+	`, len(args), where)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.PayoutSummaryRow
+	for rows.Next() {
+		var row models.PayoutSummaryRow
+		var status, bank sql.NullString
+		var period sql.NullTime
+		var grouping int
+		var avg sql.NullFloat64
+		if err := rows.Scan(
+			&row.Currency, &status, &bank, &period, &grouping,
+			&row.Count, &row.Volume, &row.CompletedCount, &row.FailedCount, &avg,
+		); err != nil {
+			return nil, err
+		}
+
+		// GROUPING() sets a bit for every argument that is aggregated away, so the single
+		// cleared bit identifies the dimension of this row.
+		switch grouping {
+		case 0b011:
+			row.Dimension, row.Status = models.SummaryStatus, status.String
+		case 0b101:
+			row.Dimension, row.Bank = models.SummaryBank, bank.String
+		case 0b110:
+			row.Dimension, row.Period = models.SummaryPeriod, period.Time
+		default:
+			row.Dimension = models.SummaryTotal
+		}
+		if avg.Valid {
+			v := avg.Float64
+			row.AvgCompletionSeconds = &v
+		}
+		list = append(list, row)
+	}
+	return list, rows.Err()
+}
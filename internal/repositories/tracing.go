@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// startQuery starts a client span for a PayoutRepository query. End it with tracing.End.
+func startQuery(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemPostgreSQL, semconv.DBOperationName(operation))
+	return tracing.StartKind(ctx, trace.SpanKindClient, "PayoutRepository."+operation, attrs...)
+}
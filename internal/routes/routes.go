@@ -1,26 +1,216 @@
 package routes
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/kodra-pay/payout-service/internal/auth"
+	"github.com/kodra-pay/payout-service/internal/background"
+	"github.com/kodra-pay/payout-service/internal/clients"
 	"github.com/kodra-pay/payout-service/internal/config"
+	"github.com/kodra-pay/payout-service/internal/correlation"
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
 	"github.com/kodra-pay/payout-service/internal/handlers"
+	"github.com/kodra-pay/payout-service/internal/health"
+	"github.com/kodra-pay/payout-service/internal/httpclient"
+	"github.com/kodra-pay/payout-service/internal/metrics"
+	"github.com/kodra-pay/payout-service/internal/middleware"
+	"github.com/kodra-pay/payout-service/internal/migrations"
+	"github.com/kodra-pay/payout-service/internal/openapi"
+	"github.com/kodra-pay/payout-service/internal/ratelimit"
 	"github.com/kodra-pay/payout-service/internal/repositories"
 	"github.com/kodra-pay/payout-service/internal/services"
 )
 
-func Register(app *fiber.App, cfg config.Config) {
-	health := handlers.NewHealthHandler(cfg.ServiceName)
+// Register mounts the merchant-facing API on app and the service-to-service/ops API on
+// internal, which is served on a separate listener. Background work is started on workers.
+// It returns the database pool so the caller can close it on shutdown.
+func Register(app, internal *fiber.App, cfg config.Config, workers *background.Group) *sql.DB {
+	db, err := repositories.Open(cfg.PostgresDSN)
+	if err != nil {
+		panic(err)
+	}
+	metrics.RegisterDB(db, "payouts")
+
+	client := httpclient.New(httpclient.Options{
+		Timeout:         time.Duration(cfg.HTTPTimeoutMS) * time.Millisecond,
+		ConnectTimeout:  time.Duration(cfg.HTTPConnectTimeoutMS) * time.Millisecond,
+		MaxRetries:      cfg.HTTPMaxRetries,
+		BreakerFailures: cfg.BreakerFailures,
+		BreakerCooldown: time.Duration(cfg.BreakerCooldownSeconds) * time.Second,
+	})
+	health := handlers.NewHealthHandler(cfg.ServiceName, newReadinessChecker(cfg, db, client))
 	health.Register(app)
 
-	repo, err := repositories.NewPayoutRepository(cfg.PostgresDSN)
+	rateLimiter := newRateLimiter(cfg, db)
+	app.Use(rateLimiter.PerIP())
+
+	// Merchants get the document without the internal routes; the internal listener serves
+	// all of it.
+	spec := openapi.Embedded()
+	handlers.NewDocsHandler(spec.PublicJSON()).Register(app)
+	validate := middleware.ValidateRequest(spec)
+
+	keys, err := fieldcrypt.NewKeyring(cfg.FieldEncryptionKeys, cfg.FieldEncryptionKeysFile, cfg.FieldEncryptionActiveVersion, cfg.BlindIndexKey)
+	if err != nil {
+		panic(err)
+	}
+	fees, err := services.NewFeeSchedule(cfg.PayoutFeeFlat, cfg.PayoutFeeBasisPoints)
+	if err != nil {
+		panic(err)
+	}
+	returnFees, err := services.ParseReturnFeePolicy(cfg.ReturnFeePolicy)
 	if err != nil {
 		panic(err)
 	}
-	svc := services.NewPayoutService(repo, cfg.MerchantServiceURL, cfg.TransactionServiceURL)
+	repo := repositories.NewPayoutRepository(db, keys)
+	svc := services.NewPayoutService(repo,
+		clients.NewHTTPMerchantClient(client, cfg.MerchantServiceURL),
+		clients.NewHTTPTransactionClient(client, cfg.TransactionServiceURL),
+		clients.NewHTTPNotificationClient(client, cfg.NotificationServiceURL),
+		workers, cfg.PageSizeDefault, cfg.PageSizeMax, cfg.PayoutMaxAttempts, fees, returnFees)
 	handler := handlers.NewPayoutHandler(svc)
 
-	app.Get("/payouts", handler.List)
-	app.Post("/payouts", handler.Create)
-	app.Get("/payouts/:id", handler.Get)
-	app.Put("/payouts/:id/status", handler.UpdateStatus)
+	// Postgres may come up after the service; until it does, readiness reports it as down.
+	// Once it is reachable, apply pending migrations if enabled, then keep picking up payouts
+	// a previous or stopped instance left pending.
+	go func() {
+		ctx := correlation.Start(context.Background())
+		if err := repositories.WaitForDB(ctx, db, 30*time.Second); err != nil {
+			return
+		}
+		if cfg.AutoMigrate {
+			if _, err := migrations.New(db).Up(ctx); err != nil {
+				// Readiness keeps failing on the schema check until this is fixed.
+				slog.ErrorContext(ctx, "failed to apply migrations", slog.Any("error", err))
+				return
+			}
+		}
+		svc.WatchPending(ctx)
+	}()
+
+	exportSvc := services.NewExportService(repositories.NewExportRepository(db), repo, workers, cfg.ExportDir, cfg.ExportAsyncThreshold)
+	export := handlers.NewExportHandler(exportSvc)
+
+	jwt, err := auth.NewJWTVerifier(cfg.JWTHMACSecrets, cfg.JWTPublicKeyFiles, cfg.JWTIssuer, cfg.JWTAudience)
+	if err != nil {
+		panic(err)
+	}
+	authenticator := auth.NewAuthenticator(repositories.NewAPIKeyRepository(db), jwt)
+
+	// Every payout route acts on behalf of the authenticated merchant.
+	payouts := app.Group("/payouts", middleware.Authenticate(authenticator), rateLimiter.PerMerchant(), validate)
+
+	// Search, summary and export routes are registered before /payouts/:id so they aren't parsed as an ID.
+	payouts.Get("/search", handler.Search)
+	payouts.Get("/summary", handler.Summary)
+	payouts.Get("/export", export.Export)
+	payouts.Get("/exports/:id", export.GetJob)
+	payouts.Get("/exports/:id/download", export.Download)
+	payouts.Get("/by-reference/:reference", handler.GetByReference)
+
+	payouts.Get("", handler.List)
+	payouts.Post("", handler.Create)
+	payouts.Get("/:id", handler.Get)
+	payouts.Post("/:id/retry", handler.Retry)
+	payouts.Post("/:id/cancel", handler.Cancel)
+
+	var nonces auth.NonceStore = auth.NewMemoryNonceStore()
+	if cfg.InternalNonceStore == "postgres" {
+		nonces = repositories.NewNonceRepository(db)
+	}
+	serviceAuth, err := auth.NewServiceAuthenticator(cfg.InternalHMACClients, cfg.InternalCertClients, nonces)
+	if err != nil {
+		panic(err)
+	}
+	health.Register(internal)
+	handlers.NewDocsHandler(spec.JSON()).Register(internal)
+	// Prometheus scrapes the internal listener; metrics aren't exposed to merchants.
+	internal.Get("/metrics", adaptor.HTTPHandler(metrics.Handler()))
+	ops := internal.Group("/internal", middleware.InternalAuth(serviceAuth), validate)
+
+	internalPayouts := handlers.NewInternalPayoutHandler(svc)
+	ops.Put("/payouts/:id/status", middleware.RequirePermission(auth.PermUpdateStatus), internalPayouts.UpdateStatus)
+	ops.Post("/payouts/:id/force-fail", middleware.RequirePermission(auth.PermForceFail), internalPayouts.ForceFail)
+	ops.Post("/payouts/:id/retry", middleware.RequirePermission(auth.PermRetry), internalPayouts.Retry)
+	ops.Post("/payouts/:id/reverse", middleware.RequirePermission(auth.PermReverse), internalPayouts.Reverse)
+	// The provider reports bank returns the way it reports statuses.
+	ops.Post("/webhooks/payout-returns", middleware.RequirePermission(auth.PermUpdateStatus), internalPayouts.ReportReturn)
+
+	reconRepo := repositories.NewReconciliationRepository(db)
+	reconSvc := services.NewReconciliationService(reconRepo, repo, cfg.ReconDateToleranceDays)
+	recon := handlers.NewReconciliationHandler(reconSvc)
+
+	reconciliation := ops.Group("/reconciliation", middleware.RequirePermission(auth.PermReconcile))
+	reconciliation.Post("/imports", recon.Import)
+	reconciliation.Get("/runs/:id", recon.GetRun)
+	reconciliation.Get("/exceptions", recon.ListExceptions)
+	reconciliation.Put("/exceptions/:id/resolve", recon.ResolveException)
+
+	return db
+}
+
+// newReadinessChecker checks Postgres, the schema version and the downstream services a
+// payout needs.
+func newReadinessChecker(cfg config.Config, db *sql.DB, client *http.Client) *health.Checker {
+	checker := health.NewChecker(2 * time.Second)
+	checker.Add("postgres", func(ctx context.Context) (string, error) {
+		return "", db.PingContext(ctx)
+	})
+	required := int64(cfg.SchemaVersionRequired)
+	if required < 0 {
+		required = migrations.Latest()
+	}
+	checker.Add("schema", func(ctx context.Context) (string, error) {
+		version, dirty, ok, err := repositories.SchemaVersion(ctx, db)
+		switch {
+		case err != nil:
+			return "", err
+		case !ok && required > 0:
+			return "", fmt.Errorf("no migrations applied, need version %d", required)
+		case !ok:
+			return "unknown", nil
+		case dirty:
+			return "", fmt.Errorf("migration %d is dirty", version)
+		case version < required:
+			return "", fmt.Errorf("schema version %d is older than required %d", version, required)
+		}
+		return fmt.Sprintf("version %d", version), nil
+	})
+	checker.Add(metrics.DependencyMerchantService, health.HTTPCheck(client, metrics.DependencyMerchantService, cfg.MerchantServiceURL))
+	checker.Add(metrics.DependencyTransactionService, health.HTTPCheck(client, metrics.DependencyTransactionService, cfg.TransactionServiceURL))
+	return checker
+}
+
+func newRateLimiter(cfg config.Config, db *sql.DB) *middleware.RateLimiter {
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.RateLimitStore == "postgres" {
+		store = repositories.NewRateLimitRepository(db)
+	}
+
+	opts := middleware.RateLimitOptions{DefaultPlan: cfg.RateLimitDefaultPlan}
+	if cfg.RateLimitPerIP != "" {
+		rule, err := ratelimit.ParseRule(cfg.RateLimitPerIP)
+		if err != nil {
+			panic(err)
+		}
+		opts.PerIP = &rule
+	}
+	var err error
+	if opts.Routes, err = ratelimit.ParseRouteRules(cfg.RateLimitRoutes); err != nil {
+		panic(err)
+	}
+	if opts.Plans, err = ratelimit.ParseRuleMap(cfg.RateLimitPlans); err != nil {
+		panic(err)
+	}
+	if opts.Exempt, err = ratelimit.ParseNetworks(cfg.RateLimitExempt); err != nil {
+		panic(err)
+	}
+	return middleware.NewRateLimiter(ratelimit.NewLimiter(store), opts)
 }
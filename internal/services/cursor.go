@@ -0,0 +1,63 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// pageCursor is the opaque position handed to clients as next_cursor. It records the sort it
+// was produced under so a cursor can't be replayed against a different ordering.
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(sort, order string, p *models.Payout) string {
+	c := pageCursor{Sort: sort, Order: order, ID: p.ID}
+	switch sort {
+	case repositories.SortAmount:
+		c.Value = strconv.FormatInt(p.Amount, 10)
+	case repositories.SortUpdatedAt:
+		c.Value = p.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	default:
+		c.Value = p.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor validates a cursor against the requested ordering and returns the seek value.
+func decodeCursor(raw, sort, order string) (any, int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid cursor")
+	}
+	var c pageCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return nil, 0, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, 0, fmt.Errorf("cursor does not match the requested sort order")
+	}
+
+	if sort == repositories.SortAmount {
+		v, err := strconv.ParseInt(c.Value, 10, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid cursor")
+		}
+		return v, c.ID, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid cursor")
+	}
+	return t, c.ID, nil
+}
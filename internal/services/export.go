@@ -0,0 +1,332 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/background"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/logging"
+	"github.com/kodra-pay/payout-service/internal/metrics"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+	"github.com/kodra-pay/payout-service/internal/tracing"
+)
+
+// Supported export formats.
+const (
+	ExportFormatCSV   = "csv"
+	ExportFormatJSONL = "jsonl"
+)
+
+var exportCSVHeader = []string{
+	"id", "merchant_id", "reference", "amount", "fee", "currency",
+	"recipient_name", "recipient_account", "recipient_bank", "status",
+	"failure_code", "failure_message", "attempts", "narration", "provider_reference", "created_at", "updated_at", "completed_at",
+}
+
+type ExportService struct {
+	repo           repositories.ExportJobStore
+	payouts        repositories.PayoutStore
+	workers        *background.Group
+	dir            string
+	asyncThreshold int
+}
+
+func NewExportService(repo repositories.ExportJobStore, payouts repositories.PayoutStore, workers *background.Group, dir string, asyncThreshold int) *ExportService {
+	return &ExportService{
+		repo:           repo,
+		payouts:        payouts,
+		workers:        workers,
+		dir:            dir,
+		asyncThreshold: asyncThreshold,
+	}
+}
+
+// ExportContentType returns the MIME type and file extension for an export format.
+func ExportContentType(format string) (string, string, error) {
+	switch format {
+	case ExportFormatCSV:
+		return "text/csv; charset=utf-8", "csv", nil
+	case ExportFormatJSONL:
+		return "application/x-ndjson", "jsonl", nil
+	default:
+		return "", "", apperr.Validation("unsupported export format", apperr.Field("format", "must be csv or jsonl"))
+	}
+}
+
+// NeedsBackgroundJob reports whether the filter matches more rows than should be streamed inline.
+func (s *ExportService) NeedsBackgroundJob(ctx context.Context, f repositories.PayoutFilter) (bool, error) {
+	n, err := s.payouts.CountPayouts(ctx, f)
+	if err != nil {
+		return false, apperr.Internal("failed to count payouts", err)
+	}
+	return n > s.asyncThreshold, nil
+}
+
+// Write streams every payout matching the filter to w and returns the number of rows written.
+func (s *ExportService) Write(ctx context.Context, w io.Writer, format string, f repositories.PayoutFilter) (int, error) {
+	rows := 0
+	switch format {
+	case ExportFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportCSVHeader); err != nil {
+			return 0, err
+		}
+		err := s.payouts.StreamPayouts(ctx, f, func(p *models.Payout) error {
+			rows++
+			return cw.Write(exportCSVRow(p))
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+		return rows, err
+	case ExportFormatJSONL:
+		enc := json.NewEncoder(w)
+		err := s.payouts.StreamPayouts(ctx, f, func(p *models.Payout) error {
+			rows++
+			return enc.Encode(toExportRecord(p))
+		})
+		return rows, err
+	default:
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// StartJob queues a background export and returns immediately.
+func (s *ExportService) StartJob(ctx context.Context, format string, f repositories.PayoutFilter) (dto.ExportJobResponse, error) {
+	if _, _, err := ExportContentType(format); err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+	select {
+	case <-s.workers.Stopping():
+		return dto.ExportJobResponse{}, apperr.Unavailable("service is shutting down, try again shortly", nil)
+	default:
+	}
+	// The job's filter is persisted, so swap the account number for its blind index.
+	if f.RecipientAccount != "" {
+		f.RecipientAccountHash = s.payouts.AccountIndex(f.RecipientAccount)
+		f.RecipientAccount = ""
+	}
+	filter, err := json.Marshal(f)
+	if err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+
+	job := &models.ExportJob{
+		ID:         uuid.NewString(),
+		MerchantID: f.MerchantID,
+		Format:     format,
+		Filter:     filter,
+		Status:     models.ExportPending,
+	}
+	if err := s.repo.Create(ctx, job); err != nil {
+		return dto.ExportJobResponse{}, apperr.Internal("failed to create export job", err)
+	}
+
+	// The job outlives the request; it keeps the request's log attributes but not its
+	// cancellation.
+	jobCtx := logging.With(ctx, slog.String("export_job_id", job.ID))
+	queued := metrics.WorkerQueueDepth.WithLabelValues(metrics.WorkerExport)
+	queued.Inc()
+	started := s.workers.Go(jobCtx, "export job "+job.ID, func(ctx context.Context) {
+		defer queued.Dec()
+		s.runJob(ctx, job.ID, format, f)
+	})
+	if !started {
+		queued.Dec()
+		_ = s.repo.Finish(ctx, job.ID, 0, "", "service shut down before the export started")
+		job.Status, job.Error = models.ExportFailed, "service shut down before the export started"
+	}
+
+	return toExportJobResponse(job), nil
+}
+
+func (s *ExportService) runJob(ctx context.Context, id, format string, f repositories.PayoutFilter) {
+	ctx, span := tracing.Start(ctx, "ExportService.runJob", attribute.String("export.job_id", id), tracing.MerchantIDKey.Int(f.MerchantID))
+	defer span.End()
+	slog.InfoContext(ctx, "export job started", slog.String("format", format))
+	if err := s.repo.SetStatus(ctx, id, models.ExportRunning); err != nil {
+		slog.ErrorContext(ctx, "failed to mark export job running", slog.Any("error", err))
+	}
+
+	path, rows, err := s.writeJobFile(ctx, id, format, f)
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		slog.ErrorContext(ctx, "export job failed", slog.Int("rows", rows), slog.Any("error", err))
+		_ = os.Remove(path)
+		path = ""
+	}
+	// Record the result even if the shutdown deadline cancelled the export itself.
+	if err := s.repo.Finish(context.WithoutCancel(ctx), id, rows, path, errMsg); err != nil {
+		slog.ErrorContext(ctx, "failed to record export job result", slog.Any("error", err))
+		return
+	}
+	if errMsg == "" {
+		slog.InfoContext(ctx, "export job finished", slog.Int("rows", rows))
+	}
+}
+
+func (s *ExportService) writeJobFile(ctx context.Context, id, format string, f repositories.PayoutFilter) (string, int, error) {
+	_, ext, err := ExportContentType(format)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return "", 0, fmt.Errorf("create export dir: %w", err)
+	}
+
+	path := filepath.Join(s.dir, id+"."+ext)
+	file, err := os.Create(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("create export file: %w", err)
+	}
+	defer file.Close()
+
+	bw := bufio.NewWriter(file)
+	rows, err := s.Write(ctx, bw, format, f)
+	if err != nil {
+		return path, rows, err
+	}
+	if err := bw.Flush(); err != nil {
+		return path, rows, err
+	}
+	return path, rows, file.Sync()
+}
+
+// GetJob returns a job owned by the given merchant.
+func (s *ExportService) GetJob(ctx context.Context, id string, merchantID int) (dto.ExportJobResponse, error) {
+	job, err := s.getJob(ctx, id, merchantID)
+	if err != nil {
+		return dto.ExportJobResponse{}, err
+	}
+	return toExportJobResponse(job), nil
+}
+
+// JobFile returns the path and download file name of a completed export.
+func (s *ExportService) JobFile(ctx context.Context, id string, merchantID int) (string, string, error) {
+	job, err := s.getJob(ctx, id, merchantID)
+	if err != nil {
+		return "", "", err
+	}
+	if job.Status != models.ExportCompleted || job.FilePath == "" {
+		return "", "", apperr.Conflict("export_not_ready", fmt.Sprintf("export job is %s", job.Status))
+	}
+	_, ext, err := ExportContentType(job.Format)
+	if err != nil {
+		return "", "", err
+	}
+	return job.FilePath, fmt.Sprintf("payouts-%s.%s", job.CreatedAt.Format("20060102-150405"), ext), nil
+}
+
+func (s *ExportService) getJob(ctx context.Context, id string, merchantID int) (*models.ExportJob, error) {
+	notFound := apperr.NotFound("export_not_found", "export job not found")
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, notFound
+	}
+	job, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, apperr.Internal("failed to load export job", err)
+	}
+	if job == nil || job.MerchantID != merchantID {
+		return nil, notFound
+	}
+	return job, nil
+}
+
+func toExportJobResponse(job *models.ExportJob) dto.ExportJobResponse {
+	resp := dto.ExportJobResponse{
+		ID:          job.ID,
+		Status:      job.Status,
+		Format:      job.Format,
+		RowCount:    job.RowCount,
+		Error:       job.Error,
+		CreatedAt:   job.CreatedAt,
+		CompletedAt: job.CompletedAt,
+	}
+	if job.Status == models.ExportCompleted {
+		resp.DownloadURL = fmt.Sprintf("/payouts/exports/%s/download", job.ID)
+	}
+	return resp
+}
+
+func toExportRecord(p *models.Payout) dto.PayoutExportRecord {
+	return dto.PayoutExportRecord{
+		ID:                p.ID,
+		MerchantID:        p.MerchantID,
+		Reference:         p.Reference,
+		Amount:            float64(p.Amount) / 100,
+		Fee:               float64(p.Fee) / 100,
+		Currency:          p.Currency,
+		RecipientName:     p.RecipientName,
+		RecipientAccount:  fieldcrypt.Mask(p.RecipientAccount),
+		RecipientBank:     p.RecipientBank,
+		Status:            p.Status,
+		FailureCode:       p.FailureCode,
+		FailureMessage:    p.FailureMessage,
+		Attempts:          p.Attempts,
+		Narration:         p.Narration,
+		ProviderReference: p.ProviderReference,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+		CompletedAt:       p.CompletedAt,
+	}
+}
+
+func exportCSVRow(p *models.Payout) []string {
+	return []string{
+		strconv.Itoa(p.ID),
+		strconv.Itoa(p.MerchantID),
+		p.Reference,
+		formatMinor(p.Amount),
+		formatMinor(p.Fee),
+		p.Currency,
+		p.RecipientName,
+		fieldcrypt.Mask(p.RecipientAccount),
+		p.RecipientBank,
+		p.Status,
+		p.FailureCode,
+		p.FailureMessage,
+		strconv.Itoa(p.Attempts),
+		p.Narration,
+		p.ProviderReference,
+		p.CreatedAt.UTC().Format(time.RFC3339),
+		p.UpdatedAt.UTC().Format(time.RFC3339),
+		formatOptionalTime(p.CompletedAt),
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatMinor renders a minor-unit amount as a decimal string without float rounding.
+func formatMinor(v int64) string {
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
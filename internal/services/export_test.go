@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/background"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+type exportFixture struct {
+	svc     *ExportService
+	payouts *repositories.MemoryPayoutStore
+	jobs    *repositories.MemoryExportJobStore
+	workers *background.Group
+}
+
+// newExportFixture builds an export service on in-memory stores that runs jobs in the
+// background above asyncThreshold rows.
+func newExportFixture(t *testing.T, asyncThreshold int) *exportFixture {
+	t.Helper()
+	f := &exportFixture{
+		payouts: repositories.NewMemoryPayoutStore(testKeyring(t)),
+		jobs:    repositories.NewMemoryExportJobStore(),
+		workers: background.NewGroup(),
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		f.workers.Shutdown(ctx)
+	})
+	f.svc = NewExportService(f.jobs, f.payouts, f.workers, t.TempDir(), asyncThreshold)
+	return f
+}
+
+// payout stores a pending payout in NGN.
+func (f *exportFixture) payout(t *testing.T, merchantID int, reference string, amount int64, account string) *models.Payout {
+	t.Helper()
+	p := &models.Payout{
+		MerchantID:       merchantID,
+		Reference:        reference,
+		Amount:           amount,
+		Currency:         "NGN",
+		RecipientName:    "Ada Obi",
+		RecipientAccount: account,
+		RecipientBank:    "058",
+		Status:           "pending",
+		Narration:        "salary june",
+	}
+	if err := f.payouts.Create(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+// twoPayouts stores a completed payout and one that failed twice for merchant 7, and one
+// for merchant 8.
+func (f *exportFixture) twoPayouts(t *testing.T) (completed, failed *models.Payout) {
+	t.Helper()
+	completed = f.payout(t, 7, "INV-1", 15025, "0123456789")
+	if err := f.payouts.UpdateStatus(context.Background(), completed.ID, "pending", "completed", repositories.StatusUpdate{ProviderReference: "PRV-1"}); err != nil {
+		t.Fatal(err)
+	}
+	failed = f.payout(t, 7, "INV-2", 5000, "9876543210")
+	update := repositories.StatusUpdate{FailureCode: FailureProviderError, FailureMessage: "bank timed out"}
+	if err := f.payouts.UpdateStatus(context.Background(), failed.ID, "pending", "failed", update); err != nil {
+		t.Fatal(err)
+	}
+	// A retry that failed again.
+	if err := f.payouts.UpdateStatus(context.Background(), failed.ID, "failed", "pending", repositories.StatusUpdate{NewAttempt: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.payouts.UpdateStatus(context.Background(), failed.ID, "pending", "failed", update); err != nil {
+		t.Fatal(err)
+	}
+	f.payout(t, 8, "INV-1", 100, "0123456789")
+	return completed, failed
+}
+
+func TestExportWritesCSV(t *testing.T) {
+	f := newExportFixture(t, 100)
+	completed, failed := f.twoPayouts(t)
+
+	var buf bytes.Buffer
+	n, err := f.svc.Write(context.Background(), &buf, ExportFormatCSV, repositories.PayoutFilter{MerchantID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(records) != 3 {
+		t.Fatalf("wrote %d rows, %d records; want 2 rows after the header", n, len(records))
+	}
+	if !slices.Equal(records[0], exportCSVHeader) {
+		t.Fatalf("header = %v", records[0])
+	}
+	col := func(row []string, name string) string { return row[slices.Index(exportCSVHeader, name)] }
+
+	first, second := records[1], records[2]
+	if col(first, "reference") != completed.Reference || col(second, "reference") != failed.Reference {
+		t.Fatalf("rows = %v, want oldest first", records[1:])
+	}
+	for name, want := range map[string]string{
+		"amount":             "150.25",
+		"fee":                "0.00",
+		"recipient_account":  fieldcrypt.Mask("0123456789"),
+		"status":             "completed",
+		"provider_reference": "PRV-1",
+	} {
+		if got := col(first, name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if col(first, "failure_code") != "" || col(second, "failure_code") != FailureProviderError || col(second, "failure_message") != "bank timed out" {
+		t.Errorf("failure columns = %v, want only the failed payout's", records[1:])
+	}
+	if col(first, "attempts") != "1" || col(second, "attempts") != "2" {
+		t.Errorf("attempts = %q and %q, want 1 and 2", col(first, "attempts"), col(second, "attempts"))
+	}
+	if col(first, "completed_at") == "" || col(second, "completed_at") != "" {
+		t.Errorf("completed_at = %q and %q, want only the completed payout's", col(first, "completed_at"), col(second, "completed_at"))
+	}
+}
+
+func TestExportWritesJSONLines(t *testing.T) {
+	f := newExportFixture(t, 100)
+	completed, _ := f.twoPayouts(t)
+
+	var buf bytes.Buffer
+	if _, err := f.svc.Write(context.Background(), &buf, ExportFormatJSONL, repositories.PayoutFilter{MerchantID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	var records []dto.PayoutExportRecord
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var r dto.PayoutExportRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d lines, want 2", len(records))
+	}
+	r := records[0]
+	if r.ID != completed.ID || r.Amount != 150.25 || r.RecipientAccount != fieldcrypt.Mask("0123456789") || r.ProviderReference != "PRV-1" || r.CompletedAt == nil {
+		t.Fatalf("first record = %+v", r)
+	}
+	if records[1].Amount != 50 || records[1].CompletedAt != nil || records[1].FailureCode != FailureProviderError || records[1].FailureMessage != "bank timed out" || records[1].Attempts != 2 {
+		t.Fatalf("second record = %+v", records[1])
+	}
+}
+
+func TestExportNeedsBackgroundJobAboveThreshold(t *testing.T) {
+	f := newExportFixture(t, 2)
+	f.twoPayouts(t)
+	filter := repositories.PayoutFilter{MerchantID: 7}
+
+	if background, err := f.svc.NeedsBackgroundJob(context.Background(), filter); err != nil || background {
+		t.Fatalf("at the threshold: %v, %v; want streamed inline", background, err)
+	}
+	f.payout(t, 7, "INV-3", 100, "0123456789")
+	if background, err := f.svc.NeedsBackgroundJob(context.Background(), filter); err != nil || !background {
+		t.Fatalf("above the threshold: %v, %v; want a background job", background, err)
+	}
+}
+
+func TestExportJob(t *testing.T) {
+	f := newExportFixture(t, 0)
+	completed, _ := f.twoPayouts(t)
+	ctx := context.Background()
+
+	started, err := f.svc.StartJob(ctx, ExportFormatCSV, repositories.PayoutFilter{MerchantID: 7, RecipientAccount: "0123456789"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if started.Status != models.ExportPending || started.DownloadURL != "" {
+		t.Fatalf("started job = %+v, want pending without a download link", started)
+	}
+	// The persisted filter holds the blind index, not the account number.
+	if stored, _ := f.jobs.Get(ctx, started.ID); strings.Contains(string(stored.Filter), "0123456789") {
+		t.Fatalf("stored filter %s contains the account number", stored.Filter)
+	}
+
+	job := waitForExportJob(t, f.svc, started.ID)
+	if job.RowCount != 1 || job.CompletedAt == nil || job.DownloadURL != "/payouts/exports/"+job.ID+"/download" {
+		t.Fatalf("finished job = %+v", job)
+	}
+	path, name, err := f.svc.JobFile(ctx, job.ID, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(name, "payouts-") || !strings.HasSuffix(name, ".csv") {
+		t.Fatalf("file name = %q", name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil || len(records) != 2 || records[1][0] != strconv.Itoa(completed.ID) {
+		t.Fatalf("export file = %v, %v; want the header and payout %d", records, err, completed.ID)
+	}
+
+	// Jobs are private to the merchant that started them.
+	if _, err := f.svc.GetJob(ctx, job.ID, 8); apperr.KindOf(err) != apperr.KindNotFound {
+		t.Fatalf("other merchant's job: got %v, want not found", err)
+	}
+	if _, _, err := f.svc.JobFile(ctx, job.ID, 8); apperr.KindOf(err) != apperr.KindNotFound {
+		t.Fatalf("other merchant's file: got %v, want not found", err)
+	}
+	if _, err := f.svc.GetJob(ctx, "not-a-uuid", 7); apperr.KindOf(err) != apperr.KindNotFound {
+		t.Fatalf("malformed id: got %v, want not found", err)
+	}
+	if _, err := f.svc.StartJob(ctx, "xml", repositories.PayoutFilter{MerchantID: 7}); apperr.KindOf(err) != apperr.KindValidation {
+		t.Fatalf("unsupported format: got %v, want a validation error", err)
+	}
+}
+
+func TestExportJobAfterShutdown(t *testing.T) {
+	f := newExportFixture(t, 0)
+	ctx := context.Background()
+	started, err := f.svc.StartJob(ctx, ExportFormatJSONL, repositories.PayoutFilter{MerchantID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForExportJob(t, f.svc, started.ID)
+
+	// Once shutdown begins new jobs are refused.
+	f.workers.Shutdown(ctx)
+	if _, err := f.svc.StartJob(ctx, ExportFormatJSONL, repositories.PayoutFilter{MerchantID: 7}); apperr.KindOf(err) != apperr.KindUnavailable {
+		t.Fatalf("got %v, want unavailable", err)
+	}
+}
+
+func TestExportJobFileNotReady(t *testing.T) {
+	f := newExportFixture(t, 0)
+	ctx := context.Background()
+	job := &models.ExportJob{ID: "5f0c6d1e-8d2a-4c1e-9a51-3f6b2c7d8e90", MerchantID: 7, Format: ExportFormatCSV, Filter: json.RawMessage(`{}`), Status: models.ExportRunning}
+	if err := f.jobs.Create(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := f.svc.JobFile(ctx, job.ID, 7)
+	if !isConflict(err, "export_not_ready") {
+		t.Fatalf("got %v, want export_not_ready", err)
+	}
+	resp, err := f.svc.GetJob(ctx, job.ID, 7)
+	if err != nil || resp.Status != models.ExportRunning || resp.DownloadURL != "" {
+		t.Fatalf("running job = %+v, %v", resp, err)
+	}
+}
+
+// waitForExportJob polls the job until it leaves pending and running, and fails the test if
+// it didn't complete.
+func waitForExportJob(t *testing.T, svc *ExportService, id string) dto.ExportJobResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := svc.GetJob(context.Background(), id, 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case job.Status == models.ExportCompleted:
+			return job
+		case job.Status == models.ExportFailed:
+			t.Fatalf("export job failed: %s", job.Error)
+		case time.Now().After(deadline):
+			t.Fatalf("export job still %s", job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/kodra-pay/payout-service/internal/clients"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// Failure codes recorded on failed payouts. The payout provider reports its own failures
+// through the internal status endpoint; the others are recorded by this service.
+const (
+	// FailureInsufficientFunds means the merchant's available balance didn't cover the
+	// payout when it was debited.
+	FailureInsufficientFunds = "insufficient_funds"
+	// FailureInvalidAccount means the bank rejected the recipient account.
+	FailureInvalidAccount = "invalid_account"
+	// FailureComplianceRejected means a compliance check blocked the payout.
+	FailureComplianceRejected = "compliance_rejected"
+	// FailureProviderTimeout means the provider didn't confirm the payout in time.
+	FailureProviderTimeout = "provider_timeout"
+	// FailureProviderError means the provider failed for a reason of its own.
+	FailureProviderError = "provider_error"
+	// FailureFinalization means merchant-service couldn't be reached to debit the
+	// balance when the payout completed.
+	FailureFinalization = "finalization_failed"
+	// FailureForceFailed marks payouts failed by an operator.
+	FailureForceFailed = "force_failed"
+	// FailureUnknown is recorded when whoever failed the payout didn't say why.
+	FailureUnknown = "unknown"
+)
+
+// FailureCodes lists every failure code, as accepted by UpdateStatus.
+var FailureCodes = []string{
+	FailureInsufficientFunds, FailureInvalidAccount, FailureComplianceRejected,
+	FailureProviderTimeout, FailureProviderError, FailureFinalization,
+	FailureForceFailed, FailureUnknown,
+}
+
+// retryableFailures may succeed when attempted again: the merchant can top up the balance,
+// and the provider or merchant-service may recover. The rest need the payout corrected or
+// an operator to look at it; in particular a payout that failed for an unknown reason may
+// have reached the recipient, so it isn't retried blindly.
+var retryableFailures = []string{
+	FailureInsufficientFunds, FailureProviderTimeout, FailureProviderError, FailureFinalization,
+}
+
+// IsRetryableFailure reports whether a payout that failed with code may be retried by the
+// merchant.
+func IsRetryableFailure(code string) bool {
+	return slices.Contains(retryableFailures, code)
+}
+
+// completionFailure describes why a completed payout couldn't be debited from the merchant
+// balance.
+func completionFailure(err error) repositories.StatusUpdate {
+	if errors.Is(err, clients.ErrInsufficientBalance) {
+		return repositories.StatusUpdate{
+			FailureCode:    FailureInsufficientFunds,
+			FailureMessage: "the merchant balance did not cover the payout",
+		}
+	}
+	return repositories.StatusUpdate{
+		FailureCode:    FailureFinalization,
+		FailureMessage: "the payout could not be debited from the merchant balance",
+	}
+}
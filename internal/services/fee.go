@@ -0,0 +1,27 @@
+package services
+
+import "fmt"
+
+// FeeSchedule prices payouts: a flat fee in minor units of the payout currency plus a
+// share of the amount in basis points. The fee is worked out when a payout is created and
+// debited with the amount when it completes.
+type FeeSchedule struct {
+	Flat        int64
+	BasisPoints int64
+}
+
+// NewFeeSchedule validates the PAYOUT_FEE_FLAT and PAYOUT_FEE_BASIS_POINTS settings.
+func NewFeeSchedule(flat, basisPoints int) (FeeSchedule, error) {
+	if flat < 0 {
+		return FeeSchedule{}, fmt.Errorf("payout flat fee %d: must not be negative", flat)
+	}
+	if basisPoints < 0 || basisPoints > 10000 {
+		return FeeSchedule{}, fmt.Errorf("payout fee basis points %d: must be between 0 and 10000", basisPoints)
+	}
+	return FeeSchedule{Flat: int64(flat), BasisPoints: int64(basisPoints)}, nil
+}
+
+// Fee returns the fee of a payout of amount minor units, rounding the percentage half up.
+func (f FeeSchedule) Fee(amount int64) int64 {
+	return f.Flat + (amount*f.BasisPoints+5000)/10000
+}
@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestFeeSchedule(t *testing.T) {
+	tests := []struct {
+		flat, basisPoints int
+		amount            int64
+		want              int64
+		wantErr           bool
+	}{
+		{amount: 15025, want: 0},
+		{flat: 100, amount: 15025, want: 100},
+		{basisPoints: 50, amount: 15025, want: 75},
+		{basisPoints: 50, amount: 15100, want: 76}, // 75.5 rounds up
+		{flat: 100, basisPoints: 150, amount: 1_000_000, want: 15100},
+		{flat: -1, wantErr: true},
+		{basisPoints: -1, wantErr: true},
+		{basisPoints: 10001, wantErr: true},
+	}
+	for _, tt := range tests {
+		fees, err := NewFeeSchedule(tt.flat, tt.basisPoints)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("NewFeeSchedule(%d, %d) accepted", tt.flat, tt.basisPoints)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fees.Fee(tt.amount); got != tt.want {
+			t.Errorf("fee of %d at %d + %d bp = %d, want %d", tt.amount, tt.flat, tt.basisPoints, got, tt.want)
+		}
+	}
+}
@@ -1,61 +1,107 @@
 package services
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"math"
-	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/codes"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/background"
+	"github.com/kodra-pay/payout-service/internal/clients"
+	"github.com/kodra-pay/payout-service/internal/correlation"
 	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/logging"
+	"github.com/kodra-pay/payout-service/internal/metrics"
 	"github.com/kodra-pay/payout-service/internal/models"
 	"github.com/kodra-pay/payout-service/internal/repositories"
+	"github.com/kodra-pay/payout-service/internal/tracing"
 )
 
 type PayoutService struct {
-	repo                  *repositories.PayoutRepository
-	merchantServiceURL    string
-	transactionServiceURL string
+	repo            repositories.PayoutStore
+	merchants       clients.MerchantClient
+	transactions    clients.TransactionClient
+	notifications   clients.NotificationClient
+	workers         *background.Group
+	pageSizeDefault int
+	pageSizeMax     int
+	maxAttempts     int
+	fees            FeeSchedule
+	returnFees      ReturnFeePolicy
+
+	// stalePendingAge and resumeInterval drive WatchPending; tests shorten them.
+	stalePendingAge time.Duration
+	resumeInterval  time.Duration
 }
 
-func NewPayoutService(repo *repositories.PayoutRepository, merchantServiceURL, transactionServiceURL string) *PayoutService {
+// NewPayoutService builds the service. merchants, transactions and notifications reach
+// merchant-service, transaction-service and notification-service; asynchronous processing
+// runs on workers so shutdown can drain it. maxAttempts bounds how many times a payout is
+// attempted, counting merchant retries. fees prices new payouts, and returnFees decides
+// whether returned payouts get their fee back.
+func NewPayoutService(repo repositories.PayoutStore, merchants clients.MerchantClient, transactions clients.TransactionClient, notifications clients.NotificationClient, workers *background.Group, pageSizeDefault, pageSizeMax, maxAttempts int, fees FeeSchedule, returnFees ReturnFeePolicy) *PayoutService {
 	return &PayoutService{
-		repo:                  repo,
-		merchantServiceURL:    merchantServiceURL,
-		transactionServiceURL: transactionServiceURL,
+		repo:            repo,
+		merchants:       merchants,
+		transactions:    transactions,
+		notifications:   notifications,
+		workers:         workers,
+		pageSizeDefault: pageSizeDefault,
+		pageSizeMax:     pageSizeMax,
+		maxAttempts:     maxAttempts,
+		fees:            fees,
+		returnFees:      returnFees,
+		stalePendingAge: stalePendingAge,
+		resumeInterval:  resumeInterval,
 	}
 }
 
 func (s *PayoutService) Create(ctx context.Context, req dto.PayoutRequest) (dto.PayoutResponse, error) {
-	if req.Amount <= 0 || req.MerchantID == 0 { // int check
-		return dto.PayoutResponse{}, fmt.Errorf("merchant_id and positive amount are required")
+	var fields []apperr.FieldError
+	if req.MerchantID == 0 {
+		fields = append(fields, apperr.Field("merchant_id", "is required"))
+	}
+	if req.Amount <= 0 {
+		fields = append(fields, apperr.Field("amount", "must be positive"))
+	}
+	req.Reference = strings.TrimSpace(req.Reference)
+	if req.Reference != "" && !referencePattern.MatchString(req.Reference) {
+		fields = append(fields, apperr.Field("reference", referenceRule))
+	}
+	if len(fields) > 0 {
+		return dto.PayoutResponse{}, apperr.Validation("invalid payout request", fields...)
 	}
 
 	amountKobo := int64(math.Round(req.Amount * 100))
+	fee := s.fees.Fee(amountKobo)
 
 	// Check available balance before creating payout
-	available, err := s.getAvailableBalance(ctx, req.MerchantID, req.Currency)
+	balance, err := s.merchants.AvailableBalance(ctx, req.MerchantID, req.Currency)
 	if err != nil {
-		return dto.PayoutResponse{}, fmt.Errorf("failed to verify balance: %w", err)
+		return dto.PayoutResponse{}, apperr.Unavailable("failed to verify balance", err)
 	}
-	if available < amountKobo {
-		return dto.PayoutResponse{}, fmt.Errorf("insufficient available balance")
+	if balance.Available < amountKobo+fee {
+		return dto.PayoutResponse{}, apperr.InsufficientFunds("insufficient available balance")
 	}
 
-	// Generate a reference if not provided to avoid duplicate zero values
-	if req.Reference == 0 {
-		req.Reference = int(time.Now().UnixNano() / 1e6) // ms timestamp
+	generated := req.Reference == ""
+	if generated {
+		req.Reference = newReference()
 	}
 
 	p := &models.Payout{
 		MerchantID:       req.MerchantID, // int
-		Reference:        req.Reference,  // int
+		Reference:        req.Reference,
 		Amount:           amountKobo,
+		Fee:              fee,
 		Currency:         req.Currency,
 		RecipientName:    req.RecipientName,
 		RecipientAccount: req.RecipientAccount,
@@ -63,105 +109,407 @@ func (s *PayoutService) Create(ctx context.Context, req dto.PayoutRequest) (dto.
 		Status:           "pending",
 		Narration:        req.Narration,
 	}
-	// Reference is an int. If req.Reference is 0, it means no reference was provided.
-	// The DB will auto-generate p.ID.
-	if err := s.repo.Create(ctx, p); err != nil {
-		return dto.PayoutResponse{}, fmt.Errorf("failed to create payout: %w", err)
+	err = s.repo.Create(ctx, p)
+	for attempt := 1; generated && errors.Is(err, repositories.ErrDuplicateReference) && attempt < generatedReferenceAttempts; attempt++ {
+		p.Reference = newReference()
+		err = s.repo.Create(ctx, p)
+	}
+	switch {
+	case errors.Is(err, repositories.ErrDuplicateReference):
+		return dto.PayoutResponse{}, apperr.Conflict("duplicate_reference", fmt.Sprintf("a payout with reference %q already exists", p.Reference))
+	case err != nil:
+		return dto.PayoutResponse{}, apperr.Internal("failed to create payout", err)
+	}
+	tracing.SetAttributes(ctx, tracing.PayoutIDKey.Int(p.ID))
+	metrics.PayoutsCreated.WithLabelValues(p.Currency).Inc()
+	metrics.PayoutAmount.WithLabelValues(p.Currency).Observe(float64(p.Amount) / 100)
+
+	s.scheduleProcessing(ctx, p.ID) // int
+
+	return payoutResponse(p), nil
+}
+
+// Get returns the merchant's payout. Payouts of other merchants are reported as not found.
+// expand names the relations to include (dto.ExpandEvents).
+func (s *PayoutService) Get(ctx context.Context, merchantID, id int, expand []string) (dto.PayoutResponse, error) { // int
+	if err := validateExpand(expand); err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	p, err := s.getOwned(ctx, merchantID, id) // int
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	return s.expanded(ctx, p, expand)
+}
+
+// GetByReference returns the merchant's payout with the reference the merchant gave it (or
+// was generated for it).
+func (s *PayoutService) GetByReference(ctx context.Context, merchantID int, reference string, expand []string) (dto.PayoutResponse, error) {
+	if err := validateExpand(expand); err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	p, err := s.repo.GetByReference(ctx, merchantID, reference)
+	if err != nil {
+		return dto.PayoutResponse{}, apperr.Internal("failed to load payout", err)
+	}
+	if p == nil {
+		return dto.PayoutResponse{}, errPayoutNotFound()
+	}
+	return s.expanded(ctx, p, expand)
+}
+
+func validateExpand(expand []string) error {
+	for _, e := range expand {
+		if e != dto.ExpandEvents {
+			return apperr.Validation("invalid expand", apperr.Field("expand", "must be one of "+dto.ExpandEvents))
+		}
+	}
+	return nil
+}
+
+// expanded builds the response for p with the relations named in expand.
+func (s *PayoutService) expanded(ctx context.Context, p *models.Payout, expand []string) (dto.PayoutResponse, error) {
+	resp := payoutResponse(p)
+	if slices.Contains(expand, dto.ExpandEvents) {
+		events, err := s.repo.ListEvents(ctx, p.ID)
+		if err != nil {
+			return dto.PayoutResponse{}, apperr.Internal("failed to load payout events", err)
+		}
+		resp.Events = make([]dto.PayoutEventResponse, 0, len(events))
+		for _, e := range events {
+			resp.Events = append(resp.Events, dto.PayoutEventResponse{
+				Type:        e.Type,
+				FromStatus:  e.FromStatus,
+				ToStatus:    e.ToStatus,
+				FailureCode: e.FailureCode,
+				Message:     e.Message,
+				Attempt:     e.Attempt,
+				CreatedAt:   e.CreatedAt,
+			})
+		}
+	}
+	return resp, nil
+}
+
+// payoutResponse is the representation of p returned by every payout endpoint. The
+// recipient account is masked.
+func payoutResponse(p *models.Payout) dto.PayoutResponse {
+	resp := dto.PayoutResponse{
+		ID:        p.ID,
+		Reference: p.Reference,
+		Status:    p.Status,
+		Amount:    float64(p.Amount) / 100,
+		Fee:       float64(p.Fee) / 100,
+		Currency:  p.Currency,
+		Recipient: dto.PayoutRecipient{
+			Name:    p.RecipientName,
+			Account: fieldcrypt.Mask(p.RecipientAccount),
+			Bank:    p.RecipientBank,
+		},
+		Narration:         p.Narration,
+		ProviderReference: p.ProviderReference,
+		CreatedAt:         p.CreatedAt,
+		UpdatedAt:         p.UpdatedAt,
+		CompletedAt:       p.CompletedAt,
+		Attempts:          p.Attempts,
+	}
+	if p.FailureCode != "" {
+		resp.Failure = &dto.PayoutFailure{Code: p.FailureCode, Message: p.FailureMessage, Retryable: IsRetryableFailure(p.FailureCode)}
 	}
+	return resp
+}
 
-	// Simulate processing: move to processed after a short delay to mimic asynchronous payout handling.
-	go func(payoutID int) { // int
-		log.Printf("payout-service: starting simulated processing for payout %d", payoutID) // int
-		time.Sleep(5 * time.Second)
+// List returns one keyset page of the merchant's payouts matching the filter.
+func (s *PayoutService) List(ctx context.Context, f repositories.PayoutFilter, req dto.ListPayoutsRequest) (dto.PayoutListResponse, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = s.pageSizeDefault
+	}
+	if limit > s.pageSizeMax {
+		limit = s.pageSizeMax
+	}
+
+	sort := strings.ToLower(req.Sort)
+	if sort == "" {
+		sort = repositories.SortCreatedAt
+	}
+	switch sort {
+	case repositories.SortCreatedAt, repositories.SortUpdatedAt, repositories.SortAmount:
+	default:
+		return dto.PayoutListResponse{}, apperr.Validation("invalid sort", apperr.Field("sort", "must be one of created_at, updated_at, amount"))
+	}
+	order := strings.ToLower(req.Order)
+	if order == "" {
+		order = "desc"
+	}
+	if order != "asc" && order != "desc" {
+		return dto.PayoutListResponse{}, apperr.Validation("invalid order", apperr.Field("order", "must be asc or desc"))
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	page := repositories.PayoutPage{Limit: limit + 1, Sort: sort, Desc: order == "desc"}
+	if req.Cursor != "" {
+		value, id, err := decodeCursor(req.Cursor, sort, order)
+		if err != nil {
+			return dto.PayoutListResponse{}, apperr.Validation("invalid cursor", apperr.Field("cursor", err.Error()))
+		}
+		page.AfterValue, page.AfterID = value, id
+	}
+
+	list, err := s.repo.ListPayouts(ctx, f, page)
+	if err != nil {
+		return dto.PayoutListResponse{}, apperr.Internal("failed to list payouts", err)
+	}
+
+	resp := dto.PayoutListResponse{Data: make([]dto.PayoutResponse, 0, len(list))}
+	if len(list) > limit {
+		list = list[:limit]
+		resp.HasMore = true
+		resp.NextCursor = encodeCursor(sort, order, list[len(list)-1])
+	}
+	for _, p := range list {
+		resp.Data = append(resp.Data, payoutResponse(p))
+	}
+	return resp, nil
+}
+
+// Cancel stops one of the merchant's payouts before processing picks it up. Only pending
+// payouts can be cancelled; cancelling a cancelled payout returns it unchanged. Nothing has
+// been deducted from the balance while a payout is pending, so there is nothing to release.
+func (s *PayoutService) Cancel(ctx context.Context, merchantID, id int) (dto.PayoutResponse, error) {
+	current, err := s.getOwned(ctx, merchantID, id)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	switch strings.ToLower(current.Status) {
+	case "cancelled":
+		return payoutResponse(current), nil
+	case "pending":
+	default:
+		return dto.PayoutResponse{}, apperr.Conflict("payout_not_pending", fmt.Sprintf("only pending payouts can be cancelled, payout is %s", current.Status))
+	}
+
+	// Processing only moves a payout on while it is still pending, so whichever of the two
+	// changes the status first wins.
+	if err := s.repo.UpdateStatus(ctx, id, current.Status, "cancelled", repositories.StatusUpdate{}); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrStatusConflict):
+			return dto.PayoutResponse{}, &apperr.Error{Kind: apperr.KindConflict, Code: "status_conflict", Message: "payout status was changed by another request, retry", Err: err}
+		case errors.Is(err, repositories.ErrPayoutNotFound):
+			return dto.PayoutResponse{}, errPayoutNotFound()
+		}
+		return dto.PayoutResponse{}, apperr.Internal("failed to cancel payout", err)
+	}
+	observeTransition(current, current.Status, "cancelled")
+	slog.InfoContext(ctx, "payout cancelled by merchant", slog.Int("payout_id", id))
+
+	updated, err := s.load(ctx, id)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	return payoutResponse(updated), nil
+}
+
+// getOwned loads a payout and hides payouts of other merchants behind the same not-found error.
+func (s *PayoutService) getOwned(ctx context.Context, merchantID, id int) (*models.Payout, error) {
+	p, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, apperr.Internal("failed to load payout", err)
+	}
+	if p == nil || p.MerchantID != merchantID {
+		return nil, errPayoutNotFound()
+	}
+	return p, nil
+}
+
+// processingDelay is how long the simulated provider takes to process a payout.
+const processingDelay = 5 * time.Second
+
+// stalePendingAge is how long a payout may stay pending before ResumePending assumes the
+// instance that scheduled it is gone. It is well above processingDelay, so payouts a live
+// worker is waiting on aren't claimed; if one is, the status compare-and-set still lets
+// only one of them process it.
+const stalePendingAge = time.Minute
+
+// resumeInterval is how often WatchPending looks for stale pending payouts.
+const resumeInterval = 30 * time.Second
+
+// scheduleProcessing simulates asynchronous payout handling: the payout moves to processed
+// after a short delay, unless its status changed in the meantime. The work keeps the
+// caller's log attributes and trace but not its cancellation. If shutdown begins during the
+// delay the payout is left pending for ResumePending to pick up after the restart.
+func (s *PayoutService) scheduleProcessing(ctx context.Context, payoutID int) { // int
+	ctx = logging.With(correlation.Start(ctx), slog.Int("payout_id", payoutID))
+	queued := metrics.WorkerQueueDepth.WithLabelValues(metrics.WorkerPayoutProcessing)
+	queued.Inc()
+	started := s.workers.Go(ctx, fmt.Sprintf("payout %d processing", payoutID), func(ctx context.Context) {
+		defer queued.Dec()
+		slog.DebugContext(ctx, "starting simulated payout processing")
+		select {
+		case <-time.After(processingDelay):
+		case <-s.workers.Stopping():
+			slog.InfoContext(ctx, "shutting down, payout left pending to be resumed on restart")
+			return
+		}
+
+		ctx, span := tracing.Start(ctx, "PayoutService.process", tracing.PayoutIDKey.Int(payoutID))
+		defer span.End()
 		// Only auto-process if the payout is still pending to avoid retrying failed/updated payouts.
-		current, err := s.repo.GetByID(context.Background(), payoutID)
+		current, err := s.repo.GetByID(ctx, payoutID)
 		if err != nil || current == nil {
-			log.Printf("payout-service: skipping auto-process for payout %d: not found or error: %v", payoutID, err)
+			slog.WarnContext(ctx, "skipping auto-process: payout not found", slog.Any("error", err))
 			return
 		}
 		if strings.ToLower(current.Status) != "pending" {
-			log.Printf("payout-service: skipping auto-process for payout %d because status is %s", payoutID, current.Status)
+			slog.InfoContext(ctx, "skipping auto-process: payout is no longer pending", slog.String("status", current.Status))
 			return
 		}
 
-		log.Printf("payout-service: attempting to update status for payout %d to 'processed'", payoutID) // int
-		if _, err := s.UpdateStatus(context.Background(), payoutID, "processed"); err != nil {           // int
-			log.Printf("payout-service: failed to auto-process payout %d: %v", payoutID, err) // int
+		providerReference := fmt.Sprintf("SIM%d%06d", time.Now().Unix(), payoutID)
+		if _, err := s.UpdateStatus(ctx, payoutID, "processed", repositories.StatusUpdate{ProviderReference: providerReference}); err != nil { // int
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			slog.ErrorContext(ctx, "failed to auto-process payout", slog.Any("error", err))
 		} else {
-			log.Printf("payout-service: successfully auto-processed payout %d to 'processed'", payoutID) // int
+			slog.InfoContext(ctx, "payout auto-processed", slog.String("provider_reference", providerReference))
 		}
-	}(p.ID) // int
+	})
+	if !started {
+		queued.Dec()
+		slog.InfoContext(ctx, "shutting down, payout left pending to be resumed on restart")
+	}
+}
 
-	return dto.PayoutResponse{
-		ID:        p.ID, // int
-		Status:    p.Status,
-		Amount:    float64(p.Amount) / 100,
-		Currency:  p.Currency,
-		Reference: p.Reference, // int
-	}, nil
+// WatchPending runs ResumePending now and then every resumeInterval until shutdown begins.
+// A single pass at startup isn't enough: payouts the previous instance left pending less
+// than stalePendingAge before the restart only become stale later, and other replicas may
+// stop at any time.
+func (s *PayoutService) WatchPending(ctx context.Context) {
+	s.workers.Go(ctx, "pending payout watcher", func(ctx context.Context) {
+		ticker := time.NewTicker(s.resumeInterval)
+		defer ticker.Stop()
+		for {
+			n, err := s.ResumePending(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to resume pending payouts", slog.Any("error", err))
+			} else if n > 0 {
+				slog.InfoContext(ctx, "resumed pending payouts", slog.Int("count", n))
+			}
+			select {
+			case <-ticker.C:
+			case <-s.workers.Stopping():
+				return
+			}
+		}
+	})
 }
 
-func (s *PayoutService) Get(ctx context.Context, id int) dto.PayoutResponse { // int
-	p, _ := s.repo.GetByID(ctx, id) // int
-	if p == nil {
-		return dto.PayoutResponse{}
+// ResumePending schedules processing for payouts left pending by an instance that stopped
+// before handling them. Payouts are claimed in batches so instances running it at the same
+// time don't resume the same payout twice. It returns the number of payouts resumed.
+func (s *PayoutService) ResumePending(ctx context.Context) (int, error) {
+	const batchSize = 100
+	resumed := 0
+	for {
+		ids, err := s.repo.ClaimStalePending(ctx, s.stalePendingAge, batchSize)
+		if err != nil {
+			return resumed, fmt.Errorf("failed to claim pending payouts: %w", err)
+		}
+		for _, id := range ids {
+			s.scheduleProcessing(ctx, id)
+		}
+		resumed += len(ids)
+		if len(ids) < batchSize {
+			return resumed, nil
+		}
 	}
-	return dto.PayoutResponse{
-		ID:        p.ID, // int
-		Status:    p.Status,
-		Amount:    float64(p.Amount) / 100,
-		Currency:  p.Currency,
-		Reference: p.Reference, // int
+}
+
+// ForceFail marks a payout that hasn't completed as failed. Completed payouts have already
+// moved money and can only be reversed.
+func (s *PayoutService) ForceFail(ctx context.Context, id int, actor, reason string) (dto.PayoutResponse, error) {
+	current, err := s.load(ctx, id)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	if isFinalStatus(current.Status) {
+		return dto.PayoutResponse{}, apperr.Conflict("payout_completed", fmt.Sprintf("payout is already %s", current.Status))
 	}
+	slog.InfoContext(ctx, "force-failing payout", slog.Int("payout_id", id), slog.String("actor", actor), slog.String("reason", reason))
+	return s.UpdateStatus(ctx, id, "failed", repositories.StatusUpdate{FailureCode: FailureForceFailed, FailureMessage: reason})
 }
 
-// getAvailableBalance fetches merchant available balance from merchant-service
-func (s *PayoutService) getAvailableBalance(ctx context.Context, merchantID int, currency string) (int64, error) { // int
-	url := fmt.Sprintf("%s/merchants/%d/balance?currency=%s", strings.TrimRight(s.merchantServiceURL, "/"), merchantID, currency) // int
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// Retry puts a failed payout back to pending and schedules it for processing again. It is
+// the ops override of RetryFailed: any failed payout can be retried, whatever the failure
+// and however many attempts it has had.
+func (s *PayoutService) Retry(ctx context.Context, id int, actor string) (dto.PayoutResponse, error) {
+	current, err := s.load(ctx, id)
 	if err != nil {
-		return 0, err
+		return dto.PayoutResponse{}, err
 	}
-	resp, err := http.DefaultClient.Do(req)
+	if strings.ToLower(current.Status) != "failed" {
+		return dto.PayoutResponse{}, apperr.Conflict("payout_not_failed", fmt.Sprintf("only failed payouts can be retried, payout is %s", current.Status))
+	}
+	slog.InfoContext(ctx, "retrying payout", slog.Int("payout_id", id), slog.String("actor", actor))
+	return s.retry(ctx, current)
+}
+
+// RetryFailed retries one of the merchant's failed payouts. Only failures that may succeed
+// on another attempt can be retried, up to the attempt limit, and the merchant's balance
+// must cover the payout again.
+func (s *PayoutService) RetryFailed(ctx context.Context, merchantID, id int) (dto.PayoutResponse, error) {
+	current, err := s.getOwned(ctx, merchantID, id)
 	if err != nil {
-		return 0, err
+		return dto.PayoutResponse{}, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("merchant service returned %d", resp.StatusCode)
+	switch {
+	case strings.ToLower(current.Status) != "failed":
+		return dto.PayoutResponse{}, apperr.Conflict("payout_not_failed", fmt.Sprintf("only failed payouts can be retried, payout is %s", current.Status))
+	case !IsRetryableFailure(current.FailureCode):
+		return dto.PayoutResponse{}, apperr.Conflict("failure_not_retryable", fmt.Sprintf("payouts that failed with %s can't be retried", current.FailureCode))
+	case current.Attempts >= s.maxAttempts:
+		return dto.PayoutResponse{}, apperr.Conflict("retry_limit_reached", fmt.Sprintf("payout has been attempted %d times, the limit", current.Attempts))
 	}
-	var payload struct {
-		AvailableBalance float64 `json:"available_balance"`
+
+	balance, err := s.merchants.AvailableBalance(ctx, current.MerchantID, current.Currency)
+	if err != nil {
+		return dto.PayoutResponse{}, apperr.Unavailable("failed to verify balance", err)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
-		return 0, err
+	if balance.Available < current.Amount+current.Fee {
+		return dto.PayoutResponse{}, apperr.InsufficientFunds("insufficient available balance")
 	}
-	return int64(math.Round(payload.AvailableBalance * 100)), nil
+	slog.InfoContext(ctx, "merchant retrying payout", slog.Int("payout_id", id), slog.Int("attempt", current.Attempts+1))
+	return s.retry(ctx, current)
 }
 
-func (s *PayoutService) List(ctx context.Context, merchantID int) []dto.PayoutResponse { // int
-	list, _ := s.repo.ListByMerchant(ctx, merchantID, 50) // int
-	var resp []dto.PayoutResponse
-	for _, p := range list {
-		resp = append(resp, dto.PayoutResponse{
-			ID:        p.ID, // int
-			Status:    p.Status,
-			Amount:    float64(p.Amount) / 100,
-			Currency:  p.Currency,
-			Reference: p.Reference, // int
-		})
+// retry starts another attempt of a failed payout.
+func (s *PayoutService) retry(ctx context.Context, current *models.Payout) (dto.PayoutResponse, error) {
+	if err := s.repo.UpdateStatus(ctx, current.ID, current.Status, "pending", repositories.StatusUpdate{NewAttempt: true}); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrStatusConflict):
+			return dto.PayoutResponse{}, &apperr.Error{Kind: apperr.KindConflict, Code: "status_conflict", Message: "payout status was changed by another request, retry", Err: err}
+		case errors.Is(err, repositories.ErrPayoutNotFound):
+			return dto.PayoutResponse{}, errPayoutNotFound()
+		}
+		return dto.PayoutResponse{}, apperr.Internal("failed to retry payout", err)
 	}
-	return resp
-}
+	observeTransition(current, current.Status, "pending")
+	s.scheduleProcessing(ctx, current.ID)
 
-func (s *PayoutService) Cancel(_ context.Context, id int) map[string]interface{} { // int, map[string]interface{}
-	return map[string]interface{}{"id": id, "status": "cancelled"}
+	updated, err := s.load(ctx, current.ID)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	return payoutResponse(updated), nil
 }
 
-func (s *PayoutService) UpdateStatus(ctx context.Context, id int, status string) (dto.PayoutResponse, error) { // int
+// UpdateStatus moves a payout to a new status. u.ProviderReference, when set, records the
+// reference the payout provider/bank assigned so statements can be reconciled later; the
+// failure code and message say why a failed payout failed (FailureUnknown if unset).
+func (s *PayoutService) UpdateStatus(ctx context.Context, id int, status string, u repositories.StatusUpdate) (dto.PayoutResponse, error) { // int
 	normalized := status
 	if normalized == "" {
-		return dto.PayoutResponse{}, fmt.Errorf("status is required")
+		return dto.PayoutResponse{}, apperr.Validation("status is required", apperr.Field("status", "is required"))
 	}
 	normalized = strings.ToLower(normalized)
 
@@ -169,152 +517,150 @@ func (s *PayoutService) UpdateStatus(ctx context.Context, id int, status string)
 	case "pending", "processing", "processed", "completed", "failed":
 		// allowed
 	default:
-		return dto.PayoutResponse{}, fmt.Errorf("invalid status")
+		return dto.PayoutResponse{}, apperr.Validation("invalid status",
+			apperr.Field("status", "must be one of pending, processing, processed, completed, failed"))
+	}
+
+	u.ProviderReference = strings.TrimSpace(u.ProviderReference)
+	if normalized == "failed" {
+		u.FailureCode = strings.ToLower(strings.TrimSpace(u.FailureCode))
+		if u.FailureCode == "" {
+			u.FailureCode = FailureUnknown
+		}
+		if !slices.Contains(FailureCodes, u.FailureCode) {
+			return dto.PayoutResponse{}, apperr.Validation("invalid failure code",
+				apperr.Field("failure_code", "must be one of "+strings.Join(FailureCodes, ", ")))
+		}
+	} else {
+		u.FailureCode, u.FailureMessage = "", ""
 	}
 
 	// Fetch current state to avoid double-deducting on repeated calls
-	current, err := s.repo.GetByID(ctx, id)
+	current, err := s.load(ctx, id)
 	if err != nil {
 		return dto.PayoutResponse{}, err
 	}
-	if current == nil {
-		return dto.PayoutResponse{}, fmt.Errorf("payout not found")
-	}
 	previousStatus := strings.ToLower(current.Status)
+	// Returned payouts have given the money back; only a new payout can send it again.
+	if isReturnedStatus(previousStatus) {
+		return dto.PayoutResponse{}, apperr.Conflict("payout_returned", fmt.Sprintf("payout is already %s", current.Status))
+	}
+	// The merchant withdrew a cancelled payout; it must not be processed after all.
+	if previousStatus == "cancelled" {
+		return dto.PayoutResponse{}, apperr.Conflict("payout_cancelled", "payout is cancelled")
+	}
 
 	// Avoid re-processing already finalized payouts
 	if isFinalStatus(previousStatus) && isFinalStatus(normalized) {
-		return dto.PayoutResponse{
-			ID:        current.ID,
-			Reference: current.Reference,
-			Status:    current.Status,
-			Amount:    float64(current.Amount) / 100,
-			Currency:  current.Currency,
-		}, nil
-	}
-
-	if err := s.repo.UpdateStatus(ctx, id, normalized); err != nil { // int
-		return dto.PayoutResponse{}, err
+		return payoutResponse(current), nil
+	}
+	// The merchant has been debited for a finalized payout. Moving it back would let a retry
+	// debit them again; money the bank sends back goes through ReportReturn instead.
+	if isFinalStatus(previousStatus) {
+		return dto.PayoutResponse{}, apperr.Conflict("payout_completed", fmt.Sprintf("payout is already %s", current.Status))
+	}
+
+	// The update only applies if nobody changed the status since it was read above, so two
+	// concurrent completions can't both deduct the balance.
+	if err := s.repo.UpdateStatus(ctx, id, current.Status, normalized, u); err != nil { // int
+		switch {
+		case errors.Is(err, repositories.ErrStatusConflict):
+			return dto.PayoutResponse{}, &apperr.Error{Kind: apperr.KindConflict, Code: "status_conflict", Message: "payout status was changed by another request, retry", Err: err}
+		case errors.Is(err, repositories.ErrPayoutNotFound):
+			return dto.PayoutResponse{}, errPayoutNotFound()
+		}
+		return dto.PayoutResponse{}, apperr.Internal("failed to update payout status", err)
 	}
+	observeTransition(current, previousStatus, normalized)
 
 	updated, err := s.repo.GetByID(ctx, id) // int
 	if err != nil || updated == nil {
-		return dto.PayoutResponse{}, fmt.Errorf("payout not found after update")
+		return dto.PayoutResponse{}, apperr.Internal("payout not found after update", err)
 	}
 
 	// On completion, deduct available balance and record payout transaction
 	if isFinalStatus(normalized) && !isFinalStatus(previousStatus) {
 		if err := s.handlePayoutCompletion(ctx, updated); err != nil {
-			_ = s.repo.UpdateStatus(context.Background(), id, "failed")
-			return dto.PayoutResponse{}, fmt.Errorf("failed to finalize payout: %w", err)
+			failure := completionFailure(err)
+			if err := s.repo.UpdateStatus(context.WithoutCancel(ctx), id, normalized, "failed", failure); err == nil {
+				observeTransition(current, normalized, "failed")
+			}
+			if failure.FailureCode == FailureInsufficientFunds {
+				return dto.PayoutResponse{}, apperr.InsufficientFunds("merchant balance does not cover the payout")
+			}
+			return dto.PayoutResponse{}, apperr.Unavailable("failed to finalize payout", err)
 		}
 	}
 
+	resp := payoutResponse(updated)
 	// Normalize "processed" to "completed" for display consistency
-	displayStatus := updated.Status
-	if displayStatus == "processed" {
-		displayStatus = "completed"
-	}
-
-	return dto.PayoutResponse{
-		ID:        updated.ID,        // int
-		Reference: updated.Reference, // int
-		Status:    displayStatus,
-		Amount:    float64(updated.Amount) / 100,
-		Currency:  updated.Currency,
-	}, nil
-}
-
-func isFinalStatus(status string) bool {
-	switch strings.ToLower(status) {
-	case "processed", "completed":
-		return true
-	default:
-		return false
-	}
-}
-
-// handlePayoutCompletion deducts merchant available balance and logs a payout transaction
-func (s *PayoutService) handlePayoutCompletion(ctx context.Context, p *models.Payout) error {
-	if err := s.deductMerchantBalance(ctx, p.MerchantID, p.Currency, p.Amount); err != nil {
-		return err
-	}
-
-	if err := s.recordPayoutTransaction(ctx, p); err != nil {
-		// If the transaction log fails, we don't want to double-deduct on retry.
-		// Log and continue so the payout remains completed.
-		log.Printf("payout-service: failed to record payout transaction for payout %d: %v", p.ID, err)
+	if resp.Status == "processed" {
+		resp.Status = "completed"
 	}
-
-	return nil
+	return resp, nil
 }
 
-func (s *PayoutService) deductMerchantBalance(ctx context.Context, merchantID int, currency string, amount int64) error {
-	url := fmt.Sprintf("%s/internal/balance/payout", strings.TrimRight(s.merchantServiceURL, "/"))
-
-	payload := map[string]interface{}{
-		"merchant_id": merchantID,
-		"currency":    currency,
-		"amount":      float64(amount) / 100, // send in currency units
-	}
-
-	body, _ := json.Marshal(payload)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+// load returns the payout or a not-found error.
+func (s *PayoutService) load(ctx context.Context, id int) (*models.Payout, error) {
+	p, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+		return nil, apperr.Internal("failed to load payout", err)
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("merchant service returned %d: %s", resp.StatusCode, string(b))
+	if p == nil {
+		return nil, errPayoutNotFound()
 	}
-
-	return nil
+	return p, nil
 }
 
-func (s *PayoutService) recordPayoutTransaction(ctx context.Context, p *models.Payout) error {
-	if s.transactionServiceURL == "" {
-		return fmt.Errorf("transaction service URL not configured")
-	}
+func errPayoutNotFound() *apperr.Error {
+	return apperr.NotFound("payout_not_found", "payout not found")
+}
 
-	reference := fmt.Sprintf("payout-%d", p.ID)
-	if p.Reference != 0 {
-		reference = fmt.Sprintf("payout-%d", p.Reference)
+// observeTransition records a status change of p in the payout metrics.
+func observeTransition(p *models.Payout, from, to string) {
+	metrics.PayoutTransitions.WithLabelValues(from, to, p.Currency).Inc()
+	if isFinalStatus(to) && !isFinalStatus(from) {
+		metrics.PayoutTimeToCompletion.WithLabelValues(p.Currency).Observe(time.Since(p.CreatedAt).Seconds())
 	}
+}
 
-	payload := map[string]interface{}{
-		"reference":      reference,
-		"merchant_id":    p.MerchantID,
-		"amount":         float64(p.Amount) / 100, // send in currency units
-		"currency":       p.Currency,
-		"payment_method": "payout",
-		"status":         "payout",
-		"description":    fmt.Sprintf("Payout to %s (%s)", p.RecipientName, p.RecipientBank),
+func isFinalStatus(status string) bool {
+	switch strings.ToLower(status) {
+	case "processed", "completed":
+		return true
+	default:
+		return false
 	}
+}
 
-	body, _ := json.Marshal(payload)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/transactions", strings.TrimRight(s.transactionServiceURL, "/")), bytes.NewReader(body))
+// handlePayoutCompletion deducts merchant available balance and logs a payout transaction
+func (s *PayoutService) handlePayoutCompletion(ctx context.Context, p *models.Payout) error {
+	// The fee is charged with the payout, so a return can give it back under ReturnFeesRefunded.
+	debit := p.Amount + p.Fee
+	err := s.merchants.DeductBalance(ctx, dto.DeductBalanceRequest{
+		MerchantID: p.MerchantID,
+		Amount:     debit,
+		Currency:   p.Currency,
+	})
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := http.DefaultClient.Do(req)
+	// Merchant references are only unique per merchant, so the ledger entry is keyed by the
+	// payout ID.
+	err = s.transactions.RecordTransaction(ctx, clients.TransactionRequest{
+		Reference:     fmt.Sprintf("payout-%d", p.ID),
+		MerchantID:    p.MerchantID,
+		Amount:        debit,
+		Currency:      p.Currency,
+		PaymentMethod: "payout",
+		Status:        "payout",
+		Description:   fmt.Sprintf("Payout to %s (%s)", p.RecipientName, p.RecipientBank),
+	})
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("transaction service returned %d: %s", resp.StatusCode, string(b))
+		// If the transaction log fails, we don't want to double-deduct on retry.
+		// Log and continue so the payout remains completed.
+		slog.ErrorContext(ctx, "failed to record payout transaction", slog.Int("payout_id", p.ID), slog.Any("error", err))
 	}
 
 	return nil
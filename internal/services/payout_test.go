@@ -0,0 +1,744 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/background"
+	"github.com/kodra-pay/payout-service/internal/clients"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/httpclient"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// Base64 test keys; never use these outside tests.
+const (
+	testEncryptionKeys = "1:MDEyMzQ1Njc4OTAxMjM0NTY3ODkwMTIzNDU2Nzg5MDE="
+	testBlindIndexKey  = "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXoxMjM0NTY="
+)
+
+type payoutFixture struct {
+	svc           *PayoutService
+	store         *repositories.MemoryPayoutStore
+	merchants     *clients.FakeMerchantClient
+	transactions  *clients.FakeTransactionClient
+	notifications *clients.FakeNotificationClient
+}
+
+func testKeyring(t *testing.T) *fieldcrypt.Keyring {
+	t.Helper()
+	keys, err := fieldcrypt.NewKeyring(testEncryptionKeys, "", 1, testBlindIndexKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// newPayoutFixture builds a service on an in-memory store and fake clients. The store's
+// behaviour against Postgres is covered by the conformance tests in repositories.
+func newPayoutFixture(t *testing.T) *payoutFixture {
+	t.Helper()
+	workers := background.NewGroup()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		workers.Shutdown(ctx)
+	})
+	f := &payoutFixture{
+		store:         repositories.NewMemoryPayoutStore(testKeyring(t)),
+		merchants:     clients.NewFakeMerchantClient(),
+		transactions:  clients.NewFakeTransactionClient(),
+		notifications: clients.NewFakeNotificationClient(),
+	}
+	f.svc = NewPayoutService(f.store, f.merchants, f.transactions, f.notifications, workers, 20, 100, 3, FeeSchedule{}, ReturnFeesKept)
+	return f
+}
+
+// createPayout creates a payout of 150.25 NGN for merchant 7 and moves it to status.
+func (f *payoutFixture) createPayout(t *testing.T, status string) int {
+	t.Helper()
+	ctx := context.Background()
+	f.merchants.SetBalance(7, "NGN", 1_000_000)
+	created, err := f.svc.Create(ctx, validPayoutRequest(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "pending" {
+		if err := f.store.UpdateStatus(ctx, created.ID, "pending", status, repositories.StatusUpdate{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return created.ID
+}
+
+func (f *payoutFixture) status(t *testing.T, id int) string {
+	t.Helper()
+	p, err := f.store.GetByID(context.Background(), id)
+	if err != nil || p == nil {
+		t.Fatalf("get payout %d: %v, %v", id, p, err)
+	}
+	return p.Status
+}
+
+func validPayoutRequest(merchantID int) dto.PayoutRequest {
+	return dto.PayoutRequest{
+		MerchantID:       merchantID,
+		Amount:           150.25,
+		Currency:         "NGN",
+		RecipientName:    "Ada Obi",
+		RecipientAccount: "0123456789",
+		RecipientBank:    "058",
+		Narration:        "test payout",
+	}
+}
+
+func TestCreateRejectsInvalidRequests(t *testing.T) {
+	f := newPayoutFixture(t)
+	tests := []struct {
+		name string
+		req  dto.PayoutRequest
+	}{
+		{"zero amount", dto.PayoutRequest{MerchantID: 1, Amount: 0, Currency: "NGN"}},
+		{"negative amount", dto.PayoutRequest{MerchantID: 1, Amount: -5, Currency: "NGN"}},
+		{"missing merchant", dto.PayoutRequest{Amount: 10, Currency: "NGN"}},
+		{"reference with a slash", dto.PayoutRequest{MerchantID: 1, Amount: 10, Currency: "NGN", Reference: "INV/42"}},
+		{"reference too long", dto.PayoutRequest{MerchantID: 1, Amount: 10, Currency: "NGN", Reference: strings.Repeat("a", 65)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.svc.Create(context.Background(), tt.req)
+			if kind := apperr.KindOf(err); err == nil || kind != apperr.KindValidation {
+				t.Fatalf("got %v (%s), want a validation error", err, kind)
+			}
+		})
+	}
+}
+
+func TestCreateRequiresAvailableBalance(t *testing.T) {
+	f := newPayoutFixture(t)
+	f.merchants.SetBalance(7, "NGN", 15024) // one kobo short of 150.25
+
+	_, err := f.svc.Create(context.Background(), validPayoutRequest(7))
+	if err == nil || apperr.KindOf(err) != apperr.KindInsufficientFunds || !strings.Contains(err.Error(), "insufficient available balance") {
+		t.Fatalf("got %v, want insufficient balance error", err)
+	}
+	if n := len(f.merchants.Deductions()); n != 0 {
+		t.Fatalf("got %d deductions, want none", n)
+	}
+}
+
+func TestCreatePricesPayout(t *testing.T) {
+	f := newPayoutFixture(t)
+	f.svc.fees = FeeSchedule{Flat: 100, BasisPoints: 50}
+	ctx := context.Background()
+
+	// The balance has to cover the fee as well as the amount.
+	f.merchants.SetBalance(7, "NGN", 15199)
+	if _, err := f.svc.Create(ctx, validPayoutRequest(7)); apperr.KindOf(err) != apperr.KindInsufficientFunds {
+		t.Fatalf("got %v, want an insufficient funds error", err)
+	}
+
+	f.merchants.SetBalance(7, "NGN", 15200)
+	resp, err := f.svc.Create(ctx, validPayoutRequest(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Fee != 1.75 {
+		t.Fatalf("fee = %v, want 1.75", resp.Fee)
+	}
+	if p, _ := f.store.GetByID(ctx, resp.ID); p == nil || p.Fee != 175 {
+		t.Fatalf("stored payout = %+v, want a fee of 175", p)
+	}
+}
+
+func TestCreateSurfacesBalanceLookupFailures(t *testing.T) {
+	f := newPayoutFixture(t)
+	f.merchants.BalanceErr = &httpclient.CircuitOpenError{Dependency: "merchant-service", RetryAfter: time.Second}
+
+	_, err := f.svc.Create(context.Background(), validPayoutRequest(7))
+	if !errors.Is(err, httpclient.ErrCircuitOpen) || apperr.KindOf(err) != apperr.KindUnavailable {
+		t.Fatalf("got %v, want an unavailable error wrapping ErrCircuitOpen", err)
+	}
+}
+
+func TestUpdateStatusRejectsUnknownStatuses(t *testing.T) {
+	f := newPayoutFixture(t)
+	for _, status := range []string{"", "cancelled", "done"} {
+		if _, err := f.svc.UpdateStatus(context.Background(), 1, status, repositories.StatusUpdate{}); apperr.KindOf(err) != apperr.KindValidation {
+			t.Errorf("status %q: got %v, want a validation error", status, err)
+		}
+	}
+}
+
+func TestUpdateStatusCompletesPayoutOnce(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	f.merchants.SetBalance(7, "NGN", 1_000_000)
+
+	created, err := f.svc.Create(ctx, validPayoutRequest(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.Status != "pending" {
+		t.Fatalf("created status = %q, want pending", created.Status)
+	}
+
+	resp, err := f.svc.UpdateStatus(ctx, created.ID, "processed", repositories.StatusUpdate{ProviderReference: "PRV-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "completed" {
+		t.Fatalf("status = %q, want completed", resp.Status)
+	}
+	// A repeated final status must not deduct again.
+	if _, err := f.svc.UpdateStatus(ctx, created.ID, "completed", repositories.StatusUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+
+	deductions := f.merchants.Deductions()
+	if len(deductions) != 1 {
+		t.Fatalf("got %d deductions, want 1", len(deductions))
+	}
+	want := dto.DeductBalanceRequest{MerchantID: 7, Amount: 15025, Currency: "NGN"}
+	if deductions[0] != want {
+		t.Fatalf("deduction = %+v, want %+v", deductions[0], want)
+	}
+	if txns := f.transactions.Transactions(); len(txns) != 1 || txns[0].Amount != 15025 {
+		t.Fatalf("transactions = %+v, want one of 15025", txns)
+	}
+}
+
+func TestUpdateStatusRecordsInsufficientFunds(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	f.merchants.SetBalance(7, "NGN", 1_000_000)
+	created, err := f.svc.Create(ctx, validPayoutRequest(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The merchant spent the balance while the payout was processing.
+	f.merchants.SetBalance(7, "NGN", 100)
+
+	if _, err := f.svc.UpdateStatus(ctx, created.ID, "completed", repositories.StatusUpdate{}); apperr.KindOf(err) != apperr.KindInsufficientFunds {
+		t.Fatalf("got %v, want an insufficient funds error", err)
+	}
+	p, _ := f.store.GetByID(ctx, created.ID)
+	if p.Status != "failed" || p.FailureCode != FailureInsufficientFunds {
+		t.Fatalf("status = %q (failure %q), want failed (%s)", p.Status, p.FailureCode, FailureInsufficientFunds)
+	}
+}
+
+func TestUpdateStatusValidatesFailureCodes(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	id := f.createPayout(t, "processing")
+
+	if _, err := f.svc.UpdateStatus(ctx, id, "failed", repositories.StatusUpdate{FailureCode: "gremlins"}); apperr.KindOf(err) != apperr.KindValidation {
+		t.Fatalf("unknown code: got %v, want a validation error", err)
+	}
+	resp, err := f.svc.UpdateStatus(ctx, id, "failed", repositories.StatusUpdate{FailureCode: "Invalid_Account", FailureMessage: "account closed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Failure == nil || resp.Failure.Code != FailureInvalidAccount || resp.Failure.Retryable {
+		t.Fatalf("failure = %+v, want a permanent %s failure", resp.Failure, FailureInvalidAccount)
+	}
+}
+
+func TestUpdateStatusFailsPayoutWhenDeductionFails(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	f.merchants.SetBalance(7, "NGN", 1_000_000)
+
+	created, err := f.svc.Create(ctx, validPayoutRequest(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.merchants.DeductErr = errors.New("merchant-service returned 500")
+
+	if _, err := f.svc.UpdateStatus(ctx, created.ID, "completed", repositories.StatusUpdate{}); err == nil {
+		t.Fatal("expected an error")
+	}
+	p, _ := f.store.GetByID(ctx, created.ID)
+	if p.Status != "failed" || p.FailureCode != FailureFinalization {
+		t.Fatalf("status = %q (failure %q), want failed (%s)", p.Status, p.FailureCode, FailureFinalization)
+	}
+	if n := len(f.transactions.Transactions()); n != 0 {
+		t.Fatalf("got %d transactions, want none", n)
+	}
+}
+
+func TestCreateStoresPendingPayout(t *testing.T) {
+	f := newPayoutFixture(t)
+	f.merchants.SetBalance(7, "NGN", 15025)
+
+	created, err := f.svc.Create(context.Background(), validPayoutRequest(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := f.store.GetByID(context.Background(), created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != "pending" || p.Amount != 15025 || p.MerchantID != 7 || p.RecipientAccount != "0123456789" {
+		t.Fatalf("stored payout = %+v", p)
+	}
+	if !strings.HasPrefix(p.Reference, "po_") || created.Reference != p.Reference {
+		t.Fatalf("reference = %q (response %q), want a generated reference", p.Reference, created.Reference)
+	}
+}
+
+func TestCreateKeepsReferencesUniquePerMerchant(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	f.merchants.SetBalance(7, "NGN", 1_000_000)
+	f.merchants.SetBalance(8, "NGN", 1_000_000)
+
+	req := validPayoutRequest(7)
+	req.Reference = " INV-2026-0042 "
+	first, err := f.svc.Create(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Reference != "INV-2026-0042" {
+		t.Fatalf("reference = %q, want it trimmed", first.Reference)
+	}
+	_, err = f.svc.Create(ctx, req)
+	var appErr *apperr.Error
+	if !errors.As(err, &appErr) || appErr.Kind != apperr.KindConflict || appErr.Code != "duplicate_reference" {
+		t.Fatalf("second payout with the reference: got %v, want a duplicate_reference conflict", err)
+	}
+
+	// Another merchant may use the same reference, and generated references never collide.
+	other := validPayoutRequest(8)
+	other.Reference = req.Reference
+	if _, err := f.svc.Create(ctx, other); err != nil {
+		t.Fatalf("other merchant: %v", err)
+	}
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		created, err := f.svc.Create(ctx, validPayoutRequest(7))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[created.Reference] {
+			t.Fatalf("generated reference %q twice", created.Reference)
+		}
+		seen[created.Reference] = true
+	}
+}
+
+func TestGetByReference(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	f.merchants.SetBalance(7, "NGN", 1_000_000)
+	req := validPayoutRequest(7)
+	req.Reference = "INV-2026-0042"
+	created, err := f.svc.Create(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.svc.GetByReference(ctx, 7, "INV-2026-0042", nil)
+	if err != nil || got.ID != created.ID {
+		t.Fatalf("got %+v, %v, want payout %d", got, err, created.ID)
+	}
+	for _, tc := range []struct {
+		merchant  int
+		reference string
+	}{{8, "INV-2026-0042"}, {7, "INV-2026-0043"}} {
+		if _, err := f.svc.GetByReference(ctx, tc.merchant, tc.reference, nil); apperr.KindOf(err) != apperr.KindNotFound {
+			t.Errorf("merchant %d, reference %s: got %v, want a not-found error", tc.merchant, tc.reference, err)
+		}
+	}
+}
+
+func TestUpdateStatusPaths(t *testing.T) {
+	tests := []struct {
+		name         string
+		from         string
+		to           string
+		deductErr    error
+		recordErr    error
+		wantErr      bool
+		wantResponse string
+		wantStored   string
+		wantDeducted bool
+		wantRecorded bool
+	}{
+		{name: "pending to processing", from: "pending", to: "processing", wantResponse: "processing", wantStored: "processing"},
+		{name: "pending to failed", from: "pending", to: "failed", wantResponse: "failed", wantStored: "failed"},
+		{name: "pending to processed", from: "pending", to: "processed", wantResponse: "completed", wantStored: "processed", wantDeducted: true, wantRecorded: true},
+		{name: "pending to completed", from: "pending", to: "completed", wantResponse: "completed", wantStored: "completed", wantDeducted: true, wantRecorded: true},
+		{name: "processing to completed", from: "processing", to: "COMPLETED", wantResponse: "completed", wantStored: "completed", wantDeducted: true, wantRecorded: true},
+		{name: "processing to failed", from: "processing", to: "failed", wantResponse: "failed", wantStored: "failed"},
+		{name: "failed to pending", from: "failed", to: "pending", wantResponse: "pending", wantStored: "pending"},
+		{name: "failed to completed", from: "failed", to: "completed", wantResponse: "completed", wantStored: "completed", wantDeducted: true, wantRecorded: true},
+		{name: "processed to completed is a no-op", from: "processed", to: "completed", wantResponse: "processed", wantStored: "processed"},
+		{name: "completed to processed is a no-op", from: "completed", to: "processed", wantResponse: "completed", wantStored: "completed"},
+		{name: "completed to failed is rejected", from: "completed", to: "failed", wantErr: true, wantStored: "completed"},
+		{name: "processed to pending is rejected", from: "processed", to: "pending", wantErr: true, wantStored: "processed"},
+		{name: "deduction failure fails the payout", from: "pending", to: "completed", deductErr: errors.New("merchant-service returned 500"), wantErr: true, wantStored: "failed"},
+		{name: "transaction failure keeps the payout completed", from: "pending", to: "completed", recordErr: errors.New("transaction-service returned 500"), wantResponse: "completed", wantStored: "completed", wantDeducted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPayoutFixture(t)
+			id := f.createPayout(t, tt.from)
+			f.merchants.DeductErr = tt.deductErr
+			f.transactions.Err = tt.recordErr
+
+			resp, err := f.svc.UpdateStatus(context.Background(), id, tt.to, repositories.StatusUpdate{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if resp.Status != tt.wantResponse {
+				t.Errorf("response status = %q, want %q", resp.Status, tt.wantResponse)
+			}
+
+			if got := f.status(t, id); got != tt.wantStored {
+				t.Errorf("stored status = %q, want %q", got, tt.wantStored)
+			}
+			if got := len(f.merchants.Deductions()) == 1; got != tt.wantDeducted {
+				t.Errorf("deducted = %v, want %v", got, tt.wantDeducted)
+			}
+			if got := len(f.transactions.Transactions()) == 1; got != tt.wantRecorded {
+				t.Errorf("recorded = %v, want %v", got, tt.wantRecorded)
+			}
+		})
+	}
+}
+
+func TestCompletedPayoutCannotBeFailedAndRetried(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	id := f.createPayout(t, "processing")
+	if _, err := f.svc.UpdateStatus(ctx, id, "completed", repositories.StatusUpdate{}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := f.svc.UpdateStatus(ctx, id, "failed", repositories.StatusUpdate{FailureCode: FailureProviderError})
+	if !isConflict(err, "payout_completed") {
+		t.Fatalf("got %v, want a payout_completed conflict", err)
+	}
+	if _, err := f.svc.RetryFailed(ctx, 7, id); !isConflict(err, "payout_not_failed") {
+		t.Fatalf("got %v, want a payout_not_failed conflict", err)
+	}
+
+	if got := f.status(t, id); got != "completed" {
+		t.Fatalf("stored status = %q, want completed", got)
+	}
+	if n := len(f.merchants.Deductions()); n != 1 {
+		t.Fatalf("got %d deductions, want 1", n)
+	}
+}
+
+func TestUpdateStatusMissingPayout(t *testing.T) {
+	f := newPayoutFixture(t)
+	if _, err := f.svc.UpdateStatus(context.Background(), 42, "completed", repositories.StatusUpdate{}); apperr.KindOf(err) != apperr.KindNotFound {
+		t.Fatalf("got %v, want a not-found error", err)
+	}
+}
+
+// racingStore changes a payout's status right after it is read, as a concurrent writer would.
+type racingStore struct {
+	*repositories.MemoryPayoutStore
+	raced bool
+}
+
+func (s *racingStore) GetByID(ctx context.Context, id int) (*models.Payout, error) {
+	p, err := s.MemoryPayoutStore.GetByID(ctx, id)
+	if p != nil && !s.raced {
+		s.raced = true
+		if err := s.MemoryPayoutStore.UpdateStatus(ctx, id, p.Status, "failed", repositories.StatusUpdate{}); err != nil {
+			return nil, err
+		}
+	}
+	return p, err
+}
+
+func TestUpdateStatusRejectsConcurrentChanges(t *testing.T) {
+	f := newPayoutFixture(t)
+	id := f.createPayout(t, "processing")
+	f.svc.repo = &racingStore{MemoryPayoutStore: f.store}
+
+	_, err := f.svc.UpdateStatus(context.Background(), id, "completed", repositories.StatusUpdate{})
+	if !errors.Is(err, repositories.ErrStatusConflict) || apperr.KindOf(err) != apperr.KindConflict {
+		t.Fatalf("got %v, want a conflict wrapping ErrStatusConflict", err)
+	}
+	if got := f.status(t, id); got != "failed" {
+		t.Fatalf("stored status = %q, want the concurrent writer's failed", got)
+	}
+	if n := len(f.merchants.Deductions()); n != 0 {
+		t.Fatalf("got %d deductions, want none", n)
+	}
+}
+
+func TestForceFail(t *testing.T) {
+	tests := []struct {
+		from       string
+		wantErr    bool
+		wantStored string
+	}{
+		{"pending", false, "failed"},
+		{"processing", false, "failed"},
+		{"failed", false, "failed"},
+		{"processed", true, "processed"},
+		{"completed", true, "completed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			f := newPayoutFixture(t)
+			id := f.createPayout(t, tt.from)
+
+			_, err := f.svc.ForceFail(context.Background(), id, "ops@example.com", "stuck at provider")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := f.status(t, id); got != tt.wantStored {
+				t.Fatalf("stored status = %q, want %q", got, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestWatchPendingResumesPayoutsThatBecomeStale(t *testing.T) {
+	f := newPayoutFixture(t)
+	f.svc.stalePendingAge = 50 * time.Millisecond
+	f.svc.resumeInterval = 10 * time.Millisecond
+	ctx := context.Background()
+
+	// Left pending by a drained instance just before this one started.
+	p := &models.Payout{MerchantID: 7, Reference: "INV-1", Amount: 15025, Currency: "NGN", RecipientName: "Ada Obi", RecipientAccount: "0123456789", RecipientBank: "058", Status: "pending"}
+	if err := f.store.Create(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	f.svc.WatchPending(ctx)
+
+	task := fmt.Sprintf("payout %d processing", p.ID)
+	deadline := time.Now().Add(2 * time.Second)
+	for !slices.Contains(f.svc.workers.Running(), task) {
+		if time.Now().After(deadline) {
+			t.Fatalf("running tasks = %v, want %q", f.svc.workers.Running(), task)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	tests := []struct {
+		from       string
+		wantCode   string
+		wantStored string
+	}{
+		{"pending", "", "cancelled"},
+		{"cancelled", "", "cancelled"},
+		{"processing", "payout_not_pending", "processing"},
+		{"completed", "payout_not_pending", "completed"},
+		{"failed", "payout_not_pending", "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			f := newPayoutFixture(t)
+			ctx := context.Background()
+			id := f.createPayout(t, tt.from)
+
+			if _, err := f.svc.Cancel(ctx, 8, id); apperr.KindOf(err) != apperr.KindNotFound {
+				t.Fatalf("other merchant: got %v, want a not-found error", err)
+			}
+			resp, err := f.svc.Cancel(ctx, 7, id)
+			if tt.wantCode != "" {
+				if !isConflict(err, tt.wantCode) {
+					t.Fatalf("got %v, want a %s conflict", err, tt.wantCode)
+				}
+			} else if err != nil || resp.Status != "cancelled" {
+				t.Fatalf("got %+v, %v; want the cancelled payout", resp, err)
+			}
+			if got := f.status(t, id); got != tt.wantStored {
+				t.Fatalf("stored status = %q, want %q", got, tt.wantStored)
+			}
+		})
+	}
+
+	// Processing must not pick a cancelled payout back up.
+	f := newPayoutFixture(t)
+	id := f.createPayout(t, "cancelled")
+	if _, err := f.svc.UpdateStatus(context.Background(), id, "processed", repositories.StatusUpdate{}); !isConflict(err, "payout_cancelled") {
+		t.Fatalf("processing a cancelled payout: got %v", err)
+	}
+	if n := len(f.transactions.Transactions()); n != 0 {
+		t.Fatalf("got %d transactions, want none", n)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		from       string
+		wantErr    bool
+		wantStored string
+	}{
+		{"failed", false, "pending"},
+		{"pending", true, "pending"},
+		{"processing", true, "processing"},
+		{"completed", true, "completed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			f := newPayoutFixture(t)
+			id := f.createPayout(t, tt.from)
+
+			_, err := f.svc.Retry(context.Background(), id, "ops@example.com")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			p, _ := f.store.GetByID(context.Background(), id)
+			wantAttempts := 1
+			if !tt.wantErr {
+				wantAttempts = 2
+			}
+			if p.Status != tt.wantStored || p.Attempts != wantAttempts {
+				t.Fatalf("stored status = %q after %d attempts, want %q after %d", p.Status, p.Attempts, tt.wantStored, wantAttempts)
+			}
+		})
+	}
+}
+
+func TestGetHidesOtherMerchantsPayouts(t *testing.T) {
+	f := newPayoutFixture(t)
+	id := f.createPayout(t, "pending")
+
+	if _, err := f.svc.Get(context.Background(), 7, id, nil); err != nil {
+		t.Fatalf("owner: %v", err)
+	}
+	for _, tc := range []struct{ merchant, id int }{{8, id}, {7, id + 1}} {
+		if _, err := f.svc.Get(context.Background(), tc.merchant, tc.id, nil); apperr.KindOf(err) != apperr.KindNotFound {
+			t.Errorf("merchant %d, payout %d: got %v, want a not-found error", tc.merchant, tc.id, err)
+		}
+	}
+}
+
+func TestGetReturnsFullPayout(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	id := f.createPayout(t, "pending")
+	if _, err := f.svc.ForceFail(ctx, id, "ops@example.com", "stuck at provider"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := f.svc.Get(ctx, 7, id, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRecipient := dto.PayoutRecipient{Name: "Ada Obi", Account: "******6789", Bank: "058"}
+	if got.Recipient != wantRecipient || got.Narration != "test payout" || got.Amount != 150.25 {
+		t.Fatalf("got %+v", got)
+	}
+	if got.CreatedAt.IsZero() || got.UpdatedAt.Before(got.CreatedAt) || got.CompletedAt != nil {
+		t.Fatalf("timestamps: created %v, updated %v, completed %v", got.CreatedAt, got.UpdatedAt, got.CompletedAt)
+	}
+	if got.Failure == nil || *got.Failure != (dto.PayoutFailure{Code: FailureForceFailed, Message: "stuck at provider"}) {
+		t.Fatalf("failure = %+v", got.Failure)
+	}
+	if got.Events != nil {
+		t.Fatalf("events returned without expand: %+v", got.Events)
+	}
+
+	got, err = f.svc.Get(ctx, 7, id, []string{dto.ExpandEvents})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Events) != 2 {
+		t.Fatalf("events = %+v, want created and failed", got.Events)
+	}
+	if e := got.Events[0]; e.Type != "created" || e.ToStatus != "pending" {
+		t.Errorf("first event = %+v", e)
+	}
+	if e := got.Events[1]; e.Type != "status_changed" || e.FromStatus != "pending" || e.ToStatus != "failed" || e.FailureCode != FailureForceFailed {
+		t.Errorf("second event = %+v", e)
+	}
+
+	if _, err := f.svc.Get(ctx, 7, id, []string{"recipient"}); apperr.KindOf(err) != apperr.KindValidation {
+		t.Fatalf("unknown expand: got %v, want a validation error", err)
+	}
+}
+
+func TestRetryFailed(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		failure  string
+		attempts int   // before the retry
+		balance  int64 // kobo; the payout is 15025
+		merchant int
+		wantKind apperr.Kind
+		wantCode string
+	}{
+		{name: "retryable", status: "failed", failure: FailureProviderTimeout, attempts: 1, balance: 15025, merchant: 7},
+		{name: "second retry", status: "failed", failure: FailureInsufficientFunds, attempts: 2, balance: 15025, merchant: 7},
+		{name: "limit reached", status: "failed", failure: FailureProviderError, attempts: 3, balance: 15025, merchant: 7, wantKind: apperr.KindConflict, wantCode: "retry_limit_reached"},
+		{name: "permanent failure", status: "failed", failure: FailureInvalidAccount, attempts: 1, balance: 15025, merchant: 7, wantKind: apperr.KindConflict, wantCode: "failure_not_retryable"},
+		{name: "unknown failure", status: "failed", failure: FailureUnknown, attempts: 1, balance: 15025, merchant: 7, wantKind: apperr.KindConflict, wantCode: "failure_not_retryable"},
+		{name: "not failed", status: "processing", attempts: 1, balance: 15025, merchant: 7, wantKind: apperr.KindConflict, wantCode: "payout_not_failed"},
+		{name: "balance too low", status: "failed", failure: FailureInsufficientFunds, attempts: 1, balance: 15024, merchant: 7, wantKind: apperr.KindInsufficientFunds, wantCode: "insufficient_funds"},
+		{name: "other merchant", status: "failed", failure: FailureProviderTimeout, attempts: 1, balance: 15025, merchant: 8, wantKind: apperr.KindNotFound, wantCode: "payout_not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newPayoutFixture(t)
+			ctx := context.Background()
+			id := f.createPayout(t, "processing")
+			for attempt := 1; attempt < tt.attempts; attempt++ {
+				f.failPayout(t, id, tt.failure)
+				if err := f.store.UpdateStatus(ctx, id, "failed", "processing", repositories.StatusUpdate{NewAttempt: true}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.status == "failed" {
+				f.failPayout(t, id, tt.failure)
+			}
+			f.merchants.SetBalance(7, "NGN", tt.balance)
+
+			resp, err := f.svc.RetryFailed(ctx, tt.merchant, id)
+			if tt.wantCode != "" {
+				var appErr *apperr.Error
+				if apperr.KindOf(err) != tt.wantKind || !errors.As(err, &appErr) || appErr.Code != tt.wantCode {
+					t.Fatalf("got %v, want a %s error", err, tt.wantCode)
+				}
+				p, _ := f.store.GetByID(ctx, id)
+				if p.Status != tt.status || p.Attempts != tt.attempts {
+					t.Fatalf("stored status = %q after %d attempts, want it unchanged", p.Status, p.Attempts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != "pending" || resp.Attempts != tt.attempts+1 || resp.Failure != nil {
+				t.Fatalf("got %+v, want a pending attempt %d", resp, tt.attempts+1)
+			}
+			events, _ := f.store.ListEvents(ctx, id)
+			if last := events[len(events)-1]; last.FromStatus != "failed" || last.ToStatus != "pending" || last.Attempt != tt.attempts+1 {
+				t.Fatalf("last event = %+v", last)
+			}
+		})
+	}
+}
+
+// failPayout fails a processing payout with the failure code.
+func (f *payoutFixture) failPayout(t *testing.T, id int, code string) {
+	t.Helper()
+	if err := f.store.UpdateStatus(context.Background(), id, "processing", "failed", repositories.StatusUpdate{FailureCode: code}); err != nil {
+		t.Fatal(err)
+	}
+}
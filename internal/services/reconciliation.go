@@ -0,0 +1,257 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+	"github.com/kodra-pay/payout-service/internal/statements"
+)
+
+// PayoutFinder is the payout lookup reconciliation matches statement lines with.
+// repositories.PayoutStore implements it.
+type PayoutFinder interface {
+	// GetByProviderReference returns the latest payout with the provider's reference, or
+	// nil, nil.
+	GetByProviderReference(ctx context.Context, providerReference string) (*models.Payout, error)
+}
+
+type ReconciliationService struct {
+	repo          repositories.ReconciliationStore
+	payouts       PayoutFinder
+	dateTolerance time.Duration
+}
+
+func NewReconciliationService(repo repositories.ReconciliationStore, payouts PayoutFinder, dateToleranceDays int) *ReconciliationService {
+	return &ReconciliationService{
+		repo:          repo,
+		payouts:       payouts,
+		dateTolerance: time.Duration(dateToleranceDays) * 24 * time.Hour,
+	}
+}
+
+// Import parses a bank statement and matches every debit line against payouts by
+// provider reference, amount and booking date. Credit lines are ignored.
+func (s *ReconciliationService) Import(ctx context.Context, fileName string, format statements.Format, r io.Reader) (dto.ReconciliationRunResponse, error) {
+	st, err := statements.Parse(format, r)
+	if err != nil {
+		return dto.ReconciliationRunResponse{}, apperr.Validation("invalid statement file", apperr.Field("file", err.Error()))
+	}
+
+	run := &models.ReconciliationRun{
+		Format:      string(st.Format),
+		FileName:    fileName,
+		StatementID: st.ID,
+		AccountID:   st.AccountID,
+	}
+
+	var items []*models.ReconciliationItem
+	seen := map[int]bool{}
+	for _, line := range st.Lines {
+		if !line.Debit {
+			continue
+		}
+		item, err := s.match(ctx, line, seen)
+		if err != nil {
+			return dto.ReconciliationRunResponse{}, apperr.Internal(fmt.Sprintf("failed to match statement line %q", line.Reference), err)
+		}
+		items = append(items, item)
+
+		run.TotalLines++
+		switch item.Result {
+		case models.ReconMatched:
+			run.MatchedCount++
+		case models.ReconMismatched:
+			run.MismatchedCount++
+		default:
+			run.UnmatchedCount++
+		}
+	}
+
+	if err := s.repo.CreateRun(ctx, run, items); err != nil {
+		return dto.ReconciliationRunResponse{}, apperr.Internal("failed to save reconciliation run", err)
+	}
+	return toRunResponse(run, items), nil
+}
+
+func (s *ReconciliationService) match(ctx context.Context, line statements.Line, seen map[int]bool) (*models.ReconciliationItem, error) {
+	item := &models.ReconciliationItem{
+		LineReference: line.Reference,
+		BankReference: line.BankReference,
+		LineAmount:    line.Amount,
+		LineCurrency:  line.Currency,
+		BookingDate:   line.BookingDate,
+		Description:   line.Description,
+		Result:        models.ReconUnmatched,
+	}
+
+	var p *models.Payout
+	for _, ref := range []string{line.Reference, line.BankReference} {
+		if ref == "" {
+			continue
+		}
+		found, err := s.payouts.GetByProviderReference(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			p = found
+			break
+		}
+	}
+	if p == nil {
+		if line.Reference == "" && line.BankReference == "" {
+			item.Reason = "statement line has no reference"
+		} else {
+			item.Reason = "no payout found for provider reference"
+		}
+		return item, nil
+	}
+
+	payoutID := p.ID
+	item.PayoutID = &payoutID
+
+	var reasons []string
+	if line.Amount != p.Amount {
+		reasons = append(reasons, fmt.Sprintf("amount %.2f does not match payout amount %.2f", float64(line.Amount)/100, float64(p.Amount)/100))
+	}
+	if line.Currency != "" && !strings.EqualFold(line.Currency, p.Currency) {
+		reasons = append(reasons, fmt.Sprintf("currency %s does not match payout currency %s", line.Currency, p.Currency))
+	}
+	if !isFinalStatus(p.Status) {
+		reasons = append(reasons, fmt.Sprintf("payout status is %s", p.Status))
+	}
+	// Compare against when the payout completed: updated_at moves with every later write.
+	if p.CompletedAt != nil {
+		if drift := absDuration(line.BookingDate.Sub(truncateDay(*p.CompletedAt))); drift > s.dateTolerance {
+			reasons = append(reasons, fmt.Sprintf("booking date %s is outside the tolerance of payout date %s",
+				line.BookingDate.Format("2006-01-02"), p.CompletedAt.Format("2006-01-02")))
+		}
+	}
+	if seen[p.ID] {
+		reasons = append(reasons, "payout appears more than once in this statement")
+	} else {
+		already, err := s.repo.IsPayoutMatched(ctx, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		if already {
+			reasons = append(reasons, "payout was already matched by an earlier run")
+		}
+	}
+	seen[p.ID] = true
+
+	if len(reasons) > 0 {
+		item.Result = models.ReconMismatched
+		item.Reason = strings.Join(reasons, "; ")
+		return item, nil
+	}
+	item.Result = models.ReconMatched
+	return item, nil
+}
+
+func (s *ReconciliationService) GetRun(ctx context.Context, id int) (dto.ReconciliationRunResponse, error) {
+	run, err := s.repo.GetRun(ctx, id)
+	if err != nil {
+		return dto.ReconciliationRunResponse{}, apperr.Internal("failed to load reconciliation run", err)
+	}
+	if run == nil {
+		return dto.ReconciliationRunResponse{}, apperr.NotFound("reconciliation_run_not_found", "reconciliation run not found")
+	}
+	items, err := s.repo.ListItems(ctx, id)
+	if err != nil {
+		return dto.ReconciliationRunResponse{}, apperr.Internal("failed to load reconciliation items", err)
+	}
+	return toRunResponse(run, items), nil
+}
+
+// ListExceptions returns unmatched and mismatched lines, most recent first.
+func (s *ReconciliationService) ListExceptions(ctx context.Context, runID int, includeResolved bool) ([]dto.ReconciliationItemResponse, error) {
+	items, err := s.repo.ListExceptions(ctx, runID, !includeResolved, 200)
+	if err != nil {
+		return nil, apperr.Internal("failed to list reconciliation exceptions", err)
+	}
+	resp := make([]dto.ReconciliationItemResponse, 0, len(items))
+	for _, it := range items {
+		resp = append(resp, toItemResponse(it))
+	}
+	return resp, nil
+}
+
+func (s *ReconciliationService) ResolveException(ctx context.Context, id int, req dto.ResolveReconciliationExceptionRequest) (dto.ReconciliationItemResponse, error) {
+	if strings.TrimSpace(req.ResolvedBy) == "" {
+		return dto.ReconciliationItemResponse{}, apperr.Validation("resolved_by is required", apperr.Field("resolved_by", "is required"))
+	}
+	item, err := s.repo.GetItem(ctx, id)
+	if err != nil {
+		return dto.ReconciliationItemResponse{}, apperr.Internal("failed to load reconciliation exception", err)
+	}
+	if item == nil || item.Result == models.ReconMatched {
+		return dto.ReconciliationItemResponse{}, apperr.NotFound("reconciliation_exception_not_found", "reconciliation exception not found")
+	}
+	if err := s.repo.ResolveItem(ctx, id, req.ResolvedBy, req.Note); err != nil {
+		return dto.ReconciliationItemResponse{}, apperr.Internal("failed to resolve reconciliation exception", err)
+	}
+	item, err = s.repo.GetItem(ctx, id)
+	if err != nil || item == nil {
+		return dto.ReconciliationItemResponse{}, apperr.Internal("reconciliation exception not found after update", err)
+	}
+	return toItemResponse(item), nil
+}
+
+func toRunResponse(run *models.ReconciliationRun, items []*models.ReconciliationItem) dto.ReconciliationRunResponse {
+	resp := dto.ReconciliationRunResponse{
+		ID:              run.ID,
+		Format:          run.Format,
+		FileName:        run.FileName,
+		StatementID:     run.StatementID,
+		AccountID:       run.AccountID,
+		TotalLines:      run.TotalLines,
+		MatchedCount:    run.MatchedCount,
+		UnmatchedCount:  run.UnmatchedCount,
+		MismatchedCount: run.MismatchedCount,
+		CreatedAt:       run.CreatedAt,
+		CompletedAt:     run.CompletedAt,
+	}
+	for _, it := range items {
+		resp.Items = append(resp.Items, toItemResponse(it))
+	}
+	return resp
+}
+
+func toItemResponse(it *models.ReconciliationItem) dto.ReconciliationItemResponse {
+	return dto.ReconciliationItemResponse{
+		ID:             it.ID,
+		RunID:          it.RunID,
+		PayoutID:       it.PayoutID,
+		LineReference:  it.LineReference,
+		BankReference:  it.BankReference,
+		Amount:         float64(it.LineAmount) / 100,
+		Currency:       it.LineCurrency,
+		BookingDate:    it.BookingDate.Format("2006-01-02"),
+		Description:    it.Description,
+		Result:         it.Result,
+		Reason:         it.Reason,
+		Resolved:       it.Resolved,
+		ResolvedBy:     it.ResolvedBy,
+		ResolutionNote: it.ResolutionNote,
+		ResolvedAt:     it.ResolvedAt,
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+	"github.com/kodra-pay/payout-service/internal/statements"
+)
+
+// fakeReconStore records the runs it is given; matched holds payouts matched by earlier runs.
+type fakeReconStore struct {
+	matched map[int]bool
+	runs    []*models.ReconciliationRun
+	items   [][]*models.ReconciliationItem
+}
+
+func (s *fakeReconStore) CreateRun(_ context.Context, run *models.ReconciliationRun, items []*models.ReconciliationItem) error {
+	run.ID = len(s.runs) + 1
+	s.runs = append(s.runs, run)
+	s.items = append(s.items, items)
+	return nil
+}
+
+func (s *fakeReconStore) GetRun(context.Context, int) (*models.ReconciliationRun, error) {
+	return nil, nil
+}
+
+func (s *fakeReconStore) ListItems(context.Context, int) ([]*models.ReconciliationItem, error) {
+	return nil, nil
+}
+
+func (s *fakeReconStore) ListExceptions(context.Context, int, bool, int) ([]*models.ReconciliationItem, error) {
+	return nil, nil
+}
+
+func (s *fakeReconStore) GetItem(context.Context, int) (*models.ReconciliationItem, error) {
+	return nil, nil
+}
+
+func (s *fakeReconStore) ResolveItem(context.Context, int, string, string) error {
+	return nil
+}
+
+func (s *fakeReconStore) IsPayoutMatched(_ context.Context, payoutID int) (bool, error) {
+	return s.matched[payoutID], nil
+}
+
+type reconFixture struct {
+	svc     *ReconciliationService
+	recon   *fakeReconStore
+	payouts *repositories.MemoryPayoutStore
+}
+
+// newReconFixture allows booking dates three days either side of completion.
+func newReconFixture(t *testing.T) *reconFixture {
+	f := &reconFixture{
+		recon:   &fakeReconStore{matched: map[int]bool{}},
+		payouts: repositories.NewMemoryPayoutStore(testKeyring(t)),
+	}
+	f.svc = NewReconciliationService(f.recon, f.payouts, 3)
+	return f
+}
+
+// payout stores a payout of 150.25 NGN in status with the provider reference.
+func (f *reconFixture) payout(t *testing.T, status, providerReference string) *models.Payout {
+	t.Helper()
+	ctx := context.Background()
+	p := &models.Payout{MerchantID: 7, Reference: providerReference, Amount: 15025, Currency: "NGN", RecipientName: "Ada Obi", RecipientAccount: "0123456789", RecipientBank: "058", Status: "pending"}
+	if err := f.payouts.Create(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.payouts.UpdateStatus(ctx, p.ID, "pending", status, repositories.StatusUpdate{ProviderReference: providerReference}); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReconciliationMatch(t *testing.T) {
+	f := newReconFixture(t)
+	completed := f.payout(t, "completed", "PRV-1")
+	processed := f.payout(t, "processed", "PRV-2")
+	processing := f.payout(t, "processing", "PRV-3")
+	earlier := f.payout(t, "completed", "PRV-4")
+	f.recon.matched[earlier.ID] = true
+
+	today := truncateDay(time.Now())
+	line := func(reference string, amount int64, currency string, booked time.Time) statements.Line {
+		return statements.Line{Reference: reference, Amount: amount, Currency: currency, BookingDate: booked, Debit: true}
+	}
+	tests := []struct {
+		name       string
+		line       statements.Line
+		wantResult string
+		wantPayout int
+		wantReason string
+	}{
+		{name: "exact", line: line("PRV-1", 15025, "NGN", today), wantResult: models.ReconMatched, wantPayout: completed.ID},
+		{name: "processed is final", line: line("PRV-2", 15025, "NGN", today), wantResult: models.ReconMatched, wantPayout: processed.ID},
+		{name: "no currency", line: line("PRV-1", 15025, "", today), wantResult: models.ReconMatched, wantPayout: completed.ID},
+		{name: "lower case currency", line: line("PRV-1", 15025, "ngn", today), wantResult: models.ReconMatched, wantPayout: completed.ID},
+		{
+			name:       "bank reference",
+			line:       statements.Line{Reference: "STMT-9", BankReference: "PRV-1", Amount: 15025, BookingDate: today, Debit: true},
+			wantResult: models.ReconMatched, wantPayout: completed.ID,
+		},
+		{name: "at the date tolerance", line: line("PRV-1", 15025, "NGN", today.AddDate(0, 0, 3)), wantResult: models.ReconMatched, wantPayout: completed.ID},
+		{name: "before completion within tolerance", line: line("PRV-1", 15025, "NGN", today.AddDate(0, 0, -3)), wantResult: models.ReconMatched, wantPayout: completed.ID},
+		{
+			name: "outside the date tolerance", line: line("PRV-1", 15025, "NGN", today.AddDate(0, 0, 4)),
+			wantResult: models.ReconMismatched, wantPayout: completed.ID, wantReason: "outside the tolerance",
+		},
+		{
+			name: "amount", line: line("PRV-1", 15000, "NGN", today),
+			wantResult: models.ReconMismatched, wantPayout: completed.ID, wantReason: "amount 150.00 does not match payout amount 150.25",
+		},
+		{
+			name: "currency", line: line("PRV-1", 15025, "USD", today),
+			wantResult: models.ReconMismatched, wantPayout: completed.ID, wantReason: "currency USD does not match payout currency NGN",
+		},
+		{
+			name: "not final", line: line("PRV-3", 15025, "NGN", today),
+			wantResult: models.ReconMismatched, wantPayout: processing.ID, wantReason: "payout status is processing",
+		},
+		{
+			name: "matched by an earlier run", line: line("PRV-4", 15025, "NGN", today),
+			wantResult: models.ReconMismatched, wantPayout: earlier.ID, wantReason: "already matched by an earlier run",
+		},
+		{name: "unknown reference", line: line("PRV-404", 15025, "NGN", today), wantResult: models.ReconUnmatched, wantReason: "no payout found"},
+		{name: "no reference", line: line("", 15025, "NGN", today), wantResult: models.ReconUnmatched, wantReason: "no reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, err := f.svc.match(context.Background(), tt.line, map[int]bool{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.Result != tt.wantResult || !strings.Contains(item.Reason, tt.wantReason) {
+				t.Fatalf("got %s (%q), want %s with %q", item.Result, item.Reason, tt.wantResult, tt.wantReason)
+			}
+			if tt.wantResult == models.ReconMatched && item.Reason != "" {
+				t.Fatalf("matched with reason %q", item.Reason)
+			}
+			switch {
+			case tt.wantPayout == 0 && item.PayoutID != nil:
+				t.Fatalf("payout = %d, want none", *item.PayoutID)
+			case tt.wantPayout != 0 && (item.PayoutID == nil || *item.PayoutID != tt.wantPayout):
+				t.Fatalf("payout = %v, want %d", item.PayoutID, tt.wantPayout)
+			}
+		})
+	}
+}
+
+func TestReconciliationImportFlagsDuplicateLines(t *testing.T) {
+	f := newReconFixture(t)
+	f.payout(t, "completed", "PRV-1")
+	today := time.Now().UTC().Format("2006-01-02")
+	data := "booking date,reference,amount,currency\n" +
+		today + ",PRV-1,-150.25,NGN\n" +
+		today + ",PRV-1,-150.25,NGN\n" +
+		today + ",PRV-404,-10.00,NGN\n" +
+		today + ",DEP-1,500.00,NGN\n" // credits are ignored
+
+	run, err := f.svc.Import(context.Background(), "march.csv", statements.FormatCSV, strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.TotalLines != 3 || run.MatchedCount != 1 || run.MismatchedCount != 1 || run.UnmatchedCount != 1 {
+		t.Fatalf("run = %+v, want 3 lines: 1 matched, 1 mismatched, 1 unmatched", run)
+	}
+	if len(f.recon.runs) != 1 || len(f.recon.items[0]) != 3 {
+		t.Fatalf("stored %d runs", len(f.recon.runs))
+	}
+	if dup := f.recon.items[0][1]; dup.Result != models.ReconMismatched || !strings.Contains(dup.Reason, "more than once in this statement") {
+		t.Fatalf("second PRV-1 line = %s (%q), want flagged as a duplicate", dup.Result, dup.Reason)
+	}
+}
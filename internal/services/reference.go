@@ -0,0 +1,30 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"regexp"
+	"strings"
+)
+
+// referencePattern limits merchant references to characters that are safe in a URL path
+// segment, so every payout can be fetched with GET /payouts/by-reference/:reference.
+var referencePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.:-]{0,63}$`)
+
+const referenceRule = "must be 1 to 64 letters, digits, '_', '.', ':' or '-', starting with a letter or digit"
+
+// generatedReferenceAttempts bounds how often Create draws a new reference when a generated
+// one is already taken by the merchant.
+const generatedReferenceAttempts = 3
+
+var referenceEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newReference returns a reference for a payout created without one: "po_" followed by 80
+// random bits, e.g. "po_k5v3n2xq7h4mbw6r".
+func newReference() string {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand doesn't fail on supported platforms
+	}
+	return "po_" + strings.ToLower(referenceEncoding.EncodeToString(b))
+}
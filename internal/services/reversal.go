@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/clients"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// ReturnFeePolicy says what happens to the fee of a payout that is returned or reversed.
+type ReturnFeePolicy string
+
+const (
+	// ReturnFeesRefunded credits the fee, which completion debits with the amount, back too.
+	ReturnFeesRefunded ReturnFeePolicy = "refund"
+	// ReturnFeesKept only credits the amount back.
+	ReturnFeesKept ReturnFeePolicy = "keep"
+)
+
+// ParseReturnFeePolicy parses the RETURN_FEE_POLICY setting.
+func ParseReturnFeePolicy(s string) (ReturnFeePolicy, error) {
+	switch p := ReturnFeePolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case ReturnFeesRefunded, ReturnFeesKept:
+		return p, nil
+	}
+	return "", fmt.Errorf("return fee policy %q: must be refund or keep", s)
+}
+
+// Return codes say why a completed payout came back. They are recorded as its failure code.
+const (
+	ReturnAccountClosed    = "account_closed"
+	ReturnInvalidAccount   = FailureInvalidAccount
+	ReturnWrongBeneficiary = "wrong_beneficiary"
+	// ReturnRecalled means the payout was recalled on the merchant's or an operator's
+	// request.
+	ReturnRecalled = "recalled"
+	ReturnUnknown  = FailureUnknown
+)
+
+// ReturnCodes lists every return code.
+var ReturnCodes = []string{ReturnAccountClosed, ReturnInvalidAccount, ReturnWrongBeneficiary, ReturnRecalled, ReturnUnknown}
+
+// ReportReturn records that the recipient's bank sent a completed payout back, as reported
+// by the payout provider. The payout is found by the reference the provider gave it.
+// Reporting the same return again is harmless, so the provider can retry its webhook.
+func (s *PayoutService) ReportReturn(ctx context.Context, providerReference, code, reason string) (dto.PayoutResponse, error) {
+	providerReference = strings.TrimSpace(providerReference)
+	if providerReference == "" {
+		return dto.PayoutResponse{}, apperr.Validation("provider reference is required", apperr.Field("provider_reference", "is required"))
+	}
+	code, err := returnCode(code, ReturnUnknown)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	current, err := s.repo.GetByProviderReference(ctx, providerReference)
+	if err != nil {
+		return dto.PayoutResponse{}, apperr.Internal("failed to load payout", err)
+	}
+	if current == nil {
+		return dto.PayoutResponse{}, errPayoutNotFound()
+	}
+	slog.InfoContext(ctx, "payout returned by bank", slog.Int("payout_id", current.ID), slog.String("code", code))
+	return s.giveBack(ctx, current, "returned", code, reason)
+}
+
+// Reverse reverses a completed payout on an operator's behalf, e.g. when the bank confirmed
+// a return out of band. code defaults to ReturnRecalled.
+func (s *PayoutService) Reverse(ctx context.Context, id int, actor, code, reason string) (dto.PayoutResponse, error) {
+	code, err := returnCode(code, ReturnRecalled)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	current, err := s.load(ctx, id)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	slog.InfoContext(ctx, "reversing payout", slog.Int("payout_id", id), slog.String("actor", actor), slog.String("code", code), slog.String("reason", reason))
+	return s.giveBack(ctx, current, "reversed", code, reason)
+}
+
+func returnCode(code, fallback string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return fallback, nil
+	}
+	if !slices.Contains(ReturnCodes, code) {
+		return "", apperr.Validation("invalid return code", apperr.Field("code", "must be one of "+strings.Join(ReturnCodes, ", ")))
+	}
+	return code, nil
+}
+
+// giveBack moves a completed payout to status (returned or reversed) and gives the money
+// back: the merchant balance is re-credited, a compensating transaction recorded and the
+// merchant notified. The status changes first so concurrent reports can't credit twice; if
+// the credit fails the payout is put back to its completed status and the caller can try
+// again, which merchant-service dedupes by the credit reference. The transaction and the
+// notification are best effort, as the completion transaction is.
+func (s *PayoutService) giveBack(ctx context.Context, current *models.Payout, status, code, reason string) (dto.PayoutResponse, error) {
+	switch {
+	case current.Status == status:
+		return payoutResponse(current), nil
+	case isReturnedStatus(current.Status):
+		return dto.PayoutResponse{}, apperr.Conflict("payout_returned", fmt.Sprintf("payout is already %s", current.Status))
+	case !isFinalStatus(current.Status):
+		return dto.PayoutResponse{}, apperr.Conflict("payout_not_completed", fmt.Sprintf("only completed payouts can be returned, payout is %s", current.Status))
+	}
+
+	reason = strings.TrimSpace(reason)
+	u := repositories.StatusUpdate{FailureCode: code, FailureMessage: reason}
+	if err := s.repo.UpdateStatus(ctx, current.ID, current.Status, status, u); err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrStatusConflict):
+			return dto.PayoutResponse{}, &apperr.Error{Kind: apperr.KindConflict, Code: "status_conflict", Message: "payout status was changed by another request, retry", Err: err}
+		case errors.Is(err, repositories.ErrPayoutNotFound):
+			return dto.PayoutResponse{}, errPayoutNotFound()
+		}
+		return dto.PayoutResponse{}, apperr.Internal("failed to update payout status", err)
+	}
+	observeTransition(current, current.Status, status)
+
+	amount := current.Amount
+	if s.returnFees == ReturnFeesRefunded {
+		amount += current.Fee
+	}
+	credit := clients.BalanceCredit{
+		MerchantID: current.MerchantID,
+		Amount:     amount,
+		Currency:   current.Currency,
+		Reference:  fmt.Sprintf("payout-%d-return", current.ID),
+	}
+	if err := s.merchants.CreditBalance(ctx, credit); err != nil {
+		if rerr := s.repo.UpdateStatus(context.WithoutCancel(ctx), current.ID, status, current.Status, repositories.StatusUpdate{}); rerr != nil {
+			// The payout keeps the new status without the credit, and reporting the return
+			// again is a no-op, so an operator has to credit the merchant by hand.
+			slog.ErrorContext(ctx, "failed to restore payout status after the re-credit failed", slog.Int("payout_id", current.ID), slog.String("status", status), slog.Any("error", rerr))
+		} else {
+			observeTransition(current, status, current.Status)
+		}
+		return dto.PayoutResponse{}, apperr.Unavailable("failed to re-credit merchant balance", err)
+	}
+
+	err := s.transactions.RecordTransaction(ctx, clients.TransactionRequest{
+		Reference:     credit.Reference,
+		MerchantID:    current.MerchantID,
+		Amount:        amount,
+		Currency:      current.Currency,
+		PaymentMethod: "payout",
+		Status:        "payout_" + status,
+		Description:   fmt.Sprintf("Payout %s to %s (%s) %s: %s", current.Reference, current.RecipientName, current.RecipientBank, status, code),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to record compensating transaction", slog.Int("payout_id", current.ID), slog.Any("error", err))
+	}
+
+	message := fmt.Sprintf("Payout %s was %s (%s); %s %.2f was credited back to your balance.", current.Reference, status, code, current.Currency, float64(amount)/100)
+	err = s.notifications.NotifyMerchant(ctx, clients.Notification{
+		MerchantID:      current.MerchantID,
+		Event:           "payout." + status,
+		PayoutID:        current.ID,
+		PayoutReference: current.Reference,
+		Amount:          amount,
+		Currency:        current.Currency,
+		Message:         message,
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to notify merchant of returned payout", slog.Int("payout_id", current.ID), slog.Any("error", err))
+	}
+
+	updated, err := s.load(ctx, current.ID)
+	if err != nil {
+		return dto.PayoutResponse{}, err
+	}
+	return payoutResponse(updated), nil
+}
+
+// isReturnedStatus reports whether a completed payout came back.
+func isReturnedStatus(status string) bool {
+	switch strings.ToLower(status) {
+	case "returned", "reversed":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// completedPayout stores a completed payout of 150.25 NGN with a 1.00 NGN fee for merchant
+// 7, paid out under provider reference PRV-1.
+func (f *payoutFixture) completedPayout(t *testing.T) *models.Payout {
+	t.Helper()
+	ctx := context.Background()
+	p := &models.Payout{
+		MerchantID:       7,
+		Reference:        "INV-1",
+		Amount:           15025,
+		Fee:              100,
+		Currency:         "NGN",
+		RecipientName:    "Ada Obi",
+		RecipientAccount: "0123456789",
+		RecipientBank:    "058",
+		Status:           "pending",
+	}
+	if err := f.store.Create(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.store.UpdateStatus(ctx, p.ID, "pending", "completed", repositories.StatusUpdate{ProviderReference: "PRV-1"}); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReportReturnCreditsMerchant(t *testing.T) {
+	tests := []struct {
+		policy     ReturnFeePolicy
+		wantCredit int64
+	}{
+		{ReturnFeesKept, 15025},
+		{ReturnFeesRefunded, 15125},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			f := newPayoutFixture(t)
+			f.svc.returnFees = tt.policy
+			ctx := context.Background()
+			p := f.completedPayout(t)
+
+			resp, err := f.svc.ReportReturn(ctx, "PRV-1", "Account_Closed", "beneficiary account closed")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != "returned" || resp.Failure == nil || resp.Failure.Code != ReturnAccountClosed || resp.Failure.Retryable {
+				t.Fatalf("got %+v (failure %+v), want returned with account_closed", resp, resp.Failure)
+			}
+
+			credits := f.merchants.Credits()
+			if c, ok := credits["payout-1-return"]; len(credits) != 1 || !ok || c.Amount != tt.wantCredit || c.MerchantID != 7 {
+				t.Fatalf("credits = %+v, want %d for payout-1-return", credits, tt.wantCredit)
+			}
+			txs := f.transactions.Transactions()
+			if len(txs) != 1 || txs[0].Reference != "payout-1-return" || txs[0].Status != "payout_returned" || txs[0].Amount != tt.wantCredit {
+				t.Fatalf("transactions = %+v", txs)
+			}
+			notes := f.notifications.Notifications()
+			if len(notes) != 1 || notes[0].Event != "payout.returned" || notes[0].PayoutID != p.ID || notes[0].MerchantID != 7 {
+				t.Fatalf("notifications = %+v", notes)
+			}
+
+			// The provider retrying its webhook changes nothing.
+			if _, err := f.svc.ReportReturn(ctx, "PRV-1", ReturnAccountClosed, ""); err != nil {
+				t.Fatalf("replayed webhook: %v", err)
+			}
+			if n := len(f.merchants.Credits()); n != 1 {
+				t.Fatalf("got %d credits after the replay, want 1", n)
+			}
+		})
+	}
+}
+
+func TestReverseReturnsTheFeePerPolicy(t *testing.T) {
+	// 150.25 NGN priced at 1.00 NGN plus 0.5%: a fee of 175 kobo.
+	tests := []struct {
+		policy     ReturnFeePolicy
+		wantCredit int64
+	}{
+		{ReturnFeesKept, 15025},
+		{ReturnFeesRefunded, 15200},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			f := newPayoutFixture(t)
+			f.svc.fees = FeeSchedule{Flat: 100, BasisPoints: 50}
+			f.svc.returnFees = tt.policy
+			f.merchants.SetBalance(7, "NGN", 15200)
+			ctx := context.Background()
+
+			created, err := f.svc.Create(ctx, validPayoutRequest(7))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.Fee != 1.75 {
+				t.Fatalf("fee = %v, want 1.75", created.Fee)
+			}
+			if _, err := f.svc.UpdateStatus(ctx, created.ID, "completed", repositories.StatusUpdate{}); err != nil {
+				t.Fatal(err)
+			}
+			if d := f.merchants.Deductions(); len(d) != 1 || d[0].Amount != 15200 {
+				t.Fatalf("deductions = %+v, want one of 15200", d)
+			}
+
+			resp, err := f.svc.Reverse(ctx, created.ID, "ops@example.com", ReturnRecalled, "duplicate payout")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != "reversed" {
+				t.Fatalf("status = %q, want reversed", resp.Status)
+			}
+			credit := fmt.Sprintf("payout-%d-return", created.ID)
+			if c, ok := f.merchants.Credits()[credit]; !ok || c.Amount != tt.wantCredit {
+				t.Fatalf("credits = %+v, want %d for %s", f.merchants.Credits(), tt.wantCredit, credit)
+			}
+			txs := f.transactions.Transactions()
+			if last := txs[len(txs)-1]; last.Reference != credit || last.Amount != tt.wantCredit {
+				t.Fatalf("compensating transaction = %+v, want %d", last, tt.wantCredit)
+			}
+		})
+	}
+}
+
+func TestReturnsOnlyApplyToCompletedPayouts(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	id := f.createPayout(t, "processing")
+	if err := f.store.UpdateStatus(ctx, id, "processing", "processing", repositories.StatusUpdate{ProviderReference: "PRV-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, e := f.svc.ReportReturn(ctx, "PRV-2", ReturnAccountClosed, ""); !isConflict(e, "payout_not_completed") {
+		t.Fatalf("processing payout: got %v", e)
+	}
+	if _, e := f.svc.ReportReturn(ctx, "PRV-404", ReturnAccountClosed, ""); apperr.KindOf(e) != apperr.KindNotFound {
+		t.Fatalf("unknown provider reference: got %v, want a not-found error", e)
+	}
+	if _, e := f.svc.ReportReturn(ctx, "PRV-2", "lost_in_post", ""); apperr.KindOf(e) != apperr.KindValidation {
+		t.Fatalf("unknown code: got %v, want a validation error", e)
+	}
+
+	p := f.completedPayout(t)
+	if _, e := f.svc.Reverse(ctx, p.ID, "ops@example.com", "", "duplicate payout"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := f.svc.ReportReturn(ctx, "PRV-1", ReturnAccountClosed, ""); !isConflict(e, "payout_returned") {
+		t.Fatalf("reversed payout: got %v", e)
+	}
+	if _, e := f.svc.UpdateStatus(ctx, p.ID, "completed", repositories.StatusUpdate{}); !isConflict(e, "payout_returned") {
+		t.Fatalf("status update of a reversed payout: got %v", e)
+	}
+	if _, e := f.svc.ForceFail(ctx, p.ID, "ops@example.com", "oops"); !isConflict(e, "payout_returned") {
+		t.Fatalf("force-failing a reversed payout: got %v", e)
+	}
+}
+
+func TestReverseRestoresPayoutWhenCreditFails(t *testing.T) {
+	f := newPayoutFixture(t)
+	ctx := context.Background()
+	p := f.completedPayout(t)
+	f.merchants.CreditErr = errors.New("merchant-service returned 503")
+
+	if _, err := f.svc.Reverse(ctx, p.ID, "ops@example.com", ReturnWrongBeneficiary, "wrong account"); apperr.KindOf(err) != apperr.KindUnavailable {
+		t.Fatalf("got %v, want an unavailable error", err)
+	}
+	stored, _ := f.store.GetByID(ctx, p.ID)
+	if stored.Status != "completed" || stored.FailureCode != "" {
+		t.Fatalf("stored payout = %+v, want it completed again", stored)
+	}
+	if n := len(f.transactions.Transactions()) + len(f.notifications.Notifications()); n != 0 {
+		t.Fatalf("got %d transactions and notifications, want none", n)
+	}
+
+	f.merchants.CreditErr = nil
+	resp, err := f.svc.Reverse(ctx, p.ID, "ops@example.com", ReturnWrongBeneficiary, "wrong account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "reversed" || resp.Failure == nil || resp.Failure.Code != ReturnWrongBeneficiary {
+		t.Fatalf("got %+v", resp)
+	}
+}
+
+// isConflict reports whether err is a conflict with the given code.
+func isConflict(err error, code string) bool {
+	var appErr *apperr.Error
+	return errors.As(err, &appErr) && appErr.Kind == apperr.KindConflict && appErr.Code == code
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/fieldcrypt"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// Search ranks the merchant's payouts against a free-text query, narrowed by the usual filters.
+func (s *PayoutService) Search(ctx context.Context, f repositories.PayoutFilter, q string, limit int) (dto.PayoutSearchResponse, error) {
+	q = strings.TrimSpace(q)
+	if utf8.RuneCountInString(q) < 2 {
+		return dto.PayoutSearchResponse{}, apperr.Validation("query too short", apperr.Field("q", "must be at least 2 characters"))
+	}
+	if limit <= 0 {
+		limit = s.pageSizeDefault
+	}
+	if limit > s.pageSizeMax {
+		limit = s.pageSizeMax
+	}
+
+	hits, err := s.repo.SearchPayouts(ctx, f, q, limit)
+	if err != nil {
+		return dto.PayoutSearchResponse{}, apperr.Internal("failed to search payouts", err)
+	}
+
+	resp := dto.PayoutSearchResponse{Data: make([]dto.PayoutSearchResult, 0, len(hits))}
+	for _, h := range hits {
+		p := h.Payout
+		resp.Data = append(resp.Data, dto.PayoutSearchResult{
+			PayoutResponse:   payoutResponse(p),
+			RecipientName:    p.RecipientName,
+			RecipientAccount: fieldcrypt.Mask(p.RecipientAccount),
+			RecipientBank:    p.RecipientBank,
+			Rank:             h.Rank,
+			Highlights:       h.Highlights,
+		})
+	}
+	return resp, nil
+}
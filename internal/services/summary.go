@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/kodra-pay/payout-service/internal/apperr"
+	"github.com/kodra-pay/payout-service/internal/dto"
+	"github.com/kodra-pay/payout-service/internal/models"
+	"github.com/kodra-pay/payout-service/internal/repositories"
+)
+
+// Summary aggregates the merchant's payouts matching the filter. Volumes are never summed
+// across currencies, so every breakdown is reported per currency. Success rate is the share
+// of completed payouts among those that reached a terminal state (completed or failed).
+func (s *PayoutService) Summary(ctx context.Context, f repositories.PayoutFilter, interval string) (dto.PayoutSummaryResponse, error) {
+	interval = strings.ToLower(interval)
+	if interval == "" {
+		interval = "day"
+	}
+	switch interval {
+	case "day", "week", "month":
+	default:
+		return dto.PayoutSummaryResponse{}, apperr.Validation("invalid interval", apperr.Field("interval", "must be one of day, week, month"))
+	}
+
+	rows, err := s.repo.SummarizePayouts(ctx, f, interval)
+	if err != nil {
+		return dto.PayoutSummaryResponse{}, apperr.Internal("failed to summarize payouts", err)
+	}
+
+	resp := dto.PayoutSummaryResponse{
+		MerchantID: f.MerchantID,
+		From:       f.CreatedFrom,
+		To:         f.CreatedTo,
+		Interval:   interval,
+		Currencies: []dto.PayoutCurrencySummary{},
+	}
+	byCurrency := map[string]*dto.PayoutCurrencySummary{}
+	for _, row := range rows {
+		cs, ok := byCurrency[row.Currency]
+		if !ok {
+			resp.Currencies = append(resp.Currencies, dto.PayoutCurrencySummary{
+				Currency: row.Currency,
+				ByStatus: []dto.PayoutSummaryBucket{},
+				ByBank:   []dto.PayoutSummaryBucket{},
+				ByPeriod: []dto.PayoutSummaryBucket{},
+			})
+			cs = &resp.Currencies[len(resp.Currencies)-1]
+			byCurrency[row.Currency] = cs
+		}
+
+		switch row.Dimension {
+		case models.SummaryStatus:
+			cs.ByStatus = append(cs.ByStatus, summaryBucket(row.Status, row))
+		case models.SummaryBank:
+			cs.ByBank = append(cs.ByBank, summaryBucket(row.Bank, row))
+		case models.SummaryPeriod:
+			cs.ByPeriod = append(cs.ByPeriod, summaryBucket(row.Period.Format("2006-01-02"), row))
+		default:
+			cs.PayoutSummaryBucket = summaryBucket(row.Currency, row)
+			cs.AvgTimeToCompleteSeconds = row.AvgCompletionSeconds
+			resp.TotalCount += row.Count
+		}
+	}
+	return resp, nil
+}
+
+func summaryBucket(key string, row models.PayoutSummaryRow) dto.PayoutSummaryBucket {
+	b := dto.PayoutSummaryBucket{
+		Key:            key,
+		Count:          row.Count,
+		Volume:         float64(row.Volume) / 100,
+		CompletedCount: row.CompletedCount,
+		FailedCount:    row.FailedCount,
+	}
+	if terminal := row.CompletedCount + row.FailedCount; terminal > 0 {
+		b.SuccessRate = float64(row.CompletedCount) / float64(terminal)
+	}
+	return b
+}
@@ -0,0 +1,160 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// camtDocument covers the subset of ISO 20022 camt.053 (BankToCustomerStatement) we reconcile against.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID      string      `xml:"Id"`
+	IBAN    string      `xml:"Acct>Id>IBAN"`
+	OtherID string      `xml:"Acct>Id>Othr>Id"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	Amount          camtAmount        `xml:"Amt"`
+	CreditDebit     string            `xml:"CdtDbtInd"`
+	BookingDate     string            `xml:"BookgDt>Dt"`
+	BookingDateTime string            `xml:"BookgDt>DtTm"`
+	ValueDate       string            `xml:"ValDt>Dt"`
+	ServicerRef     string            `xml:"AcctSvcrRef"`
+	AdditionalInfo  string            `xml:"AddtlNtryInf"`
+	Transactions    []camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTransaction struct {
+	// Amount is the transaction's own amount, needed when an entry batches several.
+	// camt.053.001.02 only carries it as AmtDtls>TxAmt>Amt; later versions add Amt.
+	Amount      camtAmount `xml:"Amt"`
+	AmountDtls  camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	CreditDebit string     `xml:"CdtDbtInd"`
+	EndToEndID  string     `xml:"Refs>EndToEndId"`
+	TxID        string     `xml:"Refs>TxId"`
+	ServicerRef string     `xml:"Refs>AcctSvcrRef"`
+	Remittance  []string   `xml:"RmtInf>Ustrd"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+func parseCAMT053(data []byte) (*Statement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse camt.053 statement: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, fmt.Errorf("camt.053 document contains no statements")
+	}
+
+	st := &Statement{ID: doc.Statements[0].ID}
+	for _, s := range doc.Statements {
+		if st.AccountID == "" {
+			st.AccountID = firstNonEmpty(s.IBAN, s.OtherID)
+		}
+		for i, e := range s.Entries {
+			amount, err := parseAmount(e.Amount.Value)
+			if err != nil {
+				return nil, fmt.Errorf("camt.053 statement %s entry %d: %w", s.ID, i+1, err)
+			}
+			date, err := camtDate(firstNonEmpty(e.BookingDate, e.BookingDateTime, e.ValueDate))
+			if err != nil {
+				return nil, fmt.Errorf("camt.053 statement %s entry %d: %w", s.ID, i+1, err)
+			}
+
+			entry := Line{
+				Reference:     e.ServicerRef,
+				BankReference: e.ServicerRef,
+				Amount:        abs(amount),
+				Currency:      strings.ToUpper(e.Amount.Currency),
+				BookingDate:   date,
+				Debit:         strings.EqualFold(e.CreditDebit, "DBIT"),
+				Description:   strings.TrimSpace(e.AdditionalInfo),
+			}
+			if len(e.Transactions) == 0 {
+				st.Lines = append(st.Lines, entry)
+				continue
+			}
+			// A batched entry books several payments under one amount; each transaction
+			// becomes its own line so every payout in the batch can be matched.
+			for j, tx := range e.Transactions {
+				line, err := camtTransactionLine(entry, tx, len(e.Transactions) > 1)
+				if err != nil {
+					return nil, fmt.Errorf("camt.053 statement %s entry %d transaction %d: %w", s.ID, i+1, j+1, err)
+				}
+				st.Lines = append(st.Lines, line)
+			}
+		}
+	}
+	return st, nil
+}
+
+// camtTransactionLine refines the entry's line with one of its transaction details. A
+// transaction in a batch must carry its own amount; a lone one may fall back to the entry's.
+func camtTransactionLine(entry Line, tx camtTransaction, batched bool) (Line, error) {
+	line := entry
+	// Prefer the end-to-end id we sent with the payment over the bank's own reference.
+	if tx.EndToEndID != "" && !strings.EqualFold(tx.EndToEndID, "NOTPROVIDED") {
+		line.Reference = tx.EndToEndID
+	} else if tx.TxID != "" {
+		line.Reference = tx.TxID
+	}
+	if line.BankReference == "" || batched && tx.ServicerRef != "" {
+		line.BankReference = tx.ServicerRef
+	}
+	if remittance := strings.TrimSpace(strings.Join(tx.Remittance, " ")); line.Description == "" || batched && remittance != "" {
+		line.Description = remittance
+	}
+
+	amt := tx.Amount
+	if strings.TrimSpace(amt.Value) == "" {
+		amt = tx.AmountDtls
+	}
+	if strings.TrimSpace(amt.Value) == "" {
+		if batched {
+			return Line{}, fmt.Errorf("batched transaction has no amount")
+		}
+		return line, nil
+	}
+	amount, err := parseAmount(amt.Value)
+	if err != nil {
+		return Line{}, err
+	}
+	line.Amount = abs(amount)
+	if amt.Currency != "" {
+		line.Currency = strings.ToUpper(amt.Currency)
+	}
+	if tx.CreditDebit != "" {
+		line.Debit = strings.EqualFold(tx.CreditDebit, "DBIT")
+	}
+	return line, nil
+}
+
+func camtDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid booking date %q", s)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+)
+
+const camtExample = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT-2026-03-02</Id>
+      <Acct><Id><Othr><Id>0123456789</Id></Othr></Id></Acct>
+      <Ntry>
+        <Amt Ccy="NGN">1500.25</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><Dt>2026-03-02</Dt></BookgDt>
+        <AcctSvcrRef>BNK-1</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>PRV-1</EndToEndId></Refs>
+          <RmtInf><Ustrd>salary</Ustrd><Ustrd>march</Ustrd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="NGN">300.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><DtTm>2026-03-03T09:30:00+01:00</DtTm></BookgDt>
+        <AcctSvcrRef>BNK-BATCH</AcctSvcrRef>
+        <AddtlNtryInf>bulk transfer</AddtlNtryInf>
+        <NtryDtls>
+          <TxDtls>
+            <Refs><EndToEndId>PRV-2</EndToEndId><AcctSvcrRef>BNK-2</AcctSvcrRef></Refs>
+            <AmtDtls><TxAmt><Amt Ccy="NGN">100.00</Amt></TxAmt></AmtDtls>
+          </TxDtls>
+          <TxDtls>
+            <Amt Ccy="NGN">200.00</Amt>
+            <Refs><EndToEndId>NOTPROVIDED</EndToEndId><TxId>PRV-3</TxId></Refs>
+          </TxDtls>
+        </NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="NGN">50</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <ValDt><Dt>2026-03-04</Dt></ValDt>
+        <AcctSvcrRef>BNK-4</AcctSvcrRef>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+func TestParseCAMT053(t *testing.T) {
+	st, err := Parse("", strings.NewReader(camtExample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Format != FormatCAMT053 || st.ID != "STMT-2026-03-02" || st.AccountID != "0123456789" {
+		t.Fatalf("statement = %s %q %q", st.Format, st.ID, st.AccountID)
+	}
+	batchDate := st.Lines[1].BookingDate
+	if batchDate.Format("2006-01-02T15:04") != "2026-03-03T09:30" {
+		t.Fatalf("batch booking date = %v", batchDate)
+	}
+	assertLines(t, st.Lines, []Line{
+		{Reference: "PRV-1", BankReference: "BNK-1", Amount: 150025, Currency: "NGN", BookingDate: day("2026-03-02"), Debit: true, Description: "salary march"},
+		{Reference: "PRV-2", BankReference: "BNK-2", Amount: 10000, Currency: "NGN", BookingDate: batchDate, Debit: true, Description: "bulk transfer"},
+		{Reference: "PRV-3", BankReference: "BNK-BATCH", Amount: 20000, Currency: "NGN", BookingDate: batchDate, Debit: true, Description: "bulk transfer"},
+		{Reference: "BNK-4", BankReference: "BNK-4", Amount: 5000, Currency: "NGN", BookingDate: day("2026-03-04")},
+	})
+}
+
+func TestParseCAMT053RejectsMalformedStatements(t *testing.T) {
+	entry := func(amount, date, details string) string {
+		return `<Document><BkToCstmrStmt><Stmt><Id>S1</Id><Ntry><Amt Ccy="NGN">` + amount + `</Amt><CdtDbtInd>DBIT</CdtDbtInd>` +
+			`<BookgDt><Dt>` + date + `</Dt></BookgDt><NtryDtls>` + details + `</NtryDtls></Ntry></Stmt></BkToCstmrStmt></Document>`
+	}
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"not xml", "<Document><BkToCstmrStmt>", "parse camt.053 statement"},
+		{"no statements", "<Document/>", "contains no statements"},
+		{"bad amount", entry("12,3x", "2026-03-02", ""), "entry 1: invalid amount"},
+		{"bad date", entry("10", "02/03/2026", ""), "entry 1: invalid booking date"},
+		{"batched transaction without amount", entry("10", "2026-03-02", "<TxDtls><Amt>4</Amt></TxDtls><TxDtls/>"), "entry 1 transaction 2: batched transaction has no amount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(FormatCAMT053, strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
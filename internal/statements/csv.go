@@ -0,0 +1,159 @@
+package statements
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var csvDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"02/01/2006",
+	"02-01-2006",
+	"02-Jan-2006",
+}
+
+// csvColumns maps the header aliases banks commonly use to our canonical column names.
+var csvColumns = map[string]string{
+	"date":               "date",
+	"booking_date":       "date",
+	"booking date":       "date",
+	"transaction_date":   "date",
+	"transaction date":   "date",
+	"value_date":         "date",
+	"value date":         "date",
+	"reference":          "reference",
+	"provider_reference": "reference",
+	"transaction_ref":    "reference",
+	"transaction ref":    "reference",
+	"bank_reference":     "bank_reference",
+	"bank reference":     "bank_reference",
+	"amount":             "amount",
+	"debit":              "debit",
+	"credit":             "credit",
+	"currency":           "currency",
+	"type":               "type",
+	"direction":          "type",
+	"dr/cr":              "type",
+	"description":        "description",
+	"narration":          "description",
+	"remarks":            "description",
+}
+
+// parseCSV reads a header-first CSV statement. Amounts are taken either from separate
+// debit/credit columns, or from an amount column combined with a type column or sign
+// (negative amounts are debits).
+func parseCSV(data []byte) (*Statement, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv statement: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("csv statement is empty")
+	}
+
+	idx := map[string]int{}
+	for i, h := range records[0] {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		if col, ok := csvColumns[key]; ok {
+			if _, seen := idx[col]; !seen {
+				idx[col] = i
+			}
+		}
+	}
+	if _, ok := idx["date"]; !ok {
+		return nil, fmt.Errorf("csv statement is missing a date column")
+	}
+	if _, ok := idx["reference"]; !ok {
+		return nil, fmt.Errorf("csv statement is missing a reference column")
+	}
+	_, hasAmount := idx["amount"]
+	_, hasDebit := idx["debit"]
+	if !hasAmount && !hasDebit {
+		return nil, fmt.Errorf("csv statement is missing an amount or debit column")
+	}
+
+	field := func(rec []string, col string) string {
+		i, ok := idx[col]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	st := &Statement{}
+	for n, rec := range records[1:] {
+		row := n + 2
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+
+		date, err := parseCSVDate(field(rec, "date"))
+		if err != nil {
+			return nil, fmt.Errorf("csv row %d: %w", row, err)
+		}
+
+		line := Line{
+			Reference:     field(rec, "reference"),
+			BankReference: field(rec, "bank_reference"),
+			Currency:      strings.ToUpper(field(rec, "currency")),
+			BookingDate:   date,
+			Description:   field(rec, "description"),
+		}
+
+		if d := field(rec, "debit"); d != "" {
+			amount, err := parseAmount(d)
+			if err != nil {
+				return nil, fmt.Errorf("csv row %d: %w", row, err)
+			}
+			line.Amount, line.Debit = abs(amount), true
+		} else if c := field(rec, "credit"); c != "" {
+			amount, err := parseAmount(c)
+			if err != nil {
+				return nil, fmt.Errorf("csv row %d: %w", row, err)
+			}
+			line.Amount = abs(amount)
+		} else {
+			amount, err := parseAmount(field(rec, "amount"))
+			if err != nil {
+				return nil, fmt.Errorf("csv row %d: %w", row, err)
+			}
+			switch strings.ToLower(field(rec, "type")) {
+			case "d", "dr", "debit", "dbit":
+				line.Debit = true
+			case "c", "cr", "credit", "crdt":
+				line.Debit = false
+			default:
+				line.Debit = amount < 0
+			}
+			line.Amount = abs(amount)
+		}
+
+		st.Lines = append(st.Lines, line)
+	}
+	return st, nil
+}
+
+func parseCSVDate(s string) (time.Time, error) {
+	for _, layout := range csvDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
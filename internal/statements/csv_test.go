@@ -0,0 +1,77 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []Line
+	}{
+		{
+			name: "signed amount",
+			data: "Booking Date,Reference,Amount,Currency\n2026-03-02,PRV-1,-1500.25,ngn\n2026-03-02,DEP-1,200,NGN\n",
+			want: []Line{
+				{Reference: "PRV-1", Amount: 150025, Currency: "NGN", BookingDate: day("2026-03-02"), Debit: true},
+				{Reference: "DEP-1", Amount: 20000, Currency: "NGN", BookingDate: day("2026-03-02")},
+			},
+		},
+		{
+			name: "type column wins over the sign",
+			data: "date,reference,amount,dr/cr\n02/03/2026,PRV-1,1500.25,DR\n02/03/2026,PRV-2,-10,CR\n",
+			want: []Line{
+				{Reference: "PRV-1", Amount: 150025, BookingDate: day("2026-03-02"), Debit: true},
+				{Reference: "PRV-2", Amount: 1000, BookingDate: day("2026-03-02")},
+			},
+		},
+		{
+			name: "debit and credit columns with decimal comma and grouping",
+			data: "\ufeffvalue date,reference,bank reference,debit,credit,narration\n" +
+				"02-Mar-2026,PRV-1,BNK-9,\"1.234.567,89\",,salary run\n" +
+				"02-Mar-2026,DEP-1,,,\"1.000,00\",top up\n",
+			want: []Line{
+				{Reference: "PRV-1", BankReference: "BNK-9", Amount: 123456789, BookingDate: day("2026-03-02"), Debit: true, Description: "salary run"},
+				{Reference: "DEP-1", Amount: 100000, BookingDate: day("2026-03-02"), Description: "top up"},
+			},
+		},
+		{
+			name: "thousands separators",
+			data: "date,reference,debit\n2026-03-02,PRV-1,\"1,500.00\"\n\n",
+			want: []Line{
+				{Reference: "PRV-1", Amount: 150000, BookingDate: day("2026-03-02"), Debit: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertLines(t, parseLines(t, FormatCSV, tt.data), tt.want)
+		})
+	}
+}
+
+func TestParseCSVRejectsMalformedStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", "csv statement is empty"},
+		{"no date column", "reference,amount\nPRV-1,10\n", "missing a date column"},
+		{"no reference column", "date,amount\n2026-03-02,10\n", "missing a reference column"},
+		{"no amount column", "date,reference\n2026-03-02,PRV-1\n", "missing an amount or debit column"},
+		{"bad date", "date,reference,amount\n2026-13-40,PRV-1,10\n", "csv row 2: invalid date"},
+		{"bad amount", "date,reference,amount\n2026-03-02,PRV-1,10\n2026-03-02,PRV-2,ten\n", "csv row 3: invalid amount"},
+		{"bad quoting", "date,reference,amount\n2026-03-02,\"PRV-1,10\n", "parse csv statement"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(FormatCSV, strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package statements
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+	// :61: value date, optional entry date, mark, optional funds code, amount, type code,
+	// customer reference and optional bank reference.
+	mt940Line    = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d{0,2})([NSF][A-Z0-9]{3})([^/]*)(?://(.*))?$`)
+	mt940Balance = regexp.MustCompile(`^[CD]\d{6}([A-Z]{3})`)
+)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// parseMT940 reads a SWIFT MT940 customer statement. Only the fields needed for
+// reconciliation are interpreted: :20:, :25:, :28C:, :60F:/:60M:, :61: and :86:.
+func parseMT940(data []byte) (*Statement, error) {
+	var fields []mt940Field
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		raw := strings.TrimRight(sc.Text(), "\r ")
+		if raw == "" || raw == "-" || strings.HasPrefix(raw, "-}") || strings.HasPrefix(raw, "{") && !strings.Contains(raw, ":20:") {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(raw); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: m[2]})
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + raw
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("parse mt940 statement: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("mt940 statement contains no fields")
+	}
+
+	st := &Statement{}
+	currency := ""
+	var last *Line
+	for _, f := range fields {
+		switch f.tag {
+		case "20":
+			if st.ID == "" {
+				st.ID = strings.TrimSpace(f.value)
+			}
+		case "25":
+			if st.AccountID == "" {
+				st.AccountID = strings.TrimSpace(f.value)
+			}
+		case "28C":
+			if st.ID == "" {
+				st.ID = strings.TrimSpace(f.value)
+			}
+		case "60F", "60M":
+			if m := mt940Balance.FindStringSubmatch(f.value); m != nil {
+				currency = m[1]
+			}
+		case "61":
+			line, err := parseMT940Line(f.value, currency)
+			if err != nil {
+				return nil, err
+			}
+			st.Lines = append(st.Lines, line)
+			last = &st.Lines[len(st.Lines)-1]
+		case "86":
+			if last != nil {
+				last.Description = strings.TrimSpace(strings.ReplaceAll(f.value, "\n", " "))
+				last = nil
+			}
+		}
+	}
+	return st, nil
+}
+
+func parseMT940Line(value, currency string) (Line, error) {
+	first, supplementary, _ := strings.Cut(value, "\n")
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		return Line{}, fmt.Errorf("invalid mt940 statement line %q", first)
+	}
+
+	date, err := time.Parse("060102", m[1])
+	if err != nil {
+		return Line{}, fmt.Errorf("invalid mt940 value date %q", m[1])
+	}
+	amount, err := parseAmount(m[5])
+	if err != nil {
+		return Line{}, fmt.Errorf("mt940 statement line: %w", err)
+	}
+
+	line := Line{
+		Reference:     strings.TrimSpace(m[7]),
+		BankReference: strings.TrimSpace(m[8]),
+		Amount:        amount,
+		Currency:      currency,
+		BookingDate:   date,
+		// A reversal of a credit (RC) moves money out of the account, like a debit.
+		Debit:       m[3] == "D" || m[3] == "RC",
+		Description: strings.TrimSpace(supplementary),
+	}
+	if strings.EqualFold(line.Reference, "NONREF") {
+		line.Reference = line.BankReference
+	}
+	return line, nil
+}
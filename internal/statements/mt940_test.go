@@ -0,0 +1,55 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMT940(t *testing.T) {
+	data := "{1:F01BANKNGLAXXXX0000000000}{2:I940BANKNGLAXXXXN}{4:\r\n" +
+		":20:STMT-0302\r\n" +
+		":25:0123456789\r\n" +
+		":28C:00042/001\r\n" +
+		":60F:C260301NGN1000000,00\r\n" +
+		":61:2603020302D1500,25NTRFPRV-1//BNK-1\r\n" +
+		"SALARY MARCH\r\n" +
+		":86:Payout to Ada Obi\r\n" +
+		"ref PRV-1\r\n" +
+		":61:260302C200,NTRFDEP-1\r\n" +
+		":61:260303RC75,5NTRFNONREF//BNK-3\r\n" +
+		":62F:C260303NGN998700,25\r\n" +
+		"-}"
+	st, err := Parse("", strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Format != FormatMT940 || st.ID != "STMT-0302" || st.AccountID != "0123456789" {
+		t.Fatalf("statement = %s %q %q", st.Format, st.ID, st.AccountID)
+	}
+	assertLines(t, st.Lines, []Line{
+		{Reference: "PRV-1", BankReference: "BNK-1", Amount: 150025, Currency: "NGN", BookingDate: day("2026-03-02"), Debit: true, Description: "Payout to Ada Obi ref PRV-1"},
+		{Reference: "DEP-1", Amount: 20000, Currency: "NGN", BookingDate: day("2026-03-02")},
+		{Reference: "BNK-3", BankReference: "BNK-3", Amount: 7550, Currency: "NGN", BookingDate: day("2026-03-03"), Debit: true},
+	})
+}
+
+func TestParseMT940RejectsMalformedStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"no fields", "hello\n", "contains no fields"},
+		{"bad line", ":20:S1\n:61:2603021500,25NTRFPRV-1\n", "invalid mt940 statement line"},
+		{"bad mark", ":20:S1\n:61:260302X1500,25NTRFPRV-1\n", "invalid mt940 statement line"},
+		{"bad date", ":20:S1\n:61:261340D1500,25NTRFPRV-1\n", "invalid mt940 value date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(FormatMT940, strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package statements
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format identifies a bank statement file format.
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatCAMT053 Format = "camt053"
+	FormatMT940   Format = "mt940"
+)
+
+// Line is a single booked entry on a bank statement. Amount is in minor units (kobo).
+type Line struct {
+	Reference     string
+	BankReference string
+	Amount        int64
+	Currency      string
+	BookingDate   time.Time
+	Debit         bool
+	Description   string
+}
+
+// Statement is the normalized content of an imported statement file.
+type Statement struct {
+	ID        string
+	AccountID string
+	Format    Format
+	Lines     []Line
+}
+
+// ParseFormat validates a user supplied format name.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "csv":
+		return FormatCSV, nil
+	case "camt053", "camt.053", "camt":
+		return FormatCAMT053, nil
+	case "mt940", "swift":
+		return FormatMT940, nil
+	default:
+		return "", fmt.Errorf("unsupported statement format %q", s)
+	}
+}
+
+// Detect guesses the statement format from the file content.
+func Detect(data []byte) Format {
+	head := bytes.TrimSpace(data)
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("<")):
+		return FormatCAMT053
+	case bytes.Contains(head, []byte(":20:")) || bytes.HasPrefix(head, []byte("{1:")):
+		return FormatMT940
+	default:
+		return FormatCSV
+	}
+}
+
+// Parse reads a statement in the given format. An empty format is detected from the content.
+func Parse(format Format, r io.Reader) (*Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read statement: %w", err)
+	}
+	if format == "" {
+		format = Detect(data)
+	}
+
+	var st *Statement
+	switch format {
+	case FormatCSV:
+		st, err = parseCSV(data)
+	case FormatCAMT053:
+		st, err = parseCAMT053(data)
+	case FormatMT940:
+		st, err = parseMT940(data)
+	default:
+		return nil, fmt.Errorf("unsupported statement format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	st.Format = format
+	return st, nil
+}
+
+// parseAmount converts a decimal string (either "." or "," as decimal separator) to minor units.
+func parseAmount(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, " ", "")
+	if s == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+
+	neg := false
+	if strings.HasPrefix(s, "-") {
+		neg = true
+		s = s[1:]
+	} else if strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+
+	if strings.Trim(s, "0123456789.,") != "" || strings.IndexAny(s, "0123456789") < 0 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+
+	// The last separator is the decimal point; anything before it is grouping.
+	sep := strings.LastIndexAny(s, ".,")
+	whole, frac := s, ""
+	if sep >= 0 && len(s)-sep-1 <= 2 {
+		whole, frac = s[:sep], s[sep+1:]
+	}
+	whole = strings.NewReplacer(",", "", ".", "").Replace(whole)
+	if whole == "" {
+		whole = "0"
+	}
+	for len(frac) < 2 {
+		frac += "0"
+	}
+
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	cents, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	amount := units*100 + cents
+	if neg {
+		amount = -amount
+	}
+	return amount, nil
+}
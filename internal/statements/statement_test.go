@@ -0,0 +1,90 @@
+package statements
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"2500.50", 250050, false},
+		{"2500", 250000, false},
+		{"2500.5", 250050, false},
+		{"-2500.50", -250050, false},
+		{"+2500.50", 250050, false},
+		{"2500,50", 250050, false},
+		{"1,234,567.89", 123456789, false},
+		{"1.234.567,89", 123456789, false},
+		{"1 234 567,89", 123456789, false},
+		{"1,234", 123400, false}, // three digits after the last separator are grouping
+		{"1.234", 123400, false},
+		{",5", 50, false},
+		{"", 0, true},
+		{"-", 0, true},
+		{"--5", 0, true},
+		{"12a.00", 0, true},
+		{"NGN 12.00", 0, true},
+		{"1e5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseAmount(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		data string
+		want Format
+	}{
+		{"<?xml version=\"1.0\"?><Document/>", FormatCAMT053},
+		{"{1:F01BANKNGLAXXXX}{4:\n:20:STMT1\n", FormatMT940},
+		{":20:STMT1\n:25:0123456789\n", FormatMT940},
+		{"date,reference,amount\n", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := Detect([]byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.data, got, tt.want)
+		}
+	}
+}
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// parseLines parses a statement and fails the test on error.
+func parseLines(t *testing.T, format Format, data string) []Line {
+	t.Helper()
+	st, err := Parse(format, strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Format != format {
+		t.Fatalf("format = %s, want %s", st.Format, format)
+	}
+	return st.Lines
+}
+
+func assertLines(t *testing.T, got, want []Line) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}